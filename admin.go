@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+)
+
+const adminUsage = "usage: admin add | remove <username>"
+
+// Handles the `admin add|remove <username>` command against POSTGRES_URI,
+// admins have to enroll in two factor authentication on their next login
+func runAdmin(args []string) error {
+	if len(args) != 2 || (args[0] != "add" && args[0] != "remove") {
+		return fmt.Errorf(adminUsage)
+	}
+	db, err := database.NewPostgres(os.Getenv("POSTGRES_URI"))
+	if err != nil {
+		return err
+	}
+	user := db.ReadUserByName(args[1])
+	if user == nil {
+		return fmt.Errorf("user %q does not exist", args[1])
+	}
+	switch args[0] {
+	case "add":
+		if !db.CreateAdmin(user.Id) {
+			return fmt.Errorf("unable to make %q an admin", user.Username)
+		}
+		fmt.Printf("%s is now an admin\n", user.Username)
+	case "remove":
+		if !db.DeleteAdmin(user.Id) {
+			return fmt.Errorf("unable to remove %q from admins", user.Username)
+		}
+		fmt.Printf("%s is no longer an admin\n", user.Username)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/internal/jobs"
+	"github.com/Bhar8at/bhar8at.github.io/internal/timeline"
+	"github.com/Bhar8at/bhar8at.github.io/middleware"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/gin-gonic/gin"
+)
+
+var allScopes = []string{models.ScopeRead, models.ScopeWritePosts, models.ScopeWriteFollows}
+
+// The API on a memory store, routed as in the app
+type testAPI struct {
+	engine *gin.Engine
+	store  *database.Memory
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	gin.SetMode(gin.TestMode)
+	store := database.NewMemory()
+	h := NewHandler(Config{Store: store, Timeline: timeline.New(store, jobs.New(store))})
+
+	engine := gin.New()
+	v1 := engine.Group("/api/v1")
+	v1.Use(h.Authenticate)
+	v1.GET("/me", Require(models.ScopeRead), h.GetMe)
+	v1.GET("/search/users", h.SearchUsers)
+	v1.GET("/users/:username", h.GetUser)
+	v1.GET("/users/:username/posts", h.GetUserPosts)
+	v1.PUT("/users/:username/follow", Require(models.ScopeWriteFollows), h.Follow)
+	v1.DELETE("/users/:username/follow", Require(models.ScopeWriteFollows), h.Unfollow)
+	v1.POST("/posts", Require(models.ScopeWritePosts), h.CreatePost)
+	v1.GET("/posts/:id", h.GetPost)
+	v1.PATCH("/posts/:id", Require(models.ScopeWritePosts), h.EditPost)
+	v1.DELETE("/posts/:id", Require(models.ScopeWritePosts), h.DeletePost)
+	v1.PUT("/posts/:id/vote", Require(models.ScopeWritePosts), h.Vote)
+	v1.DELETE("/posts/:id/vote", Require(models.ScopeWritePosts), h.Unvote)
+	return &testAPI{engine: engine, store: store}
+}
+
+// Creates the user with a token holding the scopes and returns the token
+func (a *testAPI) createUser(t *testing.T, username string, scopes ...string) string {
+	if !a.store.CreateUser(&models.User{Id: username + "-id", Username: username, CreatedAt: time.Now()}) {
+		t.Fatalf("unable to create %s", username)
+	}
+	token, hash, err := middleware.GenerateAccessToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.store.CreateAccessToken(&models.AccessToken{
+		Id:        username + "-token",
+		UserId:    username + "-id",
+		Name:      "test",
+		Hash:      hash,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}) {
+		t.Fatalf("unable to create a token for %s", username)
+	}
+	return token
+}
+
+// Sends the request with the token unless empty, decoding the JSON
+// response into result unless nil
+func (a *testAPI) do(t *testing.T, method string, path string, token string, body string, result any) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	response := httptest.NewRecorder()
+	a.engine.ServeHTTP(response, request)
+	if result != nil {
+		if err := json.Unmarshal(response.Body.Bytes(), result); err != nil {
+			t.Fatalf("%s %s = %s: %v", method, path, response.Body, err)
+		}
+	}
+	return response
+}
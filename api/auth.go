@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Bhar8at/bhar8at.github.io/middleware"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Identifies the caller from an Authorization: Bearer token when one is
+// sent. Anonymous requests go through, Require decides what needs a token.
+// The session cookie is never used so the API isn't open to CSRF.
+func (h *Handler) Authenticate(c *gin.Context) {
+	bearer, ok := middleware.BearerToken(c)
+	if !ok {
+		c.Next()
+		return
+	}
+	if middleware.AuthenticateAccessToken(c, h.store, bearer) == nil {
+		abort(c, http.StatusUnauthorized, codeUnauthorized, "Invalid or revoked access token.")
+		return
+	}
+	c.Next()
+}
+
+// Requires a token with the scope
+func Require(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := accessToken(c)
+		if token == nil {
+			c.Header("WWW-Authenticate", `Bearer realm="api"`)
+			abort(c, http.StatusUnauthorized, codeUnauthorized, "An access token is required.")
+			return
+		}
+		if !token.HasScope(scope) {
+			abort(c, http.StatusForbidden, codeInsufficientScope, "The access token is missing the "+scope+" scope.")
+			return
+		}
+		c.Next()
+	}
+}
+
+func accessToken(c *gin.Context) *models.AccessToken {
+	value, ok := c.Get("accessToken")
+	if !ok {
+		return nil
+	}
+	return value.(*models.AccessToken)
+}
+
+// Id of the calling user, empty for anonymous requests
+func viewerId(c *gin.Context) string {
+	if token := accessToken(c); token != nil {
+		return token.UserId
+	}
+	return ""
+}
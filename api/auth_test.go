@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func TestRequire(t *testing.T) {
+	tests := []struct {
+		name       string
+		scopes     []string
+		anonymous  bool
+		token      string
+		wantStatus int
+		wantCode   string
+	}{
+		{"token with scope", []string{models.ScopeRead}, false, "", http.StatusOK, ""},
+		{"token without scope", []string{models.ScopeWritePosts}, false, "", http.StatusForbidden, codeInsufficientScope},
+		{"no token", nil, true, "", http.StatusUnauthorized, codeUnauthorized},
+		{"unknown token", nil, false, "cnx_unknown", http.StatusUnauthorized, codeUnauthorized},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := newTestAPI(t)
+			token := a.createUser(t, "alice", test.scopes...)
+			if test.anonymous {
+				token = ""
+			} else if test.token != "" {
+				token = test.token
+			}
+			var result struct {
+				Data  Me          `json:"data"`
+				Error ErrorDetail `json:"error"`
+			}
+			response := a.do(t, http.MethodGet, "/api/v1/me", token, "", &result)
+			if response.Code != test.wantStatus || result.Error.Code != test.wantCode {
+				t.Fatalf("GET /me = %d %q, want %d %q", response.Code, result.Error.Code, test.wantStatus, test.wantCode)
+			}
+			if test.wantStatus == http.StatusOK && result.Data.Username != "alice" {
+				t.Errorf("GET /me = %+v, want alice", result.Data)
+			}
+		})
+	}
+}
+
+func TestAuthenticateAnonymous(t *testing.T) {
+	a := newTestAPI(t)
+	a.createUser(t, "alice")
+	var result Item[User]
+	if response := a.do(t, http.MethodGet, "/api/v1/users/alice", "", "", &result); response.Code != http.StatusOK {
+		t.Fatalf("anonymous GET = %d", response.Code)
+	}
+	if result.Data.FollowedByMe != nil {
+		t.Errorf("followedByMe = %v for an anonymous caller", *result.Data.FollowedByMe)
+	}
+}
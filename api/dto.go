@@ -0,0 +1,207 @@
+package api
+
+import (
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+// The response types below are the API's contract with clients. They are
+// kept apart from the models so storage changes can't leak fields.
+
+// Author of a post or comment
+type UserSummary struct {
+	Id       string  `json:"id"`
+	Username string  `json:"username"`
+	Avatar   *string `json:"avatar"`
+}
+
+type User struct {
+	UserSummary
+	Verified  bool      `json:"verified"`
+	CreatedAt time.Time `json:"createdAt"`
+	Followers int       `json:"followers"`
+	Following int       `json:"following"`
+	Posts     int       `json:"posts"`
+	// Whether the caller follows the user, left out for anonymous callers
+	// and the caller's own profile
+	FollowedByMe *bool `json:"followedByMe,omitempty"`
+}
+
+// The caller's own account
+type Me struct {
+	User
+	Email  *string  `json:"email"`
+	Scopes []string `json:"scopes"`
+}
+
+type Post struct {
+	Id     string      `json:"id"`
+	Author UserSummary `json:"author"`
+	Body   string      `json:"body"`
+	// URL of the first of media, kept for clients from before posts had
+	// more than one image
+	Image     *string   `json:"image"`
+	Media     []Media   `json:"media"`
+	Votes     int       `json:"votes"`
+	VotedByMe *bool     `json:"votedByMe,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	// When the body was last changed, null for posts never edited
+	EditedAt *time.Time `json:"editedAt"`
+}
+
+// Image attached to a post
+type Media struct {
+	// Original, without its metadata
+	URL string `json:"url"`
+	// Scaled down to fit 1280 and 320 pixels, or the original when smaller
+	MediumURL    string `json:"mediumUrl"`
+	ThumbnailURL string `json:"thumbnailUrl"`
+	// Placeholder to show while loading, see https://blurha.sh, empty
+	// when unknown
+	Blurhash string `json:"blurhash"`
+	AltText  string `json:"altText"`
+	// Zero when unknown
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Earlier body of an edited post, createdAt being when it was written
+type Revision struct {
+	Id        string    `json:"id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type Comment struct {
+	Id        string      `json:"id"`
+	PostId    string      `json:"postId"`
+	Author    UserSummary `json:"author"`
+	Body      string      `json:"body"`
+	CreatedAt time.Time   `json:"createdAt"`
+}
+
+type Follow struct {
+	Following bool `json:"following"`
+}
+
+type Vote struct {
+	Voted bool `json:"voted"`
+	Votes int  `json:"votes"`
+}
+
+// Body of POST /posts, PATCH /posts/:id and POST /posts/:id/comments
+type createBody struct {
+	Body string `json:"body"`
+}
+
+// Body of a single resource response
+type Item[T any] struct {
+	Data T `json:"data"`
+}
+
+func newUserSummary(user *models.User) UserSummary {
+	if user == nil {
+		return UserSummary{Username: "[deleted]"}
+	}
+	return UserSummary{Id: user.Id, Username: user.Username, Avatar: user.Avatar}
+}
+
+// Author of a post or comment from the columns joined onto it
+func newAuthor(id string, username string, avatar *string) UserSummary {
+	if username == "" {
+		return UserSummary{Username: "[deleted]"}
+	}
+	return UserSummary{Id: id, Username: username, Avatar: avatar}
+}
+
+// Converts users along with their counts, read in one go whatever their number
+func (h *Handler) newUsers(users []models.User, viewer string) []User {
+	ids := make([]string, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.Id)
+	}
+	stats := h.store.ReadUserStats(viewer, ids)
+	result := make([]User, 0, len(users))
+	for index := range users {
+		user := &users[index]
+		stat := stats[user.Id]
+		item := User{
+			UserSummary: newUserSummary(user),
+			Verified:    user.Verified,
+			CreatedAt:   user.CreatedAt,
+			Followers:   stat.Followers,
+			Following:   stat.Following,
+			Posts:       stat.Posts,
+		}
+		if viewer != "" && viewer != user.Id {
+			followed := stat.Followed
+			item.FollowedByMe = &followed
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+func (h *Handler) newUser(user *models.User, viewer string) User {
+	return h.newUsers([]models.User{*user}, viewer)[0]
+}
+
+// Converts posts along with their votes, read in one go whatever their number
+func (h *Handler) newPosts(posts []models.Post, viewer string) []Post {
+	ids := make([]string, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.Id)
+	}
+	votes := h.store.ReadPostVotes(viewer, ids)
+	result := make([]Post, 0, len(posts))
+	for _, post := range posts {
+		item := Post{
+			Id:        post.Id,
+			Author:    newAuthor(post.UserId, post.Username, post.Avatar),
+			Body:      post.Body,
+			Votes:     votes[post.Id].Count,
+			CreatedAt: post.CreatedAt,
+			EditedAt:  post.EditedAt,
+		}
+		item.Media = make([]Media, 0, len(post.Media))
+		for _, media := range post.Media {
+			item.Media = append(item.Media, Media{
+				URL:          media.URL,
+				MediumURL:    media.MediumURL,
+				ThumbnailURL: media.ThumbnailURL,
+				Blurhash:     media.Blurhash,
+				AltText:      media.AltText,
+				Width:        media.Width,
+				Height:       media.Height,
+			})
+		}
+		if len(item.Media) > 0 {
+			item.Image = &item.Media[0].URL
+		}
+		if viewer != "" {
+			voted := votes[post.Id].Voted
+			item.VotedByMe = &voted
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+func (h *Handler) newPost(post *models.Post, viewer string) Post {
+	return h.newPosts([]models.Post{*post}, viewer)[0]
+}
+
+func newComments(comments []models.Comment) []Comment {
+	result := make([]Comment, 0, len(comments))
+	for _, comment := range comments {
+		result = append(result, Comment{
+			Id:        comment.Id,
+			PostId:    comment.PostId,
+			Author:    newAuthor(comment.UserId, comment.Username, comment.Avatar),
+			Body:      comment.Body,
+			CreatedAt: comment.CreatedAt,
+		})
+	}
+	return result
+}
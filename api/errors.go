@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Machine readable error codes, clients should switch on these rather
+// than the messages
+const (
+	codeBadRequest        = "bad_request"
+	codeInvalidCursor     = "invalid_cursor"
+	codeUnauthorized      = "unauthorized"
+	codeForbidden         = "forbidden"
+	codeInsufficientScope = "insufficient_scope"
+	codeNotFound          = "not_found"
+	codeMethodNotAllowed  = "method_not_allowed"
+	codeInternal          = "internal_error"
+)
+
+type ErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Every error is sent as {"error": {"code": ..., "message": ...}}
+type ErrorResponse struct {
+	Error ErrorDetail `json:"error"`
+}
+
+func abort(c *gin.Context, status int, code string, message string) {
+	c.AbortWithStatusJSON(status, ErrorResponse{Error: ErrorDetail{Code: code, Message: message}})
+}
+
+// Reports whether the request is for the API, so the app's fallback
+// handlers can answer with JSON instead of a page
+func IsAPIRequest(c *gin.Context) bool {
+	return strings.HasPrefix(c.Request.URL.Path, "/api/")
+}
+
+func NotFound(c *gin.Context) {
+	abort(c, http.StatusNotFound, codeNotFound, "No such endpoint.")
+}
+
+func MethodNotAllowed(c *gin.Context) {
+	abort(c, http.StatusMethodNotAllowed, codeMethodNotAllowed, "Method not allowed on this endpoint.")
+}
@@ -0,0 +1,31 @@
+// Package api serves the versioned JSON API under /api/v1 for mobile and
+// command line clients, authenticated with personal access tokens
+package api
+
+import (
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/internal/timeline"
+)
+
+// Handler holds the dependencies shared by the API handlers
+type Handler struct {
+	store           database.Store
+	requireVerified bool
+	timeline        *timeline.Timeline
+}
+
+type Config struct {
+	Store database.Store
+	// Limits posting and commenting to users with a verified email
+	RequireVerified bool
+	// Pushes new posts and follows to home timelines
+	Timeline *timeline.Timeline
+}
+
+func NewHandler(config Config) *Handler {
+	return &Handler{
+		store:           config.Store,
+		requireVerified: config.RequireVerified,
+		timeline:        config.Timeline,
+	}
+}
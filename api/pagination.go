@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// List responses wrap their items, nextCursor is null on the last page
+type Page[T any] struct {
+	Data       []T     `json:"data"`
+	NextCursor *string `json:"nextCursor"`
+}
+
+// Reads the cursor and limit query parameters, aborting with a 400 when
+// they're malformed
+func pageParams(c *gin.Context) (*models.Cursor, int, bool) {
+	after, err := models.DecodeCursor(c.Query("cursor"))
+	if err != nil {
+		abort(c, http.StatusBadRequest, codeInvalidCursor, "The cursor is malformed, start again without one.")
+		return nil, 0, false
+	}
+	limit := defaultPageSize
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxPageSize {
+			abort(c, http.StatusBadRequest, codeBadRequest, "limit must be between 1 and "+strconv.Itoa(maxPageSize)+".")
+			return nil, 0, false
+		}
+		limit = parsed
+	}
+	return after, limit, true
+}
+
+func newPage[T any](items []T, next *models.Cursor) Page[T] {
+	result := Page[T]{Data: items}
+	if next != nil {
+		encoded := next.Encode()
+		result.NextCursor = &encoded
+	}
+	if result.Data == nil {
+		result.Data = []T{}
+	}
+	return result
+}
+
+// Pages through unique keys that are already fully loaded, in order
+func pageKeys(keys []string, after *models.Cursor, limit int) ([]string, *models.Cursor) {
+	sorted := append([]string(nil), keys...)
+	sort.Strings(sorted)
+	start := 0
+	if after != nil {
+		start = sort.SearchStrings(sorted, after.Id)
+		if start < len(sorted) && sorted[start] == after.Id {
+			start++
+		}
+	}
+	sorted = sorted[start:]
+	if len(sorted) <= limit {
+		return sorted, nil
+	}
+	return sorted[:limit], &models.Cursor{Id: sorted[limit-1]}
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Longest post or comment the database holds
+const maxBodyLength = 320
+
+// Reads the post in the path, aborting with a 404 when there's none
+func (h *Handler) pathPost(c *gin.Context) *models.Post {
+	post := h.store.ReadPost(c.Param("id"))
+	if post == nil {
+		abort(c, http.StatusNotFound, codeNotFound, "Post not found.")
+	}
+	return post
+}
+
+// Reads and checks the body of a post or comment
+func (h *Handler) readBody(c *gin.Context) (string, bool) {
+	var request createBody
+	if err := c.ShouldBindJSON(&request); err != nil {
+		abort(c, http.StatusBadRequest, codeBadRequest, "The request body must be JSON like {\"body\": \"...\"}.")
+		return "", false
+	}
+	body := strings.TrimSpace(request.Body)
+	if body == "" || utf8.RuneCountInString(body) > maxBodyLength {
+		abort(c, http.StatusBadRequest, codeBadRequest, "body must be between 1 and 320 characters.")
+		return "", false
+	}
+	if h.requireVerified {
+		if user := h.store.ReadUserById(viewerId(c)); user == nil || !user.Verified {
+			abort(c, http.StatusForbidden, codeForbidden, "Verify your email address before posting.")
+			return "", false
+		}
+	}
+	return body, true
+}
+
+// GET /feed
+func (h *Handler) GetFeed(c *gin.Context) {
+	after, limit, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	viewer := viewerId(c)
+	posts, next := h.store.ReadFeedPosts(viewer, after, limit)
+	c.JSON(http.StatusOK, newPage(h.newPosts(posts, viewer), next))
+}
+
+// POST /posts
+func (h *Handler) CreatePost(c *gin.Context) {
+	body, ok := h.readBody(c)
+	if !ok {
+		return
+	}
+	post := models.Post{
+		Id:        uuid.NewString(),
+		Body:      body,
+		CreatedAt: time.Now(),
+	}
+	if !h.store.CreatePost(viewerId(c), &post) {
+		abort(c, http.StatusInternalServerError, codeInternal, "Unable to create post, try again later.")
+		return
+	}
+	post.UserId = viewerId(c)
+	h.timeline.Posted(post.UserId, post.Id)
+	if user := h.store.ReadUserById(post.UserId); user != nil {
+		post.Username, post.Avatar = user.Username, user.Avatar
+	}
+	c.Header("Location", "/api/v1/posts/"+post.Id)
+	c.JSON(http.StatusCreated, Item[Post]{Data: h.newPost(&post, viewerId(c))})
+}
+
+// GET /posts/:id
+func (h *Handler) GetPost(c *gin.Context) {
+	post := h.pathPost(c)
+	if post == nil {
+		return
+	}
+	c.JSON(http.StatusOK, Item[Post]{Data: h.newPost(post, viewerId(c))})
+}
+
+// PATCH /posts/:id
+func (h *Handler) EditPost(c *gin.Context) {
+	post := h.pathPost(c)
+	if post == nil {
+		return
+	}
+	if post.UserId != viewerId(c) {
+		abort(c, http.StatusForbidden, codeForbidden, "Only the author can edit a post.")
+		return
+	}
+	body, ok := h.readBody(c)
+	if !ok {
+		return
+	}
+	if body != post.Body {
+		editedAt := time.Now()
+		if !h.store.EditPost(post.Id, body, editedAt, uuid.NewString()) {
+			abort(c, http.StatusInternalServerError, codeInternal, "Unable to edit post, try again later.")
+			return
+		}
+		post.Body = body
+		post.EditedAt = &editedAt
+	}
+	c.JSON(http.StatusOK, Item[Post]{Data: h.newPost(post, viewerId(c))})
+}
+
+// GET /posts/:id/revisions
+func (h *Handler) GetRevisions(c *gin.Context) {
+	post := h.pathPost(c)
+	if post == nil {
+		return
+	}
+	after, limit, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	revisions, next := h.store.ReadPostRevisions(post.Id, after, limit)
+	result := make([]Revision, 0, len(revisions))
+	for _, revision := range revisions {
+		result = append(result, Revision{
+			Id:        revision.Id,
+			Body:      revision.Body,
+			CreatedAt: revision.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, newPage(result, next))
+}
+
+// DELETE /posts/:id
+func (h *Handler) DeletePost(c *gin.Context) {
+	post := h.pathPost(c)
+	if post == nil {
+		return
+	}
+	if post.UserId != viewerId(c) {
+		abort(c, http.StatusForbidden, codeForbidden, "Only the author can delete a post.")
+		return
+	}
+	if !h.store.DeletePost(post.Id) {
+		abort(c, http.StatusInternalServerError, codeInternal, "Unable to delete post, try again later.")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// PUT /posts/:id/vote
+func (h *Handler) Vote(c *gin.Context) {
+	h.setVote(c, true)
+}
+
+// DELETE /posts/:id/vote
+func (h *Handler) Unvote(c *gin.Context) {
+	h.setVote(c, false)
+}
+
+// Votes or removes the vote, doing nothing when already in that state so
+// retries are safe
+func (h *Handler) setVote(c *gin.Context, vote bool) {
+	post := h.pathPost(c)
+	if post == nil {
+		return
+	}
+	viewer := viewerId(c)
+	if h.store.Voted(viewer, post.Id) != vote {
+		h.store.ToggleVote(viewer, post.Id)
+	}
+	c.JSON(http.StatusOK, Item[Vote]{Data: Vote{
+		Voted: vote,
+		Votes: len(h.store.ReadVotes(post.Id)),
+	}})
+}
+
+// GET /posts/:id/comments
+func (h *Handler) GetComments(c *gin.Context) {
+	post := h.pathPost(c)
+	if post == nil {
+		return
+	}
+	after, limit, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	comments, next := h.store.ReadComments(post.Id, after, limit)
+	c.JSON(http.StatusOK, newPage(newComments(comments), next))
+}
+
+// POST /posts/:id/comments
+func (h *Handler) CreateComment(c *gin.Context) {
+	post := h.pathPost(c)
+	if post == nil {
+		return
+	}
+	body, ok := h.readBody(c)
+	if !ok {
+		return
+	}
+	comment := models.Comment{
+		Id:        uuid.NewString(),
+		Body:      body,
+		CreatedAt: time.Now(),
+	}
+	if !h.store.CreateComment(viewerId(c), post.Id, &comment) {
+		abort(c, http.StatusInternalServerError, codeInternal, "Unable to add comment, try again later.")
+		return
+	}
+	comment.PostId = post.Id
+	comment.UserId = viewerId(c)
+	if user := h.store.ReadUserById(comment.UserId); user != nil {
+		comment.Username, comment.Avatar = user.Username, user.Avatar
+	}
+	c.JSON(http.StatusCreated, Item[Comment]{Data: newComments([]models.Comment{comment})[0]})
+}
+
+// DELETE /posts/:id/comments/:commentId
+func (h *Handler) DeleteComment(c *gin.Context) {
+	comment := h.store.ReadComment(c.Param("commentId"))
+	if comment == nil || comment.PostId != c.Param("id") {
+		abort(c, http.StatusNotFound, codeNotFound, "Comment not found.")
+		return
+	}
+	if comment.UserId != viewerId(c) {
+		abort(c, http.StatusForbidden, codeForbidden, "Only the author can delete a comment.")
+		return
+	}
+	if !h.store.DeleteComment(comment.Id) {
+		abort(c, http.StatusInternalServerError, codeInternal, "Unable to delete comment, try again later.")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func TestCreatePost(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"post", `{"body": " hello "}`, http.StatusCreated},
+		{"empty", `{"body": "  "}`, http.StatusBadRequest},
+		{"too long", `{"body": "` + strings.Repeat("a", maxBodyLength+1) + `"}`, http.StatusBadRequest},
+		{"not JSON", `body=hello`, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := newTestAPI(t)
+			token := a.createUser(t, "alice", allScopes...)
+			var result Item[Post]
+			response := a.do(t, http.MethodPost, "/api/v1/posts", token, test.body, &result)
+			if response.Code != test.wantStatus {
+				t.Fatalf("POST = %d, want %d: %s", response.Code, test.wantStatus, response.Body)
+			}
+			if test.wantStatus != http.StatusCreated {
+				return
+			}
+			if result.Data.Body != "hello" || result.Data.Author.Username != "alice" {
+				t.Errorf("created %+v", result.Data)
+			}
+			if location := response.Header().Get("Location"); location != "/api/v1/posts/"+result.Data.Id {
+				t.Errorf("Location %q", location)
+			}
+			if a.store.ReadPost(result.Data.Id) == nil {
+				t.Errorf("post %s not stored", result.Data.Id)
+			}
+		})
+	}
+}
+
+func TestPostAuthor(t *testing.T) {
+	tests := []struct {
+		name       string
+		author     string
+		postId     string
+		wantStatus int
+	}{
+		{"author", "alice-id", "post", http.StatusOK},
+		{"someone else", "bob-id", "post", http.StatusForbidden},
+		{"missing post", "alice-id", "missing", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := newTestAPI(t)
+			token := a.createUser(t, "alice", allScopes...)
+			a.createUser(t, "bob")
+			a.store.CreatePost(test.author, &models.Post{Id: "post", Body: "hello", CreatedAt: time.Now()})
+
+			path := "/api/v1/posts/" + test.postId
+			if response := a.do(t, http.MethodPatch, path, token, `{"body": "edited"}`, nil); response.Code != test.wantStatus {
+				t.Errorf("PATCH = %d, want %d", response.Code, test.wantStatus)
+			}
+			wantStatus := test.wantStatus
+			if wantStatus == http.StatusOK {
+				wantStatus = http.StatusNoContent
+			}
+			if response := a.do(t, http.MethodDelete, path, token, "", nil); response.Code != wantStatus {
+				t.Errorf("DELETE = %d, want %d", response.Code, wantStatus)
+			}
+			if deleted := a.store.ReadPost("post") == nil; deleted != (wantStatus == http.StatusNoContent) {
+				t.Errorf("deleted = %v", deleted)
+			}
+		})
+	}
+}
+
+func TestVote(t *testing.T) {
+	a := newTestAPI(t)
+	token := a.createUser(t, "alice", allScopes...)
+	a.store.CreatePost("alice-id", &models.Post{Id: "post", Body: "hello", CreatedAt: time.Now()})
+
+	// Voting twice is the same as once so retries are safe
+	for attempt := 0; attempt < 2; attempt++ {
+		var result Item[Vote]
+		a.do(t, http.MethodPut, "/api/v1/posts/post/vote", token, "", &result)
+		if !result.Data.Voted || result.Data.Votes != 1 {
+			t.Fatalf("PUT vote = %+v, want one vote", result.Data)
+		}
+	}
+	var post Item[Post]
+	a.do(t, http.MethodGet, "/api/v1/posts/post", token, "", &post)
+	if post.Data.VotedByMe == nil || !*post.Data.VotedByMe {
+		t.Errorf("GET post = %+v, want voted by alice", post.Data)
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		var result Item[Vote]
+		a.do(t, http.MethodDelete, "/api/v1/posts/post/vote", token, "", &result)
+		if result.Data.Voted || result.Data.Votes != 0 {
+			t.Fatalf("DELETE vote = %+v, want none", result.Data)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Reads the user named in the path, aborting with a 404 when there's none
+func (h *Handler) pathUser(c *gin.Context) *models.User {
+	user := h.store.ReadUserByName(c.Param("username"))
+	if user == nil {
+		abort(c, http.StatusNotFound, codeNotFound, "User not found.")
+	}
+	return user
+}
+
+// GET /me
+func (h *Handler) GetMe(c *gin.Context) {
+	token := accessToken(c)
+	user := h.store.ReadUserById(token.UserId)
+	if user == nil {
+		abort(c, http.StatusUnauthorized, codeUnauthorized, "The token's account no longer exists.")
+		return
+	}
+	c.JSON(http.StatusOK, Item[Me]{Data: Me{
+		User:   h.newUser(user, ""),
+		Email:  user.Email,
+		Scopes: token.Scopes,
+	}})
+}
+
+// GET /users/:username
+func (h *Handler) GetUser(c *gin.Context) {
+	user := h.pathUser(c)
+	if user == nil {
+		return
+	}
+	c.JSON(http.StatusOK, Item[User]{Data: h.newUser(user, viewerId(c))})
+}
+
+// GET /users/:username/posts
+func (h *Handler) GetUserPosts(c *gin.Context) {
+	user := h.pathUser(c)
+	if user == nil {
+		return
+	}
+	after, limit, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	viewer := viewerId(c)
+	posts, next := h.store.ReadPosts(user.Id, after, limit)
+	c.JSON(http.StatusOK, newPage(h.newPosts(posts, viewer), next))
+}
+
+// GET /users/:username/followers
+func (h *Handler) GetFollowers(c *gin.Context) {
+	user := h.pathUser(c)
+	if user == nil {
+		return
+	}
+	h.userList(c, h.store.ReadFollowers(user.Id))
+}
+
+// GET /users/:username/following
+func (h *Handler) GetFollowing(c *gin.Context) {
+	user := h.pathUser(c)
+	if user == nil {
+		return
+	}
+	h.userList(c, h.store.ReadFollowing(user.Id))
+}
+
+// Responds with a page of the users with the given usernames
+func (h *Handler) userList(c *gin.Context, usernames []string) {
+	after, limit, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	viewer := viewerId(c)
+	usernames, next := pageKeys(usernames, after, limit)
+	users := h.store.ReadUsersByName(usernames)
+	c.JSON(http.StatusOK, newPage(h.newUsers(users, viewer), next))
+}
+
+// PUT /users/:username/follow
+func (h *Handler) Follow(c *gin.Context) {
+	h.setFollow(c, true)
+}
+
+// DELETE /users/:username/follow
+func (h *Handler) Unfollow(c *gin.Context) {
+	h.setFollow(c, false)
+}
+
+// Follows or unfollows, doing nothing when already in that state so
+// retries are safe
+func (h *Handler) setFollow(c *gin.Context, follow bool) {
+	user := h.pathUser(c)
+	if user == nil {
+		return
+	}
+	viewer := viewerId(c)
+	if user.Id == viewer {
+		abort(c, http.StatusBadRequest, codeBadRequest, "You can't follow yourself.")
+		return
+	}
+	if h.store.Followed(viewer, user.Id) != follow {
+		h.store.ToggleFollow(viewer, user.Id)
+		h.timeline.Followed(viewer, user.Id)
+	}
+	c.JSON(http.StatusOK, Item[Follow]{Data: Follow{Following: follow}})
+}
+
+// GET /search/users?q=
+func (h *Handler) SearchUsers(c *gin.Context) {
+	query := c.Query("q")
+	if query == "" {
+		abort(c, http.StatusBadRequest, codeBadRequest, "The q parameter is required.")
+		return
+	}
+	after, limit, ok := pageParams(c)
+	if !ok {
+		return
+	}
+	viewer := viewerId(c)
+	users, next := h.store.ReadUsers(query, after, limit)
+	c.JSON(http.StatusOK, newPage(h.newUsers(users, viewer), next))
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestFollow(t *testing.T) {
+	a := newTestAPI(t)
+	token := a.createUser(t, "alice", allScopes...)
+	a.createUser(t, "bob")
+
+	// Following twice is the same as once so retries are safe
+	for attempt := 0; attempt < 2; attempt++ {
+		var result Item[Follow]
+		if response := a.do(t, http.MethodPut, "/api/v1/users/bob/follow", token, "", &result); response.Code != http.StatusOK || !result.Data.Following {
+			t.Fatalf("PUT follow = %d %+v", response.Code, result.Data)
+		}
+	}
+	if following := a.store.ReadFollowing("alice-id"); len(following) != 1 {
+		t.Fatalf("following %v, want bob", following)
+	}
+	var user Item[User]
+	a.do(t, http.MethodGet, "/api/v1/users/bob", token, "", &user)
+	if user.Data.Followers != 1 || user.Data.FollowedByMe == nil || !*user.Data.FollowedByMe {
+		t.Errorf("GET user = %+v, want followed by alice", user.Data)
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if response := a.do(t, http.MethodDelete, "/api/v1/users/bob/follow", token, "", nil); response.Code != http.StatusOK {
+			t.Fatalf("DELETE follow = %d", response.Code)
+		}
+	}
+	if following := a.store.ReadFollowing("alice-id"); len(following) != 0 {
+		t.Errorf("following %v after unfollowing", following)
+	}
+}
+
+func TestFollowErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		username   string
+		wantStatus int
+		wantCode   string
+	}{
+		{"self", "alice", http.StatusBadRequest, codeBadRequest},
+		{"unknown user", "nobody", http.StatusNotFound, codeNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := newTestAPI(t)
+			token := a.createUser(t, "alice", allScopes...)
+			var result ErrorResponse
+			response := a.do(t, http.MethodPut, "/api/v1/users/"+test.username+"/follow", token, "", &result)
+			if response.Code != test.wantStatus || result.Error.Code != test.wantCode {
+				t.Errorf("PUT follow = %d %q, want %d %q", response.Code, result.Error.Code, test.wantStatus, test.wantCode)
+			}
+		})
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	a := newTestAPI(t)
+	for _, username := range []string{"alice", "alina", "bob"} {
+		a.createUser(t, username)
+	}
+
+	var first Page[User]
+	if response := a.do(t, http.MethodGet, "/api/v1/search/users?q=al&limit=1", "", "", &first); response.Code != http.StatusOK {
+		t.Fatalf("search = %d", response.Code)
+	}
+	if len(first.Data) != 1 || first.NextCursor == nil {
+		t.Fatalf("first page %+v, want one user and a cursor", first)
+	}
+	var second Page[User]
+	a.do(t, http.MethodGet, "/api/v1/search/users?q=al&limit=1&cursor="+*first.NextCursor, "", "", &second)
+	if len(second.Data) != 1 || second.Data[0].Username == first.Data[0].Username {
+		t.Errorf("second page %+v after %+v", second.Data, first.Data)
+	}
+
+	for _, query := range []string{"", "?q=al&limit=0", "?q=al&limit=101", "?q=al&cursor=%25"} {
+		if response := a.do(t, http.MethodGet, "/api/v1/search/users"+query, "", "", nil); response.Code != http.StatusBadRequest {
+			t.Errorf("search %q = %d, want 400", query, response.Code)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/internal/jobs"
+)
+
+const kindCleanup = "cleanup"
+
+// Deletes expired sessions, email tokens and pending logins every hour
+func scheduleCleanup(queue *jobs.Queue, store database.Store) {
+	queue.Register(kindCleanup, jobs.Kind{Run: func(ctx context.Context, payload []byte) error {
+		now := time.Now()
+		if !store.DeleteExpiredSessions(now) || !store.DeleteExpiredTokens(now) || !store.DeleteExpiredPendingLogins(now) {
+			return errors.New("unable to delete expired sessions, tokens and pending logins")
+		}
+		return nil
+	}})
+	queue.Every(kindCleanup, time.Hour)
+}
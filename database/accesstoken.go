@@ -0,0 +1,98 @@
+package database
+
+import (
+	"log"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/lib/pq"
+)
+
+func (p *Postgres) CreateAccessToken(token *models.AccessToken) bool {
+	if _, err := p.db.Exec(
+		`INSERT INTO access_tokens(id, user_id, name, token_hash, scopes, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		token.Id,
+		token.UserId,
+		token.Name,
+		token.Hash,
+		pq.Array(token.Scopes),
+		token.CreatedAt,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+func (p *Postgres) ReadAccessTokenByHash(hash string) *models.AccessToken {
+	var token models.AccessToken
+	if err := p.db.QueryRow(
+		`SELECT id, user_id, name, token_hash, scopes, created_at, last_used_at
+		FROM access_tokens WHERE token_hash = $1`, hash,
+	).Scan(
+		&token.Id,
+		&token.UserId,
+		&token.Name,
+		&token.Hash,
+		pq.Array(&token.Scopes),
+		&token.CreatedAt,
+		&token.LastUsedAt,
+	); err != nil {
+		return nil
+	}
+	return &token
+}
+
+// Returns the user's tokens, newest first
+func (p *Postgres) ReadAccessTokens(userId string) []models.AccessToken {
+	var tokens []models.AccessToken
+	rows, err := p.db.Query(
+		`SELECT id, user_id, name, token_hash, scopes, created_at, last_used_at
+		FROM access_tokens WHERE user_id = $1 ORDER BY created_at DESC`,
+		userId,
+	)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var token models.AccessToken
+		if err := rows.Scan(
+			&token.Id,
+			&token.UserId,
+			&token.Name,
+			&token.Hash,
+			pq.Array(&token.Scopes),
+			&token.CreatedAt,
+			&token.LastUsedAt,
+		); err != nil {
+			log.Println(err)
+			return nil
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func (p *Postgres) TouchAccessToken(id string, lastUsedAt time.Time) bool {
+	if _, err := p.db.Exec(
+		`UPDATE access_tokens SET last_used_at = $2 WHERE id = $1`, id, lastUsedAt,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// Revokes the token if it belongs to the user
+func (p *Postgres) DeleteAccessToken(userId string, id string) bool {
+	result, err := p.db.Exec(`DELETE FROM access_tokens WHERE id = $1 AND user_id = $2`, id, userId)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	count, _ := result.RowsAffected()
+	return count > 0
+}
@@ -0,0 +1,40 @@
+package database
+
+import "github.com/Bhar8at/bhar8at.github.io/models"
+
+// Trims a page read with one row past the limit, returning the cursor of
+// the next page when that extra row showed there is one
+func trimPage[T any](items []T, limit int, key func(T) models.Cursor) ([]T, *models.Cursor) {
+	if len(items) <= limit {
+		return items, nil
+	}
+	items = items[:limit]
+	next := key(items[limit-1])
+	return items, &next
+}
+
+func postCursor(post models.Post) models.Cursor {
+	return models.Cursor{CreatedAt: post.CreatedAt, Id: post.Id}
+}
+
+func commentCursor(comment models.Comment) models.Cursor {
+	return models.Cursor{CreatedAt: comment.CreatedAt, Id: comment.Id}
+}
+
+func revisionCursor(revision models.PostRevision) models.Cursor {
+	return models.Cursor{CreatedAt: revision.CreatedAt, Id: revision.Id}
+}
+
+// Users are listed by username, which is unique
+func userCursor(user models.User) models.Cursor {
+	return models.Cursor{Id: user.Username}
+}
+
+// Arguments for comparing (created_at, id) with the cursor in SQL, both
+// NULL for the first page
+func cursorArgs(after *models.Cursor) (any, any) {
+	if after == nil {
+		return nil, nil
+	}
+	return after.CreatedAt, after.Id
+}
@@ -0,0 +1,84 @@
+package database
+
+import (
+	"log"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func (p *Postgres) CreateIdentity(identity *models.Identity) bool {
+	if _, err := p.db.Exec(
+		`INSERT INTO identities(id, user_id, provider, subject, email, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		identity.Id,
+		identity.UserId,
+		identity.Provider,
+		identity.Subject,
+		identity.Email,
+		identity.CreatedAt,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+func (p *Postgres) ReadIdentity(provider string, subject string) *models.Identity {
+	var identity models.Identity
+	if err := p.db.QueryRow(
+		`SELECT id, user_id, provider, subject, email, created_at
+		FROM identities WHERE provider = $1 AND subject = $2`, provider, subject,
+	).Scan(
+		&identity.Id,
+		&identity.UserId,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+	); err != nil {
+		return nil
+	}
+	return &identity
+}
+
+// Returns the user's linked logins, oldest first
+func (p *Postgres) ReadIdentities(userId string) []models.Identity {
+	var identities []models.Identity
+	rows, err := p.db.Query(
+		`SELECT id, user_id, provider, subject, email, created_at
+		FROM identities WHERE user_id = $1 ORDER BY created_at`,
+		userId,
+	)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var identity models.Identity
+		if err := rows.Scan(
+			&identity.Id,
+			&identity.UserId,
+			&identity.Provider,
+			&identity.Subject,
+			&identity.Email,
+			&identity.CreatedAt,
+		); err != nil {
+			log.Println(err)
+			return nil
+		}
+		identities = append(identities, identity)
+	}
+	return identities
+}
+
+// Unlinks the login if it belongs to the user
+func (p *Postgres) DeleteIdentity(userId string, id string) bool {
+	result, err := p.db.Exec(`DELETE FROM identities WHERE id = $1 AND user_id = $2`, id, userId)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	count, _ := result.RowsAffected()
+	return count > 0
+}
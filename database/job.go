@@ -0,0 +1,195 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+const jobColumns = `id, kind, payload, attempts, max_attempts, run_at, locked_until, last_error, created_at`
+
+func scanJob(row interface{ Scan(...any) error }, job *models.Job) error {
+	return row.Scan(
+		&job.Id,
+		&job.Kind,
+		&job.Payload,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&job.RunAt,
+		&job.LockedUntil,
+		&job.LastError,
+		&job.CreatedAt,
+	)
+}
+
+func (p *Postgres) CreateJob(job *models.Job) bool {
+	if _, err := p.db.Exec(
+		`INSERT INTO jobs(id, kind, payload, attempts, max_attempts, run_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		job.Id,
+		job.Kind,
+		// pq sends byte slices as bytea, which JSONB won't take
+		string(job.Payload),
+		job.Attempts,
+		job.MaxAttempts,
+		job.RunAt,
+		job.CreatedAt,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// Locks the oldest due job of the kind until lockedUntil and counts the
+// attempt, returning nil when there's none. Jobs locked by other workers
+// are skipped rather than waited on.
+func (p *Postgres) ClaimJob(kind string, lockedUntil time.Time) *models.Job {
+	var job models.Job
+	if err := scanJob(p.db.QueryRow(
+		`UPDATE jobs SET attempts = attempts + 1, locked_until = $2
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE kind = $1 AND run_at <= NOW()
+			AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY run_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+jobColumns,
+		kind, lockedUntil,
+	), &job); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Println(err)
+		}
+		return nil
+	}
+	return &job
+}
+
+// Unlocks a failed job to run again at runAt
+func (p *Postgres) RetryJob(id string, runAt time.Time, lastError string) bool {
+	if _, err := p.db.Exec(
+		`UPDATE jobs SET run_at = $2, locked_until = NULL, last_error = $3 WHERE id = $1`,
+		id, runAt, lastError,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// Removes a finished job
+func (p *Postgres) DeleteJob(id string) bool {
+	if _, err := p.db.Exec(`DELETE FROM jobs WHERE id = $1`, id); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// Moves a job out of attempts to the dead jobs
+func (p *Postgres) BuryJob(id string, lastError string, failedAt time.Time) bool {
+	if _, err := p.db.Exec(
+		`WITH dead AS (DELETE FROM jobs WHERE id = $1 RETURNING *)
+		INSERT INTO dead_jobs(id, kind, payload, attempts, max_attempts, last_error, created_at, failed_at)
+		SELECT id, kind, payload, attempts, max_attempts, $2, created_at, $3 FROM dead`,
+		id, lastError, failedAt,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// Returns the jobs waiting or running, soonest first
+func (p *Postgres) ReadJobs(limit int) []models.Job {
+	var jobs []models.Job
+	rows, err := p.db.Query(
+		`SELECT `+jobColumns+` FROM jobs ORDER BY run_at LIMIT $1`, limit,
+	)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var job models.Job
+		if err := scanJob(rows, &job); err != nil {
+			log.Println(err)
+			return jobs
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// Returns the jobs that ran out of attempts, latest failure first
+func (p *Postgres) ReadDeadJobs(limit int) []models.Job {
+	var jobs []models.Job
+	rows, err := p.db.Query(
+		`SELECT id, kind, payload, attempts, max_attempts, last_error, created_at, failed_at
+		FROM dead_jobs ORDER BY failed_at DESC LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var job models.Job
+		if err := rows.Scan(
+			&job.Id,
+			&job.Kind,
+			&job.Payload,
+			&job.Attempts,
+			&job.MaxAttempts,
+			&job.LastError,
+			&job.CreatedAt,
+			&job.FailedAt,
+		); err != nil {
+			log.Println(err)
+			return jobs
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// Moves a dead job back to the queue with its attempts reset
+func (p *Postgres) RetryDeadJob(id string, runAt time.Time) bool {
+	result, err := p.db.Exec(
+		`WITH retried AS (DELETE FROM dead_jobs WHERE id = $1 RETURNING *)
+		INSERT INTO jobs(id, kind, payload, attempts, max_attempts, run_at, last_error, created_at)
+		SELECT id, kind, payload, 0, max_attempts, $2, last_error, created_at FROM retried`,
+		id, runAt,
+	)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	count, err := result.RowsAffected()
+	return err == nil && count > 0
+}
+
+// Takes the recurring job's next run when it is due, pushing it back to
+// next. Returns whether it was taken, which only one process will see.
+func (p *Postgres) ClaimSchedule(kind string, now time.Time, next time.Time) bool {
+	if err := p.db.QueryRow(
+		`INSERT INTO job_schedules(kind, next_run_at) VALUES ($1, $3)
+		ON CONFLICT (kind) DO UPDATE SET next_run_at = $3
+		WHERE job_schedules.next_run_at <= $2
+		RETURNING kind`,
+		kind, now, next,
+	).Scan(&kind); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Println(err)
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"log"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func (p *Postgres) CreateLoginAttempt(attempt *models.LoginAttempt) bool {
+	if err := p.db.QueryRow(
+		`INSERT INTO login_attempts(username, ip, user_agent, success, created_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		attempt.Username,
+		attempt.Ip,
+		attempt.UserAgent,
+		attempt.Success,
+		attempt.CreatedAt,
+	).Scan(&attempt.Id); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// Counts failed logins to the account since the later of since and its
+// last successful login
+func (p *Postgres) ReadLoginFailuresCount(username string, since time.Time) int {
+	var count int
+	if err := p.db.QueryRow(
+		`SELECT COUNT(*) FROM login_attempts
+		WHERE username = $1 AND NOT success AND created_at > $2 AND created_at > COALESCE(
+			(SELECT MAX(created_at) FROM login_attempts WHERE username = $1 AND success), '-infinity'
+		)`,
+		username, since,
+	).Scan(&count); err != nil {
+		log.Println(err)
+	}
+	return count
+}
+
+// Counts failed logins from the IP address since the given time
+func (p *Postgres) ReadIpLoginFailuresCount(ip string, since time.Time) int {
+	var count int
+	if err := p.db.QueryRow(
+		`SELECT COUNT(*) FROM login_attempts WHERE ip = $1 AND NOT success AND created_at > $2`,
+		ip, since,
+	).Scan(&count); err != nil {
+		log.Println(err)
+	}
+	return count
+}
+
+// Returns failed logins, most recent first
+func (p *Postgres) ReadFailedLoginAttempts(limit int, offset int) []models.LoginAttempt {
+	var attempts []models.LoginAttempt
+	rows, err := p.db.Query(
+		`SELECT id, username, ip, user_agent, success, created_at FROM login_attempts
+		WHERE NOT success ORDER BY created_at DESC, id DESC LIMIT $1 OFFSET $2`,
+		limit, offset,
+	)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var attempt models.LoginAttempt
+		if err := rows.Scan(
+			&attempt.Id,
+			&attempt.Username,
+			&attempt.Ip,
+			&attempt.UserAgent,
+			&attempt.Success,
+			&attempt.CreatedAt,
+		); err != nil {
+			log.Println(err)
+			return nil
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts
+}
+
+// Creates the lockout, or replaces the earlier one for the same key
+func (p *Postgres) CreateLockout(lockout *models.Lockout) bool {
+	if _, err := p.db.Exec(
+		`INSERT INTO lockouts(scope, key, failures, locked_until, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (scope, key) DO UPDATE
+		SET failures = EXCLUDED.failures, locked_until = EXCLUDED.locked_until, created_at = EXCLUDED.created_at`,
+		lockout.Scope,
+		lockout.Key,
+		lockout.Failures,
+		lockout.LockedUntil,
+		lockout.CreatedAt,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// Returns the lockout if it hasn't ended yet
+func (p *Postgres) ReadLockout(scope string, key string) *models.Lockout {
+	var lockout models.Lockout
+	if err := p.db.QueryRow(
+		`SELECT scope, key, failures, locked_until, created_at FROM lockouts
+		WHERE scope = $1 AND key = $2 AND locked_until > NOW()`,
+		scope, key,
+	).Scan(
+		&lockout.Scope,
+		&lockout.Key,
+		&lockout.Failures,
+		&lockout.LockedUntil,
+		&lockout.CreatedAt,
+	); err != nil {
+		return nil
+	}
+	return &lockout
+}
+
+// Returns the lockouts that haven't ended, ending soonest first
+func (p *Postgres) ReadLockouts() []models.Lockout {
+	var lockouts []models.Lockout
+	rows, err := p.db.Query(
+		`SELECT scope, key, failures, locked_until, created_at FROM lockouts
+		WHERE locked_until > NOW() ORDER BY locked_until`,
+	)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var lockout models.Lockout
+		if err := rows.Scan(
+			&lockout.Scope,
+			&lockout.Key,
+			&lockout.Failures,
+			&lockout.LockedUntil,
+			&lockout.CreatedAt,
+		); err != nil {
+			log.Println(err)
+			return nil
+		}
+		lockouts = append(lockouts, lockout)
+	}
+	return lockouts
+}
+
+func (p *Postgres) DeleteLockout(scope string, key string) bool {
+	if _, err := p.db.Exec(`DELETE FROM lockouts WHERE scope = $1 AND key = $2`, scope, key); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/lib/pq"
+)
+
+// References are counted by triggers on post_media and t_users, by the key
+// at the end of each URL, so only the files themselves are written here
+func (p *Postgres) TouchMediaObject(object *models.MediaObject) bool {
+	if _, err := p.db.Exec(
+		`INSERT INTO media_objects(key, size, created_at, touched_at)
+		VALUES ($1, $2, $3, $3)
+		ON CONFLICT (key) DO UPDATE SET touched_at = EXCLUDED.touched_at`,
+		object.Key,
+		object.Size,
+		object.TouchedAt,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// Uses are counted here since the triggers only count them for recorded
+// files, skipping files already recorded before counting
+func (p *Postgres) TrackMediaObjects(objects []models.MediaObject) int {
+	if len(objects) == 0 {
+		return 0
+	}
+	keys := make([]string, len(objects))
+	sizes := make([]int64, len(objects))
+	touched := make([]string, len(objects))
+	for i, object := range objects {
+		keys[i] = object.Key
+		sizes[i] = object.Size
+		touched[i] = object.TouchedAt.Format(time.RFC3339Nano)
+	}
+	result, err := p.db.Exec(
+		`INSERT INTO media_objects(key, size, refs, created_at, touched_at)
+		SELECT files.key, files.size,
+			(SELECT COUNT(*) FROM post_media
+			WHERE files.key IN (media_key(url), media_key(medium_url), media_key(thumbnail_url)))
+			+ (SELECT COUNT(*) FROM t_users WHERE media_key(avatar) = files.key),
+			files.touched_at, files.touched_at
+		FROM unnest($1::TEXT[], $2::BIGINT[], $3::TIMESTAMPTZ[]) AS files(key, size, touched_at)
+		WHERE NOT EXISTS (SELECT 1 FROM media_objects WHERE media_objects.key = files.key)
+		ON CONFLICT (key) DO NOTHING`,
+		pq.Array(keys),
+		pq.Array(sizes),
+		pq.Array(touched),
+	)
+	if err != nil {
+		log.Println(err)
+		return 0
+	}
+	tracked, err := result.RowsAffected()
+	if err != nil {
+		log.Println(err)
+		return 0
+	}
+	return int(tracked)
+}
+
+func (p *Postgres) ReadUnreferencedMediaObjects(before time.Time, limit int) []models.MediaObject {
+	rows, err := p.db.Query(
+		`SELECT key, size, refs, created_at, touched_at FROM media_objects
+		WHERE refs = 0 AND touched_at < $1
+		ORDER BY touched_at
+		LIMIT $2`,
+		before, limit,
+	)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	defer rows.Close()
+	var objects []models.MediaObject
+	for rows.Next() {
+		var object models.MediaObject
+		if err := rows.Scan(
+			&object.Key,
+			&object.Size,
+			&object.Refs,
+			&object.CreatedAt,
+			&object.TouchedAt,
+		); err != nil {
+			log.Println(err)
+			return nil
+		}
+		objects = append(objects, object)
+	}
+	return objects
+}
+
+// The row stays locked while the file is removed, so an upload of the same
+// content waits and then stores the file again under a new row
+func (p *Postgres) DeleteMediaObject(key string, before time.Time, remove func() error) bool {
+	tx, err := p.db.Begin()
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	defer tx.Rollback()
+	if err := tx.QueryRow(
+		`SELECT key FROM media_objects
+		WHERE key = $1 AND refs = 0 AND touched_at < $2
+		FOR UPDATE`,
+		key, before,
+	).Scan(&key); err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Println(err)
+		}
+		return false
+	}
+	if err := remove(); err != nil {
+		log.Println(err)
+		return false
+	}
+	if _, err := tx.Exec(`DELETE FROM media_objects WHERE key = $1`, key); err != nil {
+		log.Println(err)
+		return false
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+func (p *Postgres) CreateQuarantinedUpload(upload *models.QuarantinedUpload) bool {
+	if _, err := p.db.Exec(
+		`INSERT INTO quarantined_uploads(id, user_id, name, key, reason, size, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		upload.Id,
+		upload.UserId,
+		upload.Name,
+		upload.Key,
+		upload.Reason,
+		upload.Size,
+		upload.CreatedAt,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+func (p *Postgres) ReadQuarantinedUploads(limit int) []models.QuarantinedUpload {
+	rows, err := p.db.Query(
+		`SELECT id, user_id, name, key, reason, size, created_at FROM quarantined_uploads
+		ORDER BY created_at DESC
+		LIMIT $1`,
+		limit,
+	)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	defer rows.Close()
+	var uploads []models.QuarantinedUpload
+	for rows.Next() {
+		var upload models.QuarantinedUpload
+		if err := rows.Scan(
+			&upload.Id,
+			&upload.UserId,
+			&upload.Name,
+			&upload.Key,
+			&upload.Reason,
+			&upload.Size,
+			&upload.CreatedAt,
+		); err != nil {
+			log.Println(err)
+			return nil
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads
+}
@@ -0,0 +1,689 @@
+package database
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+// Memory is a Store that keeps everything in process memory, used for
+// running the app and its tests without a Postgres server
+type Memory struct {
+	mu       sync.RWMutex
+	users    map[string]models.User
+	oauth    map[string]bool
+	posts    map[string]models.Post
+	comments map[string]models.Comment
+	// revisions[postId]
+	revisions map[string][]models.PostRevision
+	// votes[postId][userId]
+	votes map[string]map[string]bool
+	// follows[userId][followId]
+	follows  map[string]map[string]bool
+	tokens   map[string]models.Token
+	sessions map[string]models.Session
+	admins   map[string]bool
+	// twoFactor[userId]
+	twoFactor map[string]models.TwoFactor
+	// recoveryCodes[userId][codeHash]
+	recoveryCodes map[string]map[string]bool
+	pendingLogins map[string]models.PendingLogin
+	identities    map[string]models.Identity
+	loginAttempts []models.LoginAttempt
+	// lockouts[scope:key]
+	lockouts     map[string]models.Lockout
+	accessTokens map[string]models.AccessToken
+	// timelines[userId][postId]
+	timelines    map[string]map[string]bool
+	fanOutOnRead map[string]bool
+	jobs         map[string]models.Job
+	deadJobs     map[string]models.Job
+	// jobSchedules[kind] is when the recurring job is next due
+	jobSchedules map[string]time.Time
+	// mediaObjects[key], referenced as counted by mediaRefs
+	mediaObjects map[string]models.MediaObject
+	quarantine   []models.QuarantinedUpload
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		users:         make(map[string]models.User),
+		oauth:         make(map[string]bool),
+		posts:         make(map[string]models.Post),
+		comments:      make(map[string]models.Comment),
+		revisions:     make(map[string][]models.PostRevision),
+		votes:         make(map[string]map[string]bool),
+		follows:       make(map[string]map[string]bool),
+		tokens:        make(map[string]models.Token),
+		sessions:      make(map[string]models.Session),
+		admins:        make(map[string]bool),
+		twoFactor:     make(map[string]models.TwoFactor),
+		recoveryCodes: make(map[string]map[string]bool),
+		pendingLogins: make(map[string]models.PendingLogin),
+		identities:    make(map[string]models.Identity),
+		lockouts:      make(map[string]models.Lockout),
+		accessTokens:  make(map[string]models.AccessToken),
+		timelines:     make(map[string]map[string]bool),
+		fanOutOnRead:  make(map[string]bool),
+		jobs:          make(map[string]models.Job),
+		deadJobs:      make(map[string]models.Job),
+		jobSchedules:  make(map[string]time.Time),
+		mediaObjects:  make(map[string]models.MediaObject),
+	}
+}
+
+// Users
+
+func (m *Memory) CreateUser(user *models.User) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.users {
+		if existing.Id == user.Id || existing.Username == user.Username ||
+			(existing.Email != nil && user.Email != nil && *existing.Email == *user.Email) {
+			log.Println("user already exists:", user.Username)
+			return false
+		}
+	}
+	m.users[user.Id] = *user
+	return true
+}
+
+func (m *Memory) CreateOAuthUser(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[id]; !ok || m.oauth[id] {
+		return false
+	}
+	m.oauth[id] = true
+	return true
+}
+
+func (m *Memory) ReadUserByName(username string) *models.User {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, user := range m.users {
+		if user.Username == username {
+			return &user
+		}
+	}
+	return nil
+}
+
+func (m *Memory) ReadUsersByName(usernames []string) []models.User {
+	wanted := make(map[string]bool, len(usernames))
+	for _, username := range usernames {
+		wanted[username] = true
+	}
+	m.mu.RLock()
+	var users []models.User
+	for _, user := range m.users {
+		if wanted[user.Username] {
+			users = append(users, user)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return users
+}
+
+func (m *Memory) ReadUserByEmail(email string) *models.User {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, user := range m.users {
+		if user.Email != nil && *user.Email == email {
+			return &user
+		}
+	}
+	return nil
+}
+
+func (m *Memory) ReadUserById(id string) *models.User {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	user, ok := m.users[id]
+	if !ok {
+		return nil
+	}
+	return &user
+}
+
+func (m *Memory) IsOAuthUser(id string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.oauth[id]
+}
+
+func (m *Memory) DeleteOAuthUser(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.oauth, id)
+	return true
+}
+
+func (m *Memory) CreateAdmin(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[id]; !ok {
+		return false
+	}
+	m.admins[id] = true
+	return true
+}
+
+func (m *Memory) DeleteAdmin(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.admins, id)
+	return true
+}
+
+func (m *Memory) IsAdmin(id string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.admins[id]
+}
+
+func (m *Memory) ReadUsers(username string, after *models.Cursor, limit int) ([]models.User, *models.Cursor) {
+	m.mu.RLock()
+	var users []models.User
+	for _, user := range m.users {
+		if strings.Contains(user.Username, username) && (after == nil || user.Username > after.Id) {
+			users = append(users, user)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	return trimPage(limited(users, limit+1), limit, userCursor)
+}
+
+func (m *Memory) UpdateUser(id string, updates map[string]any) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, ok := m.users[id]
+	if !ok {
+		return false
+	}
+	for column, value := range updates {
+		switch column {
+		case "email":
+			email, ok := value.(string)
+			if !ok {
+				return false
+			}
+			user.Email = &email
+		case "username":
+			username, ok := value.(string)
+			if !ok {
+				return false
+			}
+			for _, existing := range m.users {
+				if existing.Id != id && existing.Username == username {
+					return false
+				}
+			}
+			user.Username = username
+		case "password":
+			password, ok := value.(string)
+			if !ok {
+				return false
+			}
+			user.Password = password
+		case "verified":
+			verified, ok := value.(bool)
+			if !ok {
+				return false
+			}
+			user.Verified = verified
+		case "avatar":
+			avatar, ok := value.(string)
+			if !ok {
+				return false
+			}
+			user.Avatar = &avatar
+		default:
+			log.Println("unknown user column:", column)
+			return false
+		}
+	}
+	m.users[id] = user
+	return true
+}
+
+func (m *Memory) DeleteUser(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[id]; !ok {
+		return true
+	}
+	delete(m.users, id)
+	delete(m.oauth, id)
+	delete(m.admins, id)
+	delete(m.twoFactor, id)
+	delete(m.recoveryCodes, id)
+	for pendingId, pending := range m.pendingLogins {
+		if pending.UserId == id {
+			delete(m.pendingLogins, pendingId)
+		}
+	}
+	for identityId, identity := range m.identities {
+		if identity.UserId == id {
+			delete(m.identities, identityId)
+		}
+	}
+	for tokenId, token := range m.accessTokens {
+		if token.UserId == id {
+			delete(m.accessTokens, tokenId)
+		}
+	}
+	m.deleteSessions(id)
+	delete(m.timelines, id)
+	delete(m.fanOutOnRead, id)
+	delete(m.follows, id)
+	for _, followed := range m.follows {
+		delete(followed, id)
+	}
+	for _, voters := range m.votes {
+		delete(voters, id)
+	}
+	for tokenId, token := range m.tokens {
+		if token.UserId == id {
+			delete(m.tokens, tokenId)
+		}
+	}
+	for commentId, comment := range m.comments {
+		if comment.UserId == id {
+			delete(m.comments, commentId)
+		}
+	}
+	for postId, post := range m.posts {
+		if post.UserId == id {
+			m.deletePost(postId)
+		}
+	}
+	return true
+}
+
+// Follows
+
+func (m *Memory) Followed(userId string, followId string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.follows[userId][followId]
+}
+
+func (m *Memory) ToggleFollow(userId string, followId string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.follows[userId][followId] {
+		delete(m.follows[userId], followId)
+		return
+	}
+	_, userExists := m.users[userId]
+	_, followExists := m.users[followId]
+	if !userExists || !followExists {
+		log.Println("unable to follow, user does not exist")
+		return
+	}
+	if m.follows[userId] == nil {
+		m.follows[userId] = make(map[string]bool)
+	}
+	m.follows[userId][followId] = true
+}
+
+func (m *Memory) ReadFollowers(userId string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var followers []string
+	for followerId, followed := range m.follows {
+		if followed[userId] {
+			followers = append(followers, m.users[followerId].Username)
+		}
+	}
+	return followers
+}
+
+func (m *Memory) ReadFollowersCount(userId string) int {
+	return len(m.ReadFollowers(userId))
+}
+
+func (m *Memory) ReadUserStats(userId string, ids []string) map[string]models.UserStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	stats := make(map[string]models.UserStats, len(ids))
+	for _, id := range ids {
+		if _, ok := m.users[id]; !ok {
+			continue
+		}
+		stat := models.UserStats{
+			Following: len(m.follows[id]),
+			Followed:  m.follows[userId][id],
+		}
+		for _, followed := range m.follows {
+			if followed[id] {
+				stat.Followers++
+			}
+		}
+		for _, post := range m.posts {
+			if post.UserId == id {
+				stat.Posts++
+			}
+		}
+		stats[id] = stat
+	}
+	return stats
+}
+
+func (m *Memory) ReadFollowing(userId string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var following []string
+	for followId := range m.follows[userId] {
+		following = append(following, m.users[followId].Username)
+	}
+	return following
+}
+
+func (m *Memory) ReadFollowingCount(userId string) int {
+	return len(m.ReadFollowing(userId))
+}
+
+// Posts
+
+func (m *Memory) CreatePost(userId string, post *models.Post) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[userId]; !ok {
+		log.Println("Error inserting post into database: user does not exist")
+		return false
+	}
+	if _, ok := m.posts[post.Id]; ok {
+		log.Println("Error inserting post into database: post already exists")
+		return false
+	}
+	stored := *post
+	stored.UserId = userId
+	stored.Username = ""
+	stored.Avatar = nil
+	stored.Media = make([]models.PostMedia, len(post.Media))
+	for index, media := range post.Media {
+		media.PostId = post.Id
+		stored.Media[index] = media
+	}
+	m.posts[post.Id] = stored
+	return true
+}
+
+func (m *Memory) ReadPost(id string) *models.Post {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	post, ok := m.posts[id]
+	if !ok {
+		return nil
+	}
+	m.withPostAuthor(&post)
+	return &post
+}
+
+// Fills in the author's username and avatar, like the join in Postgres.
+// Must be called with the lock held.
+func (m *Memory) withPostAuthor(post *models.Post) {
+	author := m.users[post.UserId]
+	post.Username = author.Username
+	post.Avatar = author.Avatar
+}
+
+func (m *Memory) ReadPostsCount(userId string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var count int
+	for _, post := range m.posts {
+		if post.UserId == userId {
+			count++
+		}
+	}
+	return count
+}
+
+func (m *Memory) ReadPosts(userId string, after *models.Cursor, limit int) ([]models.Post, *models.Cursor) {
+	return m.readPosts(func(post models.Post) bool {
+		return post.UserId == userId
+	}, after, limit)
+}
+
+func (m *Memory) ReadFeedPosts(userId string, after *models.Cursor, limit int) ([]models.Post, *models.Cursor) {
+	m.mu.RLock()
+	timeline := make(map[string]bool, len(m.timelines[userId]))
+	for postId := range m.timelines[userId] {
+		timeline[postId] = true
+	}
+	onRead := make(map[string]bool)
+	for followId := range m.follows[userId] {
+		if m.fanOutOnRead[followId] {
+			onRead[followId] = true
+		}
+	}
+	m.mu.RUnlock()
+	return m.readPosts(func(post models.Post) bool {
+		return timeline[post.Id] || onRead[post.UserId]
+	}, after, limit)
+}
+
+// Returns the page of posts matching filter after the cursor, newest first
+func (m *Memory) readPosts(filter func(models.Post) bool, after *models.Cursor, limit int) ([]models.Post, *models.Cursor) {
+	m.mu.RLock()
+	var posts []models.Post
+	for _, post := range m.posts {
+		if filter(post) && after.After(post.CreatedAt, post.Id) {
+			m.withPostAuthor(&post)
+			posts = append(posts, post)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(posts, func(i, j int) bool { return newer(posts[i].CreatedAt, posts[i].Id, posts[j].CreatedAt, posts[j].Id) })
+	return trimPage(limited(posts, limit+1), limit, postCursor)
+}
+
+func (m *Memory) EditPost(id string, body string, editedAt time.Time, revisionId string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	post, ok := m.posts[id]
+	if !ok {
+		return true
+	}
+	writtenAt := post.CreatedAt
+	if post.EditedAt != nil {
+		writtenAt = *post.EditedAt
+	}
+	m.revisions[id] = append(m.revisions[id], models.PostRevision{
+		Id:        revisionId,
+		PostId:    id,
+		Body:      post.Body,
+		CreatedAt: writtenAt,
+	})
+	post.Body = body
+	post.EditedAt = &editedAt
+	m.posts[id] = post
+	return true
+}
+
+func (m *Memory) ReadPostRevisions(postId string, after *models.Cursor, limit int) ([]models.PostRevision, *models.Cursor) {
+	m.mu.RLock()
+	var revisions []models.PostRevision
+	for _, revision := range m.revisions[postId] {
+		if after.After(revision.CreatedAt, revision.Id) {
+			revisions = append(revisions, revision)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(revisions, func(i, j int) bool {
+		return newer(revisions[i].CreatedAt, revisions[i].Id, revisions[j].CreatedAt, revisions[j].Id)
+	})
+	return trimPage(limited(revisions, limit+1), limit, revisionCursor)
+}
+
+func (m *Memory) DeletePost(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deletePost(id)
+	return true
+}
+
+// Removes a post along with its votes and comments, m.mu must be held
+func (m *Memory) deletePost(id string) {
+	delete(m.posts, id)
+	delete(m.revisions, id)
+	delete(m.votes, id)
+	for _, timeline := range m.timelines {
+		delete(timeline, id)
+	}
+	for commentId, comment := range m.comments {
+		if comment.PostId == id {
+			delete(m.comments, commentId)
+		}
+	}
+}
+
+// Votes
+
+func (m *Memory) Voted(userId string, id string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.votes[id][userId]
+}
+
+func (m *Memory) ToggleVote(userId string, id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.votes[id][userId] {
+		delete(m.votes[id], userId)
+		return
+	}
+	_, userExists := m.users[userId]
+	_, postExists := m.posts[id]
+	if !userExists || !postExists {
+		log.Println("unable to vote, user or post does not exist")
+		return
+	}
+	if m.votes[id] == nil {
+		m.votes[id] = make(map[string]bool)
+	}
+	m.votes[id][userId] = true
+}
+
+func (m *Memory) ReadVotes(id string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var voters []string
+	for userId := range m.votes[id] {
+		voters = append(voters, m.users[userId].Username)
+	}
+	return voters
+}
+
+func (m *Memory) ReadPostVotes(userId string, ids []string) map[string]models.PostVotes {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	votes := make(map[string]models.PostVotes)
+	for _, id := range ids {
+		if count := len(m.votes[id]); count > 0 {
+			votes[id] = models.PostVotes{Count: count, Voted: m.votes[id][userId]}
+		}
+	}
+	return votes
+}
+
+// Comments
+
+func (m *Memory) CreateComment(userId string, postId string, comment *models.Comment) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, userExists := m.users[userId]
+	_, postExists := m.posts[postId]
+	if !userExists || !postExists {
+		log.Println("unable to comment, user or post does not exist")
+		return false
+	}
+	if _, ok := m.comments[comment.Id]; ok {
+		return false
+	}
+	stored := *comment
+	stored.UserId = userId
+	stored.PostId = postId
+	stored.Username = ""
+	stored.Self = false
+	m.comments[comment.Id] = stored
+	return true
+}
+
+// Must be called with the lock held
+func (m *Memory) withCommentAuthor(comment *models.Comment) {
+	author := m.users[comment.UserId]
+	comment.Username = author.Username
+	comment.Avatar = author.Avatar
+}
+
+func (m *Memory) ReadComment(id string) *models.Comment {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	comment, ok := m.comments[id]
+	if !ok {
+		return nil
+	}
+	m.withCommentAuthor(&comment)
+	return &comment
+}
+
+func (m *Memory) ReadComments(postId string, after *models.Cursor, limit int) ([]models.Comment, *models.Cursor) {
+	m.mu.RLock()
+	var comments []models.Comment
+	for _, comment := range m.comments {
+		if comment.PostId == postId && after.After(comment.CreatedAt, comment.Id) {
+			m.withCommentAuthor(&comment)
+			comments = append(comments, comment)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(comments, func(i, j int) bool {
+		return newer(comments[i].CreatedAt, comments[i].Id, comments[j].CreatedAt, comments[j].Id)
+	})
+	return trimPage(limited(comments, limit+1), limit, commentCursor)
+}
+
+func (m *Memory) DeleteComment(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.comments, id)
+	return true
+}
+
+// Orders by (createdAt, id) descending, the order of paged lists
+func newer(createdAt time.Time, id string, otherCreatedAt time.Time, otherId string) bool {
+	if !createdAt.Equal(otherCreatedAt) {
+		return createdAt.After(otherCreatedAt)
+	}
+	return id > otherId
+}
+
+// Applies LIMIT to an already sorted slice
+func limited[T any](items []T, limit int) []T {
+	if limit < len(items) {
+		return items[:limit]
+	}
+	return items
+}
+
+// Applies LIMIT and OFFSET to an already sorted slice
+func page[T any](items []T, limit int, offset int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func (m *Memory) CreateAccessToken(token *models.AccessToken) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[token.UserId]; !ok {
+		return false
+	}
+	for id, existing := range m.accessTokens {
+		if id == token.Id || existing.Hash == token.Hash {
+			return false
+		}
+	}
+	stored := *token
+	stored.Scopes = append([]string(nil), token.Scopes...)
+	m.accessTokens[token.Id] = stored
+	return true
+}
+
+func (m *Memory) ReadAccessTokenByHash(hash string) *models.AccessToken {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, token := range m.accessTokens {
+		if token.Hash == hash {
+			return &token
+		}
+	}
+	return nil
+}
+
+func (m *Memory) ReadAccessTokens(userId string) []models.AccessToken {
+	m.mu.RLock()
+	var tokens []models.AccessToken
+	for _, token := range m.accessTokens {
+		if token.UserId == userId {
+			tokens = append(tokens, token)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].CreatedAt.After(tokens[j].CreatedAt) })
+	return tokens
+}
+
+func (m *Memory) TouchAccessToken(id string, lastUsedAt time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token, ok := m.accessTokens[id]
+	if !ok {
+		return false
+	}
+	token.LastUsedAt = &lastUsedAt
+	m.accessTokens[id] = token
+	return true
+}
+
+func (m *Memory) DeleteAccessToken(userId string, id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token, ok := m.accessTokens[id]
+	if !ok || token.UserId != userId {
+		return false
+	}
+	delete(m.accessTokens, id)
+	return true
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"sort"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func (m *Memory) CreateIdentity(identity *models.Identity) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[identity.UserId]; !ok {
+		return false
+	}
+	for id, existing := range m.identities {
+		if id == identity.Id || (existing.Provider == identity.Provider && existing.Subject == identity.Subject) {
+			return false
+		}
+	}
+	m.identities[identity.Id] = *identity
+	return true
+}
+
+func (m *Memory) ReadIdentity(provider string, subject string) *models.Identity {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, identity := range m.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return &identity
+		}
+	}
+	return nil
+}
+
+func (m *Memory) ReadIdentities(userId string) []models.Identity {
+	m.mu.RLock()
+	var identities []models.Identity
+	for _, identity := range m.identities {
+		if identity.UserId == userId {
+			identities = append(identities, identity)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(identities, func(i, j int) bool { return identities[i].CreatedAt.Before(identities[j].CreatedAt) })
+	return identities
+}
+
+func (m *Memory) DeleteIdentity(userId string, id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	identity, ok := m.identities[id]
+	if !ok || identity.UserId != userId {
+		return false
+	}
+	delete(m.identities, id)
+	return true
+}
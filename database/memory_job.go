@@ -0,0 +1,126 @@
+package database
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func (m *Memory) CreateJob(job *models.Job) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.jobs[job.Id]; ok {
+		return false
+	}
+	stored := *job
+	stored.Payload = append([]byte(nil), job.Payload...)
+	m.jobs[job.Id] = stored
+	return true
+}
+
+func (m *Memory) ClaimJob(kind string, lockedUntil time.Time) *models.Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	var claimed *models.Job
+	for _, job := range m.jobs {
+		if job.Kind != kind || job.RunAt.After(now) || (job.LockedUntil != nil && job.LockedUntil.After(now)) {
+			continue
+		}
+		if claimed == nil || job.RunAt.Before(claimed.RunAt) {
+			job := job
+			claimed = &job
+		}
+	}
+	if claimed == nil {
+		return nil
+	}
+	claimed.Attempts++
+	claimed.LockedUntil = &lockedUntil
+	m.jobs[claimed.Id] = *claimed
+	return claimed
+}
+
+func (m *Memory) RetryJob(id string, runAt time.Time, lastError string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return true
+	}
+	job.RunAt = runAt
+	job.LockedUntil = nil
+	job.LastError = &lastError
+	m.jobs[id] = job
+	return true
+}
+
+func (m *Memory) DeleteJob(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.jobs, id)
+	return true
+}
+
+func (m *Memory) BuryJob(id string, lastError string, failedAt time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return true
+	}
+	delete(m.jobs, id)
+	job.LockedUntil = nil
+	job.LastError = &lastError
+	job.FailedAt = &failedAt
+	m.deadJobs[id] = job
+	return true
+}
+
+func (m *Memory) ReadJobs(limit int) []models.Job {
+	m.mu.RLock()
+	jobs := make([]models.Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		jobs = append(jobs, job)
+	}
+	m.mu.RUnlock()
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].RunAt.Before(jobs[j].RunAt) })
+	return limited(jobs, limit)
+}
+
+func (m *Memory) ReadDeadJobs(limit int) []models.Job {
+	m.mu.RLock()
+	jobs := make([]models.Job, 0, len(m.deadJobs))
+	for _, job := range m.deadJobs {
+		jobs = append(jobs, job)
+	}
+	m.mu.RUnlock()
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].FailedAt.After(*jobs[j].FailedAt) })
+	return limited(jobs, limit)
+}
+
+func (m *Memory) RetryDeadJob(id string, runAt time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.deadJobs[id]
+	if !ok {
+		return false
+	}
+	delete(m.deadJobs, id)
+	job.Attempts = 0
+	job.RunAt = runAt
+	job.FailedAt = nil
+	m.jobs[id] = job
+	return true
+}
+
+func (m *Memory) ClaimSchedule(kind string, now time.Time, next time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if due, ok := m.jobSchedules[kind]; ok && due.After(now) {
+		return false
+	}
+	m.jobSchedules[kind] = next
+	return true
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func (m *Memory) CreateLoginAttempt(attempt *models.LoginAttempt) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	attempt.Id = int64(len(m.loginAttempts) + 1)
+	m.loginAttempts = append(m.loginAttempts, *attempt)
+	return true
+}
+
+func (m *Memory) ReadLoginFailuresCount(username string, since time.Time) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	count := 0
+	for _, attempt := range m.loginAttempts {
+		if attempt.Username != username {
+			continue
+		}
+		if attempt.Success {
+			count = 0
+		} else if attempt.CreatedAt.After(since) {
+			count++
+		}
+	}
+	return count
+}
+
+func (m *Memory) ReadIpLoginFailuresCount(ip string, since time.Time) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	count := 0
+	for _, attempt := range m.loginAttempts {
+		if attempt.Ip == ip && !attempt.Success && attempt.CreatedAt.After(since) {
+			count++
+		}
+	}
+	return count
+}
+
+func (m *Memory) ReadFailedLoginAttempts(limit int, offset int) []models.LoginAttempt {
+	m.mu.RLock()
+	var attempts []models.LoginAttempt
+	for index := len(m.loginAttempts) - 1; index >= 0; index-- {
+		if !m.loginAttempts[index].Success {
+			attempts = append(attempts, m.loginAttempts[index])
+		}
+	}
+	m.mu.RUnlock()
+	return page(attempts, limit, offset)
+}
+
+func (m *Memory) CreateLockout(lockout *models.Lockout) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lockouts[lockout.Scope+":"+lockout.Key] = *lockout
+	return true
+}
+
+func (m *Memory) ReadLockout(scope string, key string) *models.Lockout {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	lockout, ok := m.lockouts[scope+":"+key]
+	if !ok || !lockout.LockedUntil.After(time.Now()) {
+		return nil
+	}
+	return &lockout
+}
+
+func (m *Memory) ReadLockouts() []models.Lockout {
+	m.mu.RLock()
+	var lockouts []models.Lockout
+	now := time.Now()
+	for _, lockout := range m.lockouts {
+		if lockout.LockedUntil.After(now) {
+			lockouts = append(lockouts, lockout)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(lockouts, func(i, j int) bool { return lockouts[i].LockedUntil.Before(lockouts[j].LockedUntil) })
+	return lockouts
+}
+
+func (m *Memory) DeleteLockout(scope string, key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.lockouts, scope+":"+key)
+	return true
+}
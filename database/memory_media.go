@@ -0,0 +1,132 @@
+package database
+
+import (
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func (m *Memory) TouchMediaObject(object *models.MediaObject) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if stored, ok := m.mediaObjects[object.Key]; ok {
+		stored.TouchedAt = object.TouchedAt
+		m.mediaObjects[object.Key] = stored
+		return true
+	}
+	stored := *object
+	stored.Refs = 0
+	stored.CreatedAt = object.TouchedAt
+	m.mediaObjects[object.Key] = stored
+	return true
+}
+
+func (m *Memory) TrackMediaObjects(objects []models.MediaObject) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tracked := 0
+	for _, object := range objects {
+		if _, ok := m.mediaObjects[object.Key]; ok {
+			continue
+		}
+		object.Refs = 0
+		object.CreatedAt = object.TouchedAt
+		m.mediaObjects[object.Key] = object
+		tracked++
+	}
+	return tracked
+}
+
+func (m *Memory) ReadUnreferencedMediaObjects(before time.Time, limit int) []models.MediaObject {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	refs := m.mediaRefs()
+	var objects []models.MediaObject
+	for _, object := range m.mediaObjects {
+		if refs[object.Key] == 0 && object.TouchedAt.Before(before) {
+			objects = append(objects, object)
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].TouchedAt.Before(objects[j].TouchedAt)
+	})
+	if len(objects) > limit {
+		objects = objects[:limit]
+	}
+	return objects
+}
+
+func (m *Memory) DeleteMediaObject(key string, before time.Time, remove func() error) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	object, ok := m.mediaObjects[key]
+	if !ok || m.mediaRefs()[object.Key] != 0 || !object.TouchedAt.Before(before) {
+		return false
+	}
+	if err := remove(); err != nil {
+		return false
+	}
+	delete(m.mediaObjects, key)
+	return true
+}
+
+// Key in the media store of a URL the app made, empty for anything else,
+// the same as the media_key function in Postgres
+func mediaKey(url string) string {
+	for _, pattern := range mediaKeyPatterns {
+		if match := pattern.FindStringSubmatch(url); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
+
+var mediaKeyPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`/((?:media|posts|avatars)/[^/?#]+)$`),
+	regexp.MustCompile(`/uploads/([0-9]+[^/?#]*)$`),
+}
+
+// Counts the post images and avatars using each key, counted from them
+// rather than kept so deleted posts and users need no bookkeeping.
+// Must be called with m.mu held.
+func (m *Memory) mediaRefs() map[string]int {
+	refs := make(map[string]int)
+	for _, post := range m.posts {
+		for _, media := range post.Media {
+			// A file used for several sizes of one image counts once
+			keys := map[string]bool{
+				mediaKey(media.URL):          true,
+				mediaKey(media.MediumURL):    true,
+				mediaKey(media.ThumbnailURL): true,
+			}
+			for key := range keys {
+				refs[key]++
+			}
+		}
+	}
+	for _, user := range m.users {
+		if user.Avatar != nil {
+			refs[mediaKey(*user.Avatar)]++
+		}
+	}
+	return refs
+}
+
+func (m *Memory) CreateQuarantinedUpload(upload *models.QuarantinedUpload) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quarantine = append(m.quarantine, *upload)
+	return true
+}
+
+func (m *Memory) ReadQuarantinedUploads(limit int) []models.QuarantinedUpload {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var uploads []models.QuarantinedUpload
+	for i := len(m.quarantine) - 1; i >= 0 && len(uploads) < limit; i-- {
+		uploads = append(uploads, m.quarantine[i])
+	}
+	return uploads
+}
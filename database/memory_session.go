@@ -0,0 +1,104 @@
+package database
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func (m *Memory) CreateSession(session *models.Session) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[session.UserId]; !ok {
+		return false
+	}
+	if _, ok := m.sessions[session.Id]; ok {
+		return false
+	}
+	m.sessions[session.Id] = *session
+	return true
+}
+
+func (m *Memory) ReadSession(id string) *models.Session {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	session, ok := m.sessions[id]
+	if !ok {
+		return nil
+	}
+	return &session
+}
+
+func (m *Memory) ReadSessions(userId string) []models.Session {
+	m.mu.RLock()
+	var sessions []models.Session
+	now := time.Now()
+	for _, session := range m.sessions {
+		if session.UserId == userId && session.ExpiresAt.After(now) {
+			sessions = append(sessions, session)
+		}
+	}
+	m.mu.RUnlock()
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt) })
+	return sessions
+}
+
+func (m *Memory) TouchSession(id string, ip string, lastSeenAt time.Time, expiresAt time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.sessions[id]
+	if !ok {
+		return true
+	}
+	session.Ip = ip
+	session.LastSeenAt = lastSeenAt
+	session.ExpiresAt = expiresAt
+	m.sessions[id] = session
+	return true
+}
+
+func (m *Memory) DeleteSession(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return true
+}
+
+func (m *Memory) DeleteSessions(userId string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteSessions(userId)
+	return true
+}
+
+func (m *Memory) DeleteOtherSessions(userId string, id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for sessionId, session := range m.sessions {
+		if session.UserId == userId && sessionId != id {
+			delete(m.sessions, sessionId)
+		}
+	}
+	return true
+}
+
+// Removes every session of the user, m.mu must be held
+func (m *Memory) deleteSessions(userId string) {
+	for id, session := range m.sessions {
+		if session.UserId == userId {
+			delete(m.sessions, id)
+		}
+	}
+}
+
+func (m *Memory) DeleteExpiredSessions(now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, session := range m.sessions {
+		if !session.ExpiresAt.After(now) {
+			delete(m.sessions, id)
+		}
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func TestMediaKey(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"http://localhost:8080/uploads/media/abc.webp", "media/abc.webp"},
+		{"https://cdn.example.com/posts/abc.png", "posts/abc.png"},
+		{"http://localhost:9000/bucket/avatars/abc.png", "avatars/abc.png"},
+		{"http://localhost:8080/uploads/1700000000-a.png", "1700000000-a.png"},
+		{"https://iili.io/abc.png", ""},
+		{"http://localhost:8080/uploads/media/abc.webp?x=1", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if got := mediaKey(test.url); got != test.want {
+			t.Errorf("mediaKey(%q) = %q, want %q", test.url, got, test.want)
+		}
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	alice, other := "alice@example.com", "other@example.com"
+	tests := []struct {
+		name string
+		user models.User
+		want bool
+	}{
+		{"new user", models.User{Id: "2", Username: "bob", Email: &other}, true},
+		{"same id", models.User{Id: "1", Username: "bob"}, false},
+		{"same username", models.User{Id: "2", Username: "alice"}, false},
+		{"same email", models.User{Id: "2", Username: "bob", Email: &alice}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewMemory()
+			store.CreateUser(&models.User{Id: "1", Username: "alice", Email: &alice})
+			if got := store.CreateUser(&test.user); got != test.want {
+				t.Errorf("CreateUser = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAttemptPendingLogin(t *testing.T) {
+	now := time.Now()
+	store := NewMemory()
+	store.CreateUser(&models.User{Id: "user", Username: "user"})
+	store.CreatePendingLogin(&models.PendingLogin{Id: "pending", UserId: "user", ExpiresAt: now.Add(time.Minute)})
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		pending := store.AttemptPendingLogin("pending", now, 3)
+		if pending == nil || pending.Attempts != attempt {
+			t.Fatalf("attempt %d = %+v", attempt, pending)
+		}
+	}
+	if pending := store.AttemptPendingLogin("pending", now, 3); pending != nil {
+		t.Errorf("attempt past the limit = %+v, want nil", pending)
+	}
+	if pending := store.AttemptPendingLogin("pending", now, 5); pending == nil {
+		t.Error("attempt within a higher limit = nil")
+	}
+	if pending := store.AttemptPendingLogin("pending", now.Add(time.Minute), 5); pending != nil {
+		t.Errorf("attempt once expired = %+v, want nil", pending)
+	}
+	if pending := store.AttemptPendingLogin("unknown", now, 5); pending != nil {
+		t.Errorf("attempt of unknown login = %+v, want nil", pending)
+	}
+
+	store.DeleteExpiredPendingLogins(now.Add(time.Minute))
+	if pending := store.AttemptPendingLogin("pending", now, 5); pending != nil {
+		t.Errorf("attempt after cleanup = %+v, want nil", pending)
+	}
+}
+
+func TestDeleteMediaObject(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Hour)
+	avatar := "http://localhost:8080/uploads/avatars/used.png"
+	tests := []struct {
+		name    string
+		key     string
+		before  time.Time
+		remove  error
+		want    bool
+		removed bool
+	}{
+		{"unreferenced", "media/unused.webp", later, nil, true, true},
+		{"referenced", "avatars/used.png", later, nil, false, false},
+		{"touched since", "media/unused.webp", now, nil, false, false},
+		{"unknown", "media/missing.webp", later, nil, false, false},
+		{"remove fails", "media/unused.webp", later, errors.New("unavailable"), false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := NewMemory()
+			store.CreateUser(&models.User{Id: "user", Username: "user", Avatar: &avatar})
+			store.TouchMediaObject(&models.MediaObject{Key: "media/unused.webp", TouchedAt: now})
+			store.TouchMediaObject(&models.MediaObject{Key: "avatars/used.png", TouchedAt: now})
+
+			unreferenced := store.ReadUnreferencedMediaObjects(later, 10)
+			if len(unreferenced) != 1 || unreferenced[0].Key != "media/unused.webp" {
+				t.Fatalf("ReadUnreferencedMediaObjects = %+v", unreferenced)
+			}
+			removed := false
+			got := store.DeleteMediaObject(test.key, test.before, func() error {
+				removed = true
+				return test.remove
+			})
+			if got != test.want || removed != test.removed {
+				t.Errorf("DeleteMediaObject = %v, removed %v, want %v, removed %v", got, removed, test.want, test.removed)
+			}
+			remaining, want := len(store.ReadUnreferencedMediaObjects(later, 10)), 1
+			if test.want {
+				want = 0
+			}
+			if remaining != want {
+				t.Errorf("%d unreferenced objects left, want %d", remaining, want)
+			}
+		})
+	}
+}
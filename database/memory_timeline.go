@@ -0,0 +1,57 @@
+package database
+
+func (m *Memory) FanOutPost(postId string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	post, ok := m.posts[postId]
+	if !ok {
+		return true
+	}
+	for userId, following := range m.follows {
+		if following[post.UserId] {
+			m.addToTimeline(userId, postId)
+		}
+	}
+	return true
+}
+
+func (m *Memory) SyncTimeline(userId string, followId string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	following := m.follows[userId][followId]
+	for postId, post := range m.posts {
+		if post.UserId != followId {
+			continue
+		}
+		if !following {
+			delete(m.timelines[userId], postId)
+		} else if !m.fanOutOnRead[followId] {
+			m.addToTimeline(userId, postId)
+		}
+	}
+	return true
+}
+
+// m.mu must be held
+func (m *Memory) addToTimeline(userId string, postId string) {
+	if m.timelines[userId] == nil {
+		m.timelines[userId] = make(map[string]bool)
+	}
+	m.timelines[userId][postId] = true
+}
+
+func (m *Memory) IsFanOutOnRead(userId string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.fanOutOnRead[userId]
+}
+
+func (m *Memory) SetFanOutOnRead(userId string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[userId]; !ok {
+		return false
+	}
+	m.fanOutOnRead[userId] = true
+	return true
+}
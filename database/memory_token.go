@@ -0,0 +1,53 @@
+package database
+
+import (
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func (m *Memory) CreateToken(token *models.Token) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[token.UserId]; !ok {
+		return false
+	}
+	if _, ok := m.tokens[token.Id]; ok {
+		return false
+	}
+	m.tokens[token.Id] = *token
+	return true
+}
+
+func (m *Memory) ConsumeToken(id string, purpose string) *models.Token {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	token, ok := m.tokens[id]
+	if !ok || token.Purpose != purpose || !token.ExpiresAt.After(time.Now()) {
+		return nil
+	}
+	delete(m.tokens, id)
+	return &token
+}
+
+func (m *Memory) DeleteTokens(userId string, purpose string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, token := range m.tokens {
+		if token.UserId == userId && token.Purpose == purpose {
+			delete(m.tokens, id)
+		}
+	}
+	return true
+}
+
+func (m *Memory) DeleteExpiredTokens(now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, token := range m.tokens {
+		if !token.ExpiresAt.After(now) {
+			delete(m.tokens, id)
+		}
+	}
+	return true
+}
@@ -0,0 +1,132 @@
+package database
+
+import (
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func (m *Memory) ReadTwoFactor(userId string) *models.TwoFactor {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	twoFactor, ok := m.twoFactor[userId]
+	if !ok {
+		return nil
+	}
+	return &twoFactor
+}
+
+func (m *Memory) CreateTwoFactor(twoFactor *models.TwoFactor) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[twoFactor.UserId]; !ok {
+		return false
+	}
+	if _, ok := m.twoFactor[twoFactor.UserId]; ok {
+		return true
+	}
+	stored := *twoFactor
+	stored.Enabled = false
+	stored.LastStep = 0
+	m.twoFactor[twoFactor.UserId] = stored
+	return true
+}
+
+func (m *Memory) EnableTwoFactor(userId string, step int64, codeHashes []string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	twoFactor, ok := m.twoFactor[userId]
+	if !ok {
+		return true
+	}
+	twoFactor.Enabled = true
+	twoFactor.LastStep = step
+	m.twoFactor[userId] = twoFactor
+	codes := make(map[string]bool, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes[hash] = true
+	}
+	m.recoveryCodes[userId] = codes
+	return true
+}
+
+func (m *Memory) UseTwoFactorStep(userId string, step int64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	twoFactor, ok := m.twoFactor[userId]
+	if !ok || twoFactor.LastStep >= step {
+		return false
+	}
+	twoFactor.LastStep = step
+	m.twoFactor[userId] = twoFactor
+	return true
+}
+
+func (m *Memory) UseRecoveryCode(userId string, codeHash string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.recoveryCodes[userId][codeHash] {
+		return false
+	}
+	delete(m.recoveryCodes[userId], codeHash)
+	return true
+}
+
+func (m *Memory) ReadRecoveryCodesCount(userId string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.recoveryCodes[userId])
+}
+
+func (m *Memory) DeleteTwoFactor(userId string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.twoFactor, userId)
+	delete(m.recoveryCodes, userId)
+	return true
+}
+
+func (m *Memory) CreatePendingLogin(pending *models.PendingLogin) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[pending.UserId]; !ok {
+		return false
+	}
+	if _, ok := m.pendingLogins[pending.Id]; ok {
+		return false
+	}
+	stored := *pending
+	stored.Attempts = 0
+	m.pendingLogins[pending.Id] = stored
+	return true
+}
+
+func (m *Memory) AttemptPendingLogin(id string, now time.Time, limit int) *models.PendingLogin {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pending, ok := m.pendingLogins[id]
+	if !ok || !pending.ExpiresAt.After(now) || pending.Attempts >= limit {
+		return nil
+	}
+	pending.Attempts++
+	m.pendingLogins[id] = pending
+	return &pending
+}
+
+func (m *Memory) DeletePendingLogin(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pendingLogins, id)
+	return true
+}
+
+func (m *Memory) DeleteExpiredPendingLogins(now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, pending := range m.pendingLogins {
+		if !pending.ExpiresAt.After(now) {
+			delete(m.pendingLogins, id)
+		}
+	}
+	return true
+}
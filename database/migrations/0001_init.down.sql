@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS votes;
+DROP TABLE IF EXISTS follows;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS shorturl;
+DROP TABLE IF EXISTS o_users;
+DROP TABLE IF EXISTS t_users;
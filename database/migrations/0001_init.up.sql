@@ -26,7 +26,6 @@ CREATE TABLE IF NOT EXISTS posts (
     id          CHAR(36)        PRIMARY KEY,
     body        VARCHAR(320)    NOT NULL,
     created_at  TIMESTAMPTZ     NOT NULL,
-    images      VARCHAR      ,
     CONSTRAINT fk_user_id
         FOREIGN KEY(user_id)
             REFERENCES t_users(id)
@@ -74,4 +73,4 @@ CREATE TABLE IF NOT EXISTS comments (
         FOREIGN KEY(user_id)
             REFERENCES t_users(id)
             ON DELETE CASCADE
-);
\ No newline at end of file
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id          VARCHAR(64)     PRIMARY KEY,
+    user_id     CHAR(36)        NOT NULL,
+    purpose     VARCHAR(32)     NOT NULL,
+    expires_at  TIMESTAMPTZ     NOT NULL,
+    created_at  TIMESTAMPTZ     NOT NULL,
+    CONSTRAINT fk_user_id
+        FOREIGN KEY(user_id)
+            REFERENCES t_users(id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS user_tokens_user_id ON user_tokens(user_id, purpose);
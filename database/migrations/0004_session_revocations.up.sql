@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS session_revocations (
+    user_id     CHAR(36)        PRIMARY KEY,
+    revoked_at  TIMESTAMPTZ     NOT NULL,
+    CONSTRAINT fk_user_id
+        FOREIGN KEY(user_id)
+            REFERENCES t_users(id)
+            ON DELETE CASCADE
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    id              CHAR(36)        PRIMARY KEY,
+    user_id         CHAR(36)        NOT NULL,
+    created_at      TIMESTAMPTZ     NOT NULL,
+    last_seen_at    TIMESTAMPTZ     NOT NULL,
+    expires_at      TIMESTAMPTZ     NOT NULL,
+    CONSTRAINT fk_user_id
+        FOREIGN KEY(user_id)
+            REFERENCES t_users(id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id ON sessions(user_id);
+
+DROP TABLE IF EXISTS session_revocations;
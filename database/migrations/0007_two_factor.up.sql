@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS admins (
+    id          CHAR(36)        PRIMARY KEY,
+    CONSTRAINT fk_id
+        FOREIGN KEY(id)
+            REFERENCES t_users(id)
+            ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS two_factor (
+    user_id     CHAR(36)        PRIMARY KEY,
+    secret      VARCHAR(64)     NOT NULL,
+    enabled     BOOL            NOT NULL,
+    last_step   BIGINT          NOT NULL DEFAULT 0,
+    created_at  TIMESTAMPTZ     NOT NULL,
+    CONSTRAINT fk_user_id
+        FOREIGN KEY(user_id)
+            REFERENCES t_users(id)
+            ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    user_id     CHAR(36)        NOT NULL,
+    code_hash   CHAR(64)        NOT NULL,
+    PRIMARY KEY(user_id, code_hash),
+    CONSTRAINT fk_user_id
+        FOREIGN KEY(user_id)
+            REFERENCES t_users(id)
+            ON DELETE CASCADE
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS identities (
+    id          CHAR(36)        PRIMARY KEY,
+    user_id     CHAR(36)        NOT NULL,
+    provider    VARCHAR(32)     NOT NULL,
+    subject     VARCHAR(255)    NOT NULL,
+    email       VARCHAR(320)    NOT NULL,
+    created_at  TIMESTAMPTZ     NOT NULL,
+    UNIQUE(provider, subject),
+    CONSTRAINT fk_user_id
+        FOREIGN KEY(user_id)
+            REFERENCES t_users(id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS identities_user_id ON identities(user_id);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id          BIGSERIAL       PRIMARY KEY,
+    username    VARCHAR(32)     NOT NULL,
+    ip          VARCHAR(45)     NOT NULL,
+    user_agent  TEXT            NOT NULL DEFAULT '',
+    success     BOOL            NOT NULL,
+    created_at  TIMESTAMPTZ     NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS login_attempts_username ON login_attempts(username, created_at);
+CREATE INDEX IF NOT EXISTS login_attempts_ip ON login_attempts(ip, created_at);
+
+CREATE TABLE IF NOT EXISTS lockouts (
+    scope       VARCHAR(16)     NOT NULL,
+    key         VARCHAR(64)     NOT NULL,
+    failures    INT             NOT NULL,
+    locked_until TIMESTAMPTZ    NOT NULL,
+    created_at  TIMESTAMPTZ     NOT NULL,
+    PRIMARY KEY(scope, key)
+);
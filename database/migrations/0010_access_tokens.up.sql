@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS access_tokens (
+    id          CHAR(36)        PRIMARY KEY,
+    user_id     CHAR(36)        NOT NULL,
+    name        VARCHAR(64)     NOT NULL,
+    token_hash  CHAR(64)        UNIQUE NOT NULL,
+    scopes      TEXT[]          NOT NULL,
+    created_at  TIMESTAMPTZ     NOT NULL,
+    last_used_at TIMESTAMPTZ,
+    CONSTRAINT fk_user_id
+        FOREIGN KEY(user_id)
+            REFERENCES t_users(id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS access_tokens_user_id ON access_tokens(user_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS comments_post_id_created_at;
+DROP INDEX IF EXISTS posts_created_at;
+DROP INDEX IF EXISTS posts_user_id_created_at;
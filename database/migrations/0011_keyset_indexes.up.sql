@@ -0,0 +1,4 @@
+-- Lists are paged by (created_at, id) so these serve each page directly
+CREATE INDEX IF NOT EXISTS posts_user_id_created_at ON posts(user_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS posts_created_at ON posts(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS comments_post_id_created_at ON comments(post_id, created_at DESC, id DESC);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS follows_follow_id;
+DROP TABLE IF EXISTS fan_out_on_read;
+DROP TABLE IF EXISTS timelines;
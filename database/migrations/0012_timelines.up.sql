@@ -0,0 +1,37 @@
+-- Home timelines are precomputed: each post is pushed to the timelines of
+-- its author's followers when it is created
+CREATE TABLE IF NOT EXISTS timelines (
+    user_id     CHAR(36)        NOT NULL,
+    post_id     CHAR(36)        NOT NULL,
+    created_at  TIMESTAMPTZ     NOT NULL,
+    PRIMARY KEY (user_id, post_id),
+    CONSTRAINT fk_user_id
+        FOREIGN KEY(user_id)
+            REFERENCES t_users(id)
+            ON DELETE CASCADE,
+    CONSTRAINT fk_post_id
+        FOREIGN KEY(post_id)
+            REFERENCES posts(id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS timelines_user_id_created_at ON timelines(user_id, created_at DESC, post_id DESC);
+CREATE INDEX IF NOT EXISTS timelines_post_id ON timelines(post_id);
+
+-- Accounts with too many followers to push to, whose posts are read from
+-- posts when feeds are read instead
+CREATE TABLE IF NOT EXISTS fan_out_on_read (
+    user_id     CHAR(36)        PRIMARY KEY,
+    created_at  TIMESTAMPTZ     NOT NULL,
+    CONSTRAINT fk_user_id
+        FOREIGN KEY(user_id)
+            REFERENCES t_users(id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS follows_follow_id ON follows(follow_id, user_id);
+
+INSERT INTO timelines(user_id, post_id, created_at)
+SELECT follows.user_id, posts.id, posts.created_at
+FROM follows JOIN posts ON posts.user_id = follows.follow_id
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,33 @@
+CREATE TABLE IF NOT EXISTS jobs (
+    id              CHAR(36)        PRIMARY KEY,
+    kind            VARCHAR(64)     NOT NULL,
+    payload         JSONB           NOT NULL,
+    attempts        INT             NOT NULL,
+    max_attempts    INT             NOT NULL,
+    run_at          TIMESTAMPTZ     NOT NULL,
+    locked_until    TIMESTAMPTZ,
+    last_error      TEXT,
+    created_at      TIMESTAMPTZ     NOT NULL
+);
+
+-- Workers claim the oldest due job of their kind
+CREATE INDEX IF NOT EXISTS jobs_kind_run_at ON jobs(kind, run_at);
+
+-- Jobs that ran out of attempts, kept until an admin retries them
+CREATE TABLE IF NOT EXISTS dead_jobs (
+    id              CHAR(36)        PRIMARY KEY,
+    kind            VARCHAR(64)     NOT NULL,
+    payload         JSONB           NOT NULL,
+    attempts        INT             NOT NULL,
+    max_attempts    INT             NOT NULL,
+    last_error      TEXT,
+    created_at      TIMESTAMPTZ     NOT NULL,
+    failed_at       TIMESTAMPTZ     NOT NULL
+);
+
+-- When each recurring job is next due, shared by every app process so
+-- only one of them enqueues it
+CREATE TABLE IF NOT EXISTS job_schedules (
+    kind            VARCHAR(64)     PRIMARY KEY,
+    next_run_at     TIMESTAMPTZ     NOT NULL
+);
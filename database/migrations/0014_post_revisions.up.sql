@@ -0,0 +1,16 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ;
+
+-- Every earlier body of an edited post, created_at being when that body
+-- was written
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id          CHAR(36)        PRIMARY KEY,
+    post_id     CHAR(36)        NOT NULL,
+    body        VARCHAR(320)    NOT NULL,
+    created_at  TIMESTAMPTZ     NOT NULL,
+    CONSTRAINT fk_post_id
+        FOREIGN KEY(post_id)
+            REFERENCES posts(id)
+            ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS post_revisions_post_id_created_at ON post_revisions(post_id, created_at DESC, id DESC);
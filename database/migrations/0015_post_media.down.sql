@@ -0,0 +1,6 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS images VARCHAR;
+
+UPDATE posts SET images = post_media.url FROM post_media
+WHERE post_media.post_id = posts.id AND post_media.position = 0;
+
+DROP TABLE IF EXISTS post_media;
@@ -0,0 +1,24 @@
+-- Images attached to a post, shown in the order of position
+CREATE TABLE IF NOT EXISTS post_media (
+    id          CHAR(36)        PRIMARY KEY,
+    post_id     CHAR(36)        NOT NULL,
+    position    INT             NOT NULL,
+    url         TEXT            NOT NULL,
+    alt_text    VARCHAR(1000)   NOT NULL,
+    -- Zero when unknown, as for images attached before this table
+    width       INT             NOT NULL,
+    height      INT             NOT NULL,
+    CONSTRAINT fk_post_id
+        FOREIGN KEY(post_id)
+            REFERENCES posts(id)
+            ON DELETE CASCADE,
+    UNIQUE (post_id, position)
+);
+
+-- Posts had at most one image, so its post's id is free to reuse
+INSERT INTO post_media(id, post_id, position, url, alt_text, width, height)
+SELECT id, id, 0, images, '', 0, 0 FROM posts
+WHERE images IS NOT NULL AND images <> ''
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS images;
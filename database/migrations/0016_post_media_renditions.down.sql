@@ -0,0 +1,3 @@
+ALTER TABLE post_media DROP COLUMN IF EXISTS blurhash;
+ALTER TABLE post_media DROP COLUMN IF EXISTS thumbnail_url;
+ALTER TABLE post_media DROP COLUMN IF EXISTS medium_url;
@@ -0,0 +1,11 @@
+-- Smaller copies of each image for feeds, and a blurhash placeholder.
+-- Images from before these were made use the original for every size.
+ALTER TABLE post_media ADD COLUMN IF NOT EXISTS medium_url TEXT;
+ALTER TABLE post_media ADD COLUMN IF NOT EXISTS thumbnail_url TEXT;
+ALTER TABLE post_media ADD COLUMN IF NOT EXISTS blurhash VARCHAR(100) NOT NULL DEFAULT '';
+
+UPDATE post_media SET medium_url = url WHERE medium_url IS NULL;
+UPDATE post_media SET thumbnail_url = url WHERE thumbnail_url IS NULL;
+
+ALTER TABLE post_media ALTER COLUMN medium_url SET NOT NULL;
+ALTER TABLE post_media ALTER COLUMN thumbnail_url SET NOT NULL;
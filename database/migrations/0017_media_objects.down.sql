@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS avatar_refs ON t_users;
+DROP FUNCTION IF EXISTS avatar_refs();
+DROP TRIGGER IF EXISTS post_media_refs ON post_media;
+DROP FUNCTION IF EXISTS post_media_refs();
+DROP FUNCTION IF EXISTS media_key(TEXT);
+DROP TABLE IF EXISTS media_objects;
@@ -0,0 +1,80 @@
+-- Files in the media store, new ones named by the hash of their content
+CREATE TABLE IF NOT EXISTS media_objects (
+    key         TEXT            PRIMARY KEY,
+    -- Zero when unknown, as for files stored before this table
+    size        BIGINT          NOT NULL DEFAULT 0,
+    -- Post images and avatars using the file, kept by the triggers below
+    refs        INT             NOT NULL DEFAULT 0,
+    created_at  TIMESTAMPTZ     NOT NULL,
+    touched_at  TIMESTAMPTZ     NOT NULL
+);
+
+-- The garbage collector looks for files nothing uses
+CREATE INDEX IF NOT EXISTS media_objects_unreferenced ON media_objects(touched_at) WHERE refs = 0;
+
+-- Key in the media store of a URL the app made, NULL for anything else
+-- such as avatars from before the media store. Taken from the end of the
+-- URL, which is the key whatever base URL the store had at the time:
+-- content addressed files under media/, posts/ and avatars/ files from
+-- before them, and the timestamp named uploads from before those.
+CREATE OR REPLACE FUNCTION media_key(url TEXT) RETURNS TEXT AS $$
+    SELECT COALESCE(
+        substring(url from '/((?:media|posts|avatars)/[^/?#]+)$'),
+        substring(url from '/uploads/([0-9]+[^/?#]*)$')
+    );
+$$ LANGUAGE sql IMMUTABLE;
+
+-- Counted in triggers so deleting a user, which deletes their posts and
+-- so their images, keeps the counts right. A post image using the same
+-- file for several sizes counts once.
+CREATE OR REPLACE FUNCTION post_media_refs() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE media_objects SET refs = refs - 1
+        WHERE key IN (media_key(OLD.url), media_key(OLD.medium_url), media_key(OLD.thumbnail_url));
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE media_objects SET refs = refs + 1
+        WHERE key IN (media_key(NEW.url), media_key(NEW.medium_url), media_key(NEW.thumbnail_url));
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS post_media_refs ON post_media;
+CREATE TRIGGER post_media_refs AFTER INSERT OR DELETE OR UPDATE OF url, medium_url, thumbnail_url ON post_media
+FOR EACH ROW EXECUTE FUNCTION post_media_refs();
+
+CREATE OR REPLACE FUNCTION avatar_refs() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE media_objects SET refs = refs - 1 WHERE key = media_key(OLD.avatar);
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        UPDATE media_objects SET refs = refs + 1 WHERE key = media_key(NEW.avatar);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS avatar_refs ON t_users;
+CREATE TRIGGER avatar_refs AFTER INSERT OR DELETE OR UPDATE OF avatar ON t_users
+FOR EACH ROW EXECUTE FUNCTION avatar_refs();
+
+-- Tracks the files already in use, so they're collected once whatever
+-- uses them is deleted
+INSERT INTO media_objects(key, refs, created_at, touched_at)
+SELECT key, COUNT(*), NOW(), NOW() FROM (
+    SELECT DISTINCT post_media.id, media_key(urls.value) AS key
+    FROM post_media
+    CROSS JOIN unnest(ARRAY[post_media.url, post_media.medium_url, post_media.thumbnail_url]) AS urls(value)
+) AS refs
+WHERE key IS NOT NULL
+GROUP BY key
+ON CONFLICT (key) DO NOTHING;
+
+INSERT INTO media_objects(key, refs, created_at, touched_at)
+SELECT media_key(avatar), COUNT(*), NOW(), NOW() FROM t_users
+WHERE media_key(avatar) IS NOT NULL
+GROUP BY media_key(avatar)
+ON CONFLICT (key) DO UPDATE SET refs = media_objects.refs + EXCLUDED.refs;
@@ -0,0 +1,13 @@
+-- Logins waiting on a two factor code, kept here rather than in the cookie
+-- so replaying an old cookie can't reset the attempts
+CREATE TABLE IF NOT EXISTS pending_logins (
+    id          CHAR(36)        PRIMARY KEY,
+    user_id     CHAR(36)        NOT NULL,
+    attempts    INT             NOT NULL DEFAULT 0,
+    expires_at  TIMESTAMPTZ     NOT NULL,
+    created_at  TIMESTAMPTZ     NOT NULL,
+    CONSTRAINT fk_user_id
+        FOREIGN KEY(user_id)
+            REFERENCES t_users(id)
+            ON DELETE CASCADE
+);
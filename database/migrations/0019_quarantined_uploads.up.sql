@@ -0,0 +1,13 @@
+-- Uploads refused as unusable images, kept privately in the media store so
+-- admins can look into them. Kept after the uploader is deleted.
+CREATE TABLE IF NOT EXISTS quarantined_uploads (
+    id          CHAR(36)        PRIMARY KEY,
+    user_id     CHAR(36)        NOT NULL,
+    name        TEXT            NOT NULL,
+    key         TEXT            NOT NULL,
+    reason      TEXT            NOT NULL,
+    size        BIGINT          NOT NULL,
+    created_at  TIMESTAMPTZ     NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS quarantined_uploads_created_at ON quarantined_uploads(created_at DESC);
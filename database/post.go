@@ -2,100 +2,239 @@ package database
 
 import (
 	"log"
+	"time"
 
 	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/lib/pq"
 )
 
-func CreatePost(userId string, post *models.Post) bool {
-	var err error
-	_, err = db.Exec(
-		`INSERT INTO posts(user_id, id, body, created_at, images)
-    VALUES ($1, $2, $3, $4, $5)`,
-		userId, post.Id, post.Body, post.CreatedAt, post.Images,
-	)
+// Inserts the post along with its media, which keep the order given
+func (p *Postgres) CreatePost(userId string, post *models.Post) bool {
+	tx, err := p.db.Begin()
 	if err != nil {
+		log.Println(err)
+		return false
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(
+		`INSERT INTO posts(user_id, id, body, created_at)
+		VALUES ($1, $2, $3, $4)`,
+		userId, post.Id, post.Body, post.CreatedAt,
+	); err != nil {
 		log.Println("Error inserting post into database:", err)
 		return false
 	}
-	println("successfully inserted values yaya")
-
+	for position, media := range post.Media {
+		if _, err := tx.Exec(
+			`INSERT INTO post_media(id, post_id, position, url, medium_url, thumbnail_url, blurhash, alt_text, width, height)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			media.Id, post.Id, position, media.URL, media.MediumURL, media.ThumbnailURL, media.Blurhash,
+			media.AltText, media.Width, media.Height,
+		); err != nil {
+			log.Println("Error inserting post media into database:", err)
+			return false
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println(err)
+		return false
+	}
 	return true
 }
 
-func ReadPost(id string) *models.Post {
+func (p *Postgres) ReadPost(id string) *models.Post {
 	var post models.Post
-	if err := db.QueryRow(`SELECT * FROM posts WHERE id = $1`, id).Scan(
-		&post.UserId, &post.Id, &post.Body, &post.CreatedAt, &post.Images,
+	if err := p.db.QueryRow(
+		`SELECT `+postColumns+` FROM posts JOIN t_users ON t_users.id = posts.user_id
+		WHERE posts.id = $1`, id,
+	).Scan(
+		&post.UserId, &post.Id, &post.Body, &post.CreatedAt, &post.EditedAt, &post.Username, &post.Avatar,
 	); err != nil {
 		log.Println(err)
 		return nil
 	}
+	post.Media = p.readMedia([]string{post.Id})[post.Id]
 	return &post
 }
 
-func ReadPostsCount(userId string) int {
+// Returns the media of the posts in one query, in order for each post
+func (p *Postgres) readMedia(ids []string) map[string][]models.PostMedia {
+	media := make(map[string][]models.PostMedia)
+	if len(ids) == 0 {
+		return media
+	}
+	rows, err := p.db.Query(
+		`SELECT id, post_id, url, medium_url, thumbnail_url, blurhash, alt_text, width, height FROM post_media
+		WHERE post_id = ANY($1) ORDER BY post_id, position`,
+		pq.Array(ids),
+	)
+	if err != nil {
+		log.Println(err)
+		return media
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var item models.PostMedia
+		if err := rows.Scan(
+			&item.Id, &item.PostId, &item.URL, &item.MediumURL, &item.ThumbnailURL, &item.Blurhash,
+			&item.AltText, &item.Width, &item.Height,
+		); err != nil {
+			log.Println(err)
+			return media
+		}
+		media[item.PostId] = append(media[item.PostId], item)
+	}
+	return media
+}
+
+func (p *Postgres) ReadPostsCount(userId string) int {
 	var count int
-	if err := db.QueryRow(`SELECT COUNT(*) FROM posts WHERE user_id = $1`, userId).Scan(&count); err != nil {
+	if err := p.db.QueryRow(`SELECT COUNT(*) FROM posts WHERE user_id = $1`, userId).Scan(&count); err != nil {
 		log.Println(err)
 		return 0
 	}
 	return count
 }
 
-func ReadPosts(userId string, limit int, offset int) []models.Post {
-	var posts []models.Post
-	rows, err := db.Query(
-		`SELECT * FROM posts WHERE user_id = $1 ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3`,
-		userId, limit, offset,
+// Returns a page of the user's posts, newest first, and the cursor of the
+// next page
+func (p *Postgres) ReadPosts(userId string, after *models.Cursor, limit int) ([]models.Post, *models.Cursor) {
+	createdAt, id := cursorArgs(after)
+	return p.readPosts(
+		`SELECT `+postColumns+` FROM posts JOIN t_users ON t_users.id = posts.user_id
+		WHERE posts.user_id = $1
+		AND ($2::timestamptz IS NULL OR (posts.created_at, posts.id) < ($2, $3))
+		ORDER BY posts.created_at DESC, posts.id DESC
+		LIMIT $4`,
+		limit, userId, createdAt, id, limit+1,
+	)
+}
+
+// Returns a page of the user's home timeline, newest first. Posts pushed to
+// the timeline are merged with those of followed accounts fanned out on read.
+func (p *Postgres) ReadFeedPosts(userId string, after *models.Cursor, limit int) ([]models.Post, *models.Cursor) {
+	createdAt, id := cursorArgs(after)
+	return p.readPosts(
+		`SELECT `+postColumns+` FROM posts JOIN t_users ON t_users.id = posts.user_id
+		WHERE posts.id IN (
+			(SELECT post_id FROM timelines
+			WHERE user_id = $1
+			AND ($2::timestamptz IS NULL OR (created_at, post_id) < ($2, $3))
+			ORDER BY created_at DESC, post_id DESC
+			LIMIT $4)
+			UNION
+			(SELECT posts.id FROM posts
+			JOIN follows ON follows.follow_id = posts.user_id
+			JOIN fan_out_on_read ON fan_out_on_read.user_id = posts.user_id
+			WHERE follows.user_id = $1
+			AND ($2::timestamptz IS NULL OR (posts.created_at, posts.id) < ($2, $3))
+			ORDER BY posts.created_at DESC, posts.id DESC
+			LIMIT $4)
+		)
+		ORDER BY posts.created_at DESC, posts.id DESC
+		LIMIT $4`,
+		limit, userId, createdAt, id, limit+1,
 	)
+}
+
+// Posts are read along with their author's username and avatar, and their
+// media with one more query
+const postColumns = `posts.user_id, posts.id, posts.body, posts.created_at, posts.edited_at,
+	t_users.username, t_users.avatar`
+
+func (p *Postgres) readPosts(query string, limit int, args ...any) ([]models.Post, *models.Cursor) {
+	var posts []models.Post
+	rows, err := p.db.Query(query, args...)
 	if err != nil {
 		log.Println(err)
-		return nil
+		return nil, nil
 	}
-
 	defer rows.Close()
 	for rows.Next() {
 		var post models.Post
-		rows.Scan(&post.UserId, &post.Id, &post.Body, &post.CreatedAt, &post.Images)
+		rows.Scan(&post.UserId, &post.Id, &post.Body, &post.CreatedAt, &post.EditedAt, &post.Username, &post.Avatar)
 		posts = append(posts, post)
 	}
-	return posts
+	posts, next := trimPage(posts, limit, postCursor)
+	ids := make([]string, len(posts))
+	for index, post := range posts {
+		ids[index] = post.Id
+	}
+	media := p.readMedia(ids)
+	for index := range posts {
+		posts[index].Media = media[posts[index].Id]
+	}
+	return posts, next
 }
 
-func ReadFeedPosts(userId string, limit int, offset int) []models.Post {
-	var posts []models.Post
-	rows, err := db.Query(
-		`SELECT * FROM posts WHERE user_id IN
-		(SELECT follow_id FROM follows WHERE user_id = $1)
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3`,
-		userId, limit, offset,
+// Replaces the post's body, keeping the one it had as a revision with the
+// given id
+func (p *Postgres) EditPost(id string, body string, editedAt time.Time, revisionId string) bool {
+	tx, err := p.db.Begin()
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(
+		`INSERT INTO post_revisions(id, post_id, body, created_at)
+		SELECT $1, id, body, COALESCE(edited_at, created_at) FROM posts
+		WHERE id = $2 FOR UPDATE`,
+		revisionId, id,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	if _, err := tx.Exec(
+		`UPDATE posts SET body = $1, edited_at = $2 WHERE id = $3`,
+		body, editedAt, id,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// Returns a page of the post's earlier bodies, newest first
+func (p *Postgres) ReadPostRevisions(postId string, after *models.Cursor, limit int) ([]models.PostRevision, *models.Cursor) {
+	var revisions []models.PostRevision
+	createdAt, id := cursorArgs(after)
+	rows, err := p.db.Query(
+		`SELECT id, post_id, body, created_at FROM post_revisions
+		WHERE post_id = $1
+		AND ($2::timestamptz IS NULL OR (created_at, id) < ($2, $3))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4`,
+		postId, createdAt, id, limit+1,
 	)
 	if err != nil {
 		log.Println(err)
-		return nil
+		return nil, nil
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var post models.Post
-		rows.Scan(&post.UserId, &post.Id, &post.Body, &post.CreatedAt, &post.Images)
-		posts = append(posts, post)
+		var revision models.PostRevision
+		rows.Scan(&revision.Id, &revision.PostId, &revision.Body, &revision.CreatedAt)
+		revisions = append(revisions, revision)
 	}
-	return posts
+	return trimPage(revisions, limit, revisionCursor)
 }
 
-func DeletePost(id string) bool {
-	if _, err := db.Exec(`DELETE FROM posts WHERE id = $1`, id); err != nil {
+func (p *Postgres) DeletePost(id string) bool {
+	if _, err := p.db.Exec(`DELETE FROM posts WHERE id = $1`, id); err != nil {
 		log.Println(err)
 		return false
 	}
 	return true
 }
 
-func Voted(userId string, id string) bool {
+func (p *Postgres) Voted(userId string, id string) bool {
 	var count int
-	db.QueryRow(
+	p.db.QueryRow(
 		`SELECT COUNT(*) FROM votes WHERE user_id = $1 AND id = $2`,
 		userId, id,
 	).Scan(&count)
@@ -108,9 +247,9 @@ func Voted(userId string, id string) bool {
 	}
 }
 
-func ToggleVote(userId string, id string) {
+func (p *Postgres) ToggleVote(userId string, id string) {
 	var query string
-	voted := Voted(userId, id)
+	voted := p.Voted(userId, id)
 
 	switch voted {
 	case false:
@@ -118,14 +257,14 @@ func ToggleVote(userId string, id string) {
 	default:
 		query = `DELETE FROM votes WHERE user_id = $1 AND id = $2`
 	}
-	if _, err := db.Exec(query, userId, id); err != nil {
+	if _, err := p.db.Exec(query, userId, id); err != nil {
 		log.Println(err)
 	}
 }
 
-func ReadVotes(id string) []string {
+func (p *Postgres) ReadVotes(id string) []string {
 	var voters []string
-	rows, err := db.Query(
+	rows, err := p.db.Query(
 		`SELECT username FROM t_users WHERE id IN
 		(SELECT user_id FROM votes WHERE id = $1)`,
 		id,
@@ -143,8 +282,34 @@ func ReadVotes(id string) []string {
 	return voters
 }
 
-func CreateComment(userId string, postId string, comment *models.Comment) bool {
-	if _, err := db.Exec(
+// Returns the vote counts of the posts, and whether the user voted on
+// each, in one query. Posts without votes are left out.
+func (p *Postgres) ReadPostVotes(userId string, ids []string) map[string]models.PostVotes {
+	votes := make(map[string]models.PostVotes)
+	rows, err := p.db.Query(
+		`SELECT id, COUNT(*), BOOL_OR(user_id = $2) FROM votes
+		WHERE id = ANY($1) GROUP BY id`,
+		pq.Array(ids), userId,
+	)
+	if err != nil {
+		log.Println(err)
+		return votes
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var vote models.PostVotes
+		if err := rows.Scan(&id, &vote.Count, &vote.Voted); err != nil {
+			log.Println(err)
+			return votes
+		}
+		votes[id] = vote
+	}
+	return votes
+}
+
+func (p *Postgres) CreateComment(userId string, postId string, comment *models.Comment) bool {
+	if _, err := p.db.Exec(
 		`INSERT INTO comments (user_id, post_id, id, body, created_at)
 		VALUES ($1, $2, $3, $4, $5)`,
 		userId, postId, comment.Id, comment.Body, comment.CreatedAt,
@@ -155,14 +320,23 @@ func CreateComment(userId string, postId string, comment *models.Comment) bool {
 	return true
 }
 
-func ReadComment(id string) *models.Comment {
+// Comments are read along with their author's username and avatar
+const commentColumns = `comments.user_id, comments.post_id, comments.id, comments.body, comments.created_at,
+	t_users.username, t_users.avatar`
+
+func (p *Postgres) ReadComment(id string) *models.Comment {
 	var comment models.Comment
-	if err := db.QueryRow(`SELECT * FROM comments WHERE id = $1`, id).Scan(
+	if err := p.db.QueryRow(
+		`SELECT `+commentColumns+` FROM comments JOIN t_users ON t_users.id = comments.user_id
+		WHERE comments.id = $1`, id,
+	).Scan(
 		&comment.UserId,
 		&comment.PostId,
 		&comment.Id,
 		&comment.Body,
 		&comment.CreatedAt,
+		&comment.Username,
+		&comment.Avatar,
 	); err != nil {
 		log.Println(err)
 		return nil
@@ -170,17 +344,21 @@ func ReadComment(id string) *models.Comment {
 	return &comment
 }
 
-func ReadComments(postId string, limit int, offset int) []models.Comment {
+// Returns a page of the post's comments, newest first
+func (p *Postgres) ReadComments(postId string, after *models.Cursor, limit int) ([]models.Comment, *models.Cursor) {
 	var comments []models.Comment
-	rows, err := db.Query(
-		`SELECT * FROM comments WHERE post_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2 OFFSET $3`,
-		postId, limit, offset,
+	createdAt, id := cursorArgs(after)
+	rows, err := p.db.Query(
+		`SELECT `+commentColumns+` FROM comments JOIN t_users ON t_users.id = comments.user_id
+		WHERE comments.post_id = $1
+		AND ($2::timestamptz IS NULL OR (comments.created_at, comments.id) < ($2, $3))
+		ORDER BY comments.created_at DESC, comments.id DESC
+		LIMIT $4`,
+		postId, createdAt, id, limit+1,
 	)
 	if err != nil {
 		log.Println(err)
-		return nil
+		return nil, nil
 	}
 	defer rows.Close()
 	for rows.Next() {
@@ -191,14 +369,16 @@ func ReadComments(postId string, limit int, offset int) []models.Comment {
 			&comment.Id,
 			&comment.Body,
 			&comment.CreatedAt,
+			&comment.Username,
+			&comment.Avatar,
 		)
 		comments = append(comments, comment)
 	}
-	return comments
+	return trimPage(comments, limit, commentCursor)
 }
 
-func DeleteComment(id string) bool {
-	if _, err := db.Exec(`DELETE FROM comments WHERE id = $1`, id); err != nil {
+func (p *Postgres) DeleteComment(id string) bool {
+	if _, err := p.db.Exec(`DELETE FROM comments WHERE id = $1`, id); err != nil {
 		log.Println(err)
 		return false
 	}
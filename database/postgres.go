@@ -2,27 +2,24 @@ package database
 
 import (
 	"database/sql"
-	"os"
 
-	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
-var db *sql.DB
+// Postgres is the Store backed by a PostgreSQL server
+type Postgres struct {
+	db *sql.DB
+}
 
-func init() {
-	godotenv.Load(".env")
-	var err error
-	db, err = sql.Open("postgres", os.Getenv("POSTGRES_URI")+"sslmode=disable")
-	if err != nil {
-		panic(err)
-	}
-	data, err := os.ReadFile("database/init.sql")
+// Opens a connection to the given Postgres URI, the schema is managed
+// separately through MigrateUp
+func NewPostgres(uri string) (*Postgres, error) {
+	db, err := sql.Open("postgres", uri+"sslmode=disable")
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
-	script := string(data)
-	if _, err := db.Exec(script); err != nil {
-		panic(err)
+	if err := db.Ping(); err != nil {
+		return nil, err
 	}
+	return &Postgres{db: db}, nil
 }
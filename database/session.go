@@ -0,0 +1,123 @@
+package database
+
+import (
+	"log"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func (p *Postgres) CreateSession(session *models.Session) bool {
+	if _, err := p.db.Exec(
+		`INSERT INTO sessions(id, user_id, user_agent, ip, created_at, last_seen_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		session.Id,
+		session.UserId,
+		session.UserAgent,
+		session.Ip,
+		session.CreatedAt,
+		session.LastSeenAt,
+		session.ExpiresAt,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+func (p *Postgres) ReadSession(id string) *models.Session {
+	var session models.Session
+	if err := p.db.QueryRow(
+		`SELECT id, user_id, user_agent, ip, created_at, last_seen_at, expires_at
+		FROM sessions WHERE id = $1`, id,
+	).Scan(
+		&session.Id,
+		&session.UserId,
+		&session.UserAgent,
+		&session.Ip,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+		&session.ExpiresAt,
+	); err != nil {
+		return nil
+	}
+	return &session
+}
+
+// Returns the user's unexpired sessions, most recently active first
+func (p *Postgres) ReadSessions(userId string) []models.Session {
+	var sessions []models.Session
+	rows, err := p.db.Query(
+		`SELECT id, user_id, user_agent, ip, created_at, last_seen_at, expires_at
+		FROM sessions WHERE user_id = $1 AND expires_at > NOW()
+		ORDER BY last_seen_at DESC`,
+		userId,
+	)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var session models.Session
+		rows.Scan(
+			&session.Id,
+			&session.UserId,
+			&session.UserAgent,
+			&session.Ip,
+			&session.CreatedAt,
+			&session.LastSeenAt,
+			&session.ExpiresAt,
+		)
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+// Records activity on the session and pushes back its expiry
+func (p *Postgres) TouchSession(id string, ip string, lastSeenAt time.Time, expiresAt time.Time) bool {
+	if _, err := p.db.Exec(
+		`UPDATE sessions SET ip = $1, last_seen_at = $2, expires_at = $3 WHERE id = $4`,
+		ip, lastSeenAt, expiresAt, id,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+func (p *Postgres) DeleteSession(id string) bool {
+	if _, err := p.db.Exec(`DELETE FROM sessions WHERE id = $1`, id); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// Logs the user out everywhere
+func (p *Postgres) DeleteSessions(userId string) bool {
+	if _, err := p.db.Exec(`DELETE FROM sessions WHERE user_id = $1`, userId); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// Logs the user out everywhere except the given session
+func (p *Postgres) DeleteOtherSessions(userId string, id string) bool {
+	if _, err := p.db.Exec(
+		`DELETE FROM sessions WHERE user_id = $1 AND id <> $2`, userId, id,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+func (p *Postgres) DeleteExpiredSessions(now time.Time) bool {
+	if _, err := p.db.Exec(`DELETE FROM sessions WHERE expires_at <= $1`, now); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
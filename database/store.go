@@ -0,0 +1,189 @@
+package database
+
+import (
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+// Store is the storage used by the handlers, implemented by
+// Postgres for production and Memory for running without a database
+type Store interface {
+	UserStore
+	FollowStore
+	PostStore
+	VoteStore
+	CommentStore
+	TokenStore
+	SessionStore
+	TwoFactorStore
+	IdentityStore
+	LoginAttemptStore
+	AccessTokenStore
+	TimelineStore
+	JobStore
+	MediaObjectStore
+	QuarantineStore
+}
+
+type UserStore interface {
+	CreateUser(user *models.User) bool
+	// OAuth users are those without a password of their own
+	CreateOAuthUser(id string) bool
+	ReadUserByName(username string) *models.User
+	ReadUsersByName(usernames []string) []models.User
+	ReadUserByEmail(email string) *models.User
+	ReadUserById(id string) *models.User
+	IsOAuthUser(id string) bool
+	DeleteOAuthUser(id string) bool
+	CreateAdmin(id string) bool
+	DeleteAdmin(id string) bool
+	IsAdmin(id string) bool
+	ReadUsers(username string, after *models.Cursor, limit int) ([]models.User, *models.Cursor)
+	UpdateUser(id string, updates map[string]any) bool
+	DeleteUser(id string) bool
+}
+
+type FollowStore interface {
+	Followed(userId string, followId string) bool
+	ToggleFollow(userId string, followId string)
+	ReadFollowers(userId string) []string
+	ReadFollowersCount(userId string) int
+	ReadFollowing(userId string) []string
+	ReadFollowingCount(userId string) int
+	ReadUserStats(userId string, ids []string) map[string]models.UserStats
+}
+
+type PostStore interface {
+	CreatePost(userId string, post *models.Post) bool
+	ReadPost(id string) *models.Post
+	ReadPostsCount(userId string) int
+	ReadPosts(userId string, after *models.Cursor, limit int) ([]models.Post, *models.Cursor)
+	ReadFeedPosts(userId string, after *models.Cursor, limit int) ([]models.Post, *models.Cursor)
+	EditPost(id string, body string, editedAt time.Time, revisionId string) bool
+	ReadPostRevisions(postId string, after *models.Cursor, limit int) ([]models.PostRevision, *models.Cursor)
+	DeletePost(id string) bool
+}
+
+type VoteStore interface {
+	Voted(userId string, id string) bool
+	ToggleVote(userId string, id string)
+	ReadVotes(id string) []string
+	ReadPostVotes(userId string, ids []string) map[string]models.PostVotes
+}
+
+type CommentStore interface {
+	CreateComment(userId string, postId string, comment *models.Comment) bool
+	ReadComment(id string) *models.Comment
+	ReadComments(postId string, after *models.Cursor, limit int) ([]models.Comment, *models.Cursor)
+	DeleteComment(id string) bool
+}
+
+type TokenStore interface {
+	CreateToken(token *models.Token) bool
+	ConsumeToken(id string, purpose string) *models.Token
+	DeleteTokens(userId string, purpose string) bool
+	DeleteExpiredTokens(now time.Time) bool
+}
+
+type SessionStore interface {
+	CreateSession(session *models.Session) bool
+	ReadSession(id string) *models.Session
+	ReadSessions(userId string) []models.Session
+	TouchSession(id string, ip string, lastSeenAt time.Time, expiresAt time.Time) bool
+	DeleteSession(id string) bool
+	DeleteSessions(userId string) bool
+	DeleteOtherSessions(userId string, id string) bool
+	DeleteExpiredSessions(now time.Time) bool
+}
+
+type TwoFactorStore interface {
+	ReadTwoFactor(userId string) *models.TwoFactor
+	// Starts enrollment with the secret, unless a secret is already there
+	// from an earlier enrollment, which is kept
+	CreateTwoFactor(twoFactor *models.TwoFactor) bool
+	EnableTwoFactor(userId string, step int64, codeHashes []string) bool
+	UseTwoFactorStep(userId string, step int64) bool
+	UseRecoveryCode(userId string, codeHash string) bool
+	ReadRecoveryCodesCount(userId string) int
+	DeleteTwoFactor(userId string) bool
+	CreatePendingLogin(pending *models.PendingLogin) bool
+	// Counts an attempt at the login's code and returns it, nil once it
+	// expired or ran out of attempts
+	AttemptPendingLogin(id string, now time.Time, limit int) *models.PendingLogin
+	DeletePendingLogin(id string) bool
+	DeleteExpiredPendingLogins(now time.Time) bool
+}
+
+type IdentityStore interface {
+	CreateIdentity(identity *models.Identity) bool
+	ReadIdentity(provider string, subject string) *models.Identity
+	ReadIdentities(userId string) []models.Identity
+	DeleteIdentity(userId string, id string) bool
+}
+
+type LoginAttemptStore interface {
+	CreateLoginAttempt(attempt *models.LoginAttempt) bool
+	ReadLoginFailuresCount(username string, since time.Time) int
+	ReadIpLoginFailuresCount(ip string, since time.Time) int
+	ReadFailedLoginAttempts(limit int, offset int) []models.LoginAttempt
+	CreateLockout(lockout *models.Lockout) bool
+	ReadLockout(scope string, key string) *models.Lockout
+	ReadLockouts() []models.Lockout
+	DeleteLockout(scope string, key string) bool
+}
+
+type AccessTokenStore interface {
+	CreateAccessToken(token *models.AccessToken) bool
+	ReadAccessTokenByHash(hash string) *models.AccessToken
+	ReadAccessTokens(userId string) []models.AccessToken
+	TouchAccessToken(id string, lastUsedAt time.Time) bool
+	DeleteAccessToken(userId string, id string) bool
+}
+
+// Home timelines are precomputed, with each post pushed to the timelines of
+// its author's followers
+type TimelineStore interface {
+	FanOutPost(postId string) bool
+	SyncTimeline(userId string, followId string) bool
+	IsFanOutOnRead(userId string) bool
+	SetFanOutOnRead(userId string) bool
+}
+
+type JobStore interface {
+	CreateJob(job *models.Job) bool
+	ClaimJob(kind string, lockedUntil time.Time) *models.Job
+	RetryJob(id string, runAt time.Time, lastError string) bool
+	DeleteJob(id string) bool
+	BuryJob(id string, lastError string, failedAt time.Time) bool
+	ReadJobs(limit int) []models.Job
+	ReadDeadJobs(limit int) []models.Job
+	RetryDeadJob(id string, runAt time.Time) bool
+	ClaimSchedule(kind string, now time.Time, next time.Time) bool
+}
+
+// Media files are named by their content and counted by the posts and
+// avatars using them, so unused ones can be collected
+type MediaObjectStore interface {
+	// Records the file, or marks it as just used when already there
+	TouchMediaObject(object *models.MediaObject) bool
+	// Records files found in the media store that have no record, such as
+	// ones stored before media_objects, with the uses they have, returning
+	// how many were recorded
+	TrackMediaObjects(objects []models.MediaObject) int
+	ReadUnreferencedMediaObjects(before time.Time, limit int) []models.MediaObject
+	// Deletes the file's record if it's still unreferenced and untouched
+	// since before, calling remove to delete the file first while holding
+	// the record, so an upload of the same content waits for it
+	DeleteMediaObject(key string, before time.Time, remove func() error) bool
+}
+
+type QuarantineStore interface {
+	CreateQuarantinedUpload(upload *models.QuarantinedUpload) bool
+	ReadQuarantinedUploads(limit int) []models.QuarantinedUpload
+}
+
+var (
+	_ Store = (*Postgres)(nil)
+	_ Store = (*Memory)(nil)
+)
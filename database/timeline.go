@@ -0,0 +1,74 @@
+package database
+
+import (
+	"log"
+	"time"
+)
+
+// Pushes the post onto the timelines of its author's followers
+func (p *Postgres) FanOutPost(postId string) bool {
+	if _, err := p.db.Exec(
+		`INSERT INTO timelines(user_id, post_id, created_at)
+		SELECT follows.user_id, posts.id, posts.created_at
+		FROM posts JOIN follows ON follows.follow_id = posts.user_id
+		WHERE posts.id = $1
+		ON CONFLICT DO NOTHING`,
+		postId,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// Brings the user's timeline in line with whether they follow followId,
+// adding followId's posts after a follow or removing them after an unfollow.
+// Posts of accounts fanned out on read are never added, as the feed reads
+// them from posts.
+func (p *Postgres) SyncTimeline(userId string, followId string) bool {
+	if _, err := p.db.Exec(
+		`INSERT INTO timelines(user_id, post_id, created_at)
+		SELECT $1, posts.id, posts.created_at FROM posts
+		WHERE posts.user_id = $2
+		AND EXISTS (SELECT 1 FROM follows WHERE user_id = $1 AND follow_id = $2)
+		AND NOT EXISTS (SELECT 1 FROM fan_out_on_read WHERE user_id = $2)
+		ON CONFLICT DO NOTHING`,
+		userId, followId,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	if _, err := p.db.Exec(
+		`DELETE FROM timelines WHERE user_id = $1
+		AND post_id IN (SELECT id FROM posts WHERE user_id = $2)
+		AND NOT EXISTS (SELECT 1 FROM follows WHERE user_id = $1 AND follow_id = $2)`,
+		userId, followId,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+func (p *Postgres) IsFanOutOnRead(userId string) bool {
+	var exists bool
+	if err := p.db.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM fan_out_on_read WHERE user_id = $1)`, userId,
+	).Scan(&exists); err != nil {
+		log.Println(err)
+		return false
+	}
+	return exists
+}
+
+func (p *Postgres) SetFanOutOnRead(userId string) bool {
+	if _, err := p.db.Exec(
+		`INSERT INTO fan_out_on_read(user_id, created_at) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING`,
+		userId, time.Now(),
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
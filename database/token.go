@@ -0,0 +1,59 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func (p *Postgres) CreateToken(token *models.Token) bool {
+	if _, err := p.db.Exec(
+		`INSERT INTO user_tokens(id, user_id, purpose, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)`,
+		token.Id, token.UserId, token.Purpose, token.ExpiresAt, token.CreatedAt,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// Deletes the token and returns it if it has the given purpose and
+// hasn't expired, so that it can only be used once
+func (p *Postgres) ConsumeToken(id string, purpose string) *models.Token {
+	var token models.Token
+	if err := p.db.QueryRow(
+		`DELETE FROM user_tokens WHERE id = $1 AND purpose = $2 AND expires_at > NOW()
+		RETURNING id, user_id, purpose, expires_at, created_at`,
+		id, purpose,
+	).Scan(&token.Id, &token.UserId, &token.Purpose, &token.ExpiresAt, &token.CreatedAt); err != nil {
+		// An unknown, used or expired token is expected, not an error
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Println(err)
+		}
+		return nil
+	}
+	return &token
+}
+
+func (p *Postgres) DeleteTokens(userId string, purpose string) bool {
+	if _, err := p.db.Exec(
+		`DELETE FROM user_tokens WHERE user_id = $1 AND purpose = $2`,
+		userId, purpose,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+func (p *Postgres) DeleteExpiredTokens(now time.Time) bool {
+	if _, err := p.db.Exec(`DELETE FROM user_tokens WHERE expires_at <= $1`, now); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
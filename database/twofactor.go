@@ -0,0 +1,186 @@
+package database
+
+import (
+	"log"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func (p *Postgres) ReadTwoFactor(userId string) *models.TwoFactor {
+	var twoFactor models.TwoFactor
+	if err := p.db.QueryRow(
+		`SELECT user_id, secret, enabled, last_step, created_at FROM two_factor WHERE user_id = $1`,
+		userId,
+	).Scan(
+		&twoFactor.UserId,
+		&twoFactor.Secret,
+		&twoFactor.Enabled,
+		&twoFactor.LastStep,
+		&twoFactor.CreatedAt,
+	); err != nil {
+		return nil
+	}
+	return &twoFactor
+}
+
+// Stores a new secret awaiting confirmation, replacing any earlier one
+// that wasn't enabled
+func (p *Postgres) CreateTwoFactor(twoFactor *models.TwoFactor) bool {
+	if _, err := p.db.Exec(
+		`INSERT INTO two_factor(user_id, secret, enabled, last_step, created_at)
+		VALUES ($1, $2, FALSE, 0, $3)
+		ON CONFLICT (user_id) DO NOTHING`,
+		twoFactor.UserId, twoFactor.Secret, twoFactor.CreatedAt,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// Turns on two factor authentication along with a fresh set of hashed
+// recovery codes
+func (p *Postgres) EnableTwoFactor(userId string, step int64, codeHashes []string) bool {
+	tx, err := p.db.Begin()
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(
+		`UPDATE two_factor SET enabled = TRUE, last_step = $1 WHERE user_id = $2`,
+		step, userId,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userId); err != nil {
+		log.Println(err)
+		return false
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(
+			`INSERT INTO recovery_codes(user_id, code_hash) VALUES ($1, $2)`,
+			userId, hash,
+		); err != nil {
+			log.Println(err)
+			return false
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// Records a code's time step as used, returns false if the step or a
+// later one was already used
+func (p *Postgres) UseTwoFactorStep(userId string, step int64) bool {
+	result, err := p.db.Exec(
+		`UPDATE two_factor SET last_step = $1 WHERE user_id = $2 AND last_step < $1`,
+		step, userId,
+	)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	count, _ := result.RowsAffected()
+	return count == 1
+}
+
+// Uses up a recovery code, returns false if it doesn't exist
+func (p *Postgres) UseRecoveryCode(userId string, codeHash string) bool {
+	result, err := p.db.Exec(
+		`DELETE FROM recovery_codes WHERE user_id = $1 AND code_hash = $2`,
+		userId, codeHash,
+	)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	count, _ := result.RowsAffected()
+	return count == 1
+}
+
+func (p *Postgres) ReadRecoveryCodesCount(userId string) int {
+	var count int
+	if err := p.db.QueryRow(
+		`SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1`, userId,
+	).Scan(&count); err != nil {
+		return 0
+	}
+	return count
+}
+
+func (p *Postgres) DeleteTwoFactor(userId string) bool {
+	tx, err := p.db.Begin()
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM recovery_codes WHERE user_id = $1`, userId); err != nil {
+		log.Println(err)
+		return false
+	}
+	if _, err := tx.Exec(`DELETE FROM two_factor WHERE user_id = $1`, userId); err != nil {
+		log.Println(err)
+		return false
+	}
+	if err := tx.Commit(); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+func (p *Postgres) CreatePendingLogin(pending *models.PendingLogin) bool {
+	if _, err := p.db.Exec(
+		`INSERT INTO pending_logins(id, user_id, attempts, expires_at, created_at)
+		VALUES ($1, $2, 0, $3, $4)`,
+		pending.Id, pending.UserId, pending.ExpiresAt, pending.CreatedAt,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// Counted in the same statement that checks the limit, so concurrent
+// guesses can't go over it
+func (p *Postgres) AttemptPendingLogin(id string, now time.Time, limit int) *models.PendingLogin {
+	var pending models.PendingLogin
+	if err := p.db.QueryRow(
+		`UPDATE pending_logins SET attempts = attempts + 1
+		WHERE id = $1 AND expires_at > $2 AND attempts < $3
+		RETURNING id, user_id, attempts, expires_at, created_at`,
+		id, now, limit,
+	).Scan(
+		&pending.Id,
+		&pending.UserId,
+		&pending.Attempts,
+		&pending.ExpiresAt,
+		&pending.CreatedAt,
+	); err != nil {
+		return nil
+	}
+	return &pending
+}
+
+func (p *Postgres) DeletePendingLogin(id string) bool {
+	if _, err := p.db.Exec(`DELETE FROM pending_logins WHERE id = $1`, id); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+func (p *Postgres) DeleteExpiredPendingLogins(now time.Time) bool {
+	if _, err := p.db.Exec(`DELETE FROM pending_logins WHERE expires_at <= $1`, now); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
@@ -8,9 +8,9 @@ import (
 	"github.com/lib/pq"
 )
 
-func CreateUser(user *models.User) bool {
+func (p *Postgres) CreateUser(user *models.User) bool {
 	// inserting user data into t_users table
-	if _, err := db.Exec(
+	if _, err := p.db.Exec(
 		`INSERT INTO t_users(email, username, password, id, verified, avatar, created_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)`,
 		user.Email,
@@ -27,18 +27,18 @@ func CreateUser(user *models.User) bool {
 	return true
 }
 
-func CreateOAuthUser(id string) bool {
-	if _, err := db.Exec(`INSERT INTO o_users(id) VALUES ($1)`, id); err != nil {
+func (p *Postgres) CreateOAuthUser(id string) bool {
+	if _, err := p.db.Exec(`INSERT INTO o_users(id) VALUES ($1)`, id); err != nil {
 		log.Println(err)
 		return false
 	}
 	return true
 }
 
-func ReadUserByName(username string) *models.User {
+func (p *Postgres) ReadUserByName(username string) *models.User {
 	var user models.User
 	// stores data retrieved from database into the user struct
-	if err := db.QueryRow(`SELECT * FROM t_users WHERE username = $1`, username).Scan(
+	if err := p.db.QueryRow(`SELECT * FROM t_users WHERE username = $1`, username).Scan(
 		&user.Email,
 		&user.Username,
 		&user.Password,
@@ -53,9 +53,37 @@ func ReadUserByName(username string) *models.User {
 	return &user
 }
 
-func ReadUserByEmail(email string) *models.User {
+// Returns the users with the usernames in one query, ordered by username
+func (p *Postgres) ReadUsersByName(usernames []string) []models.User {
+	var users []models.User
+	rows, err := p.db.Query(
+		`SELECT * FROM t_users WHERE username = ANY($1) ORDER BY username`,
+		pq.Array(usernames),
+	)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var user models.User
+		rows.Scan(
+			&user.Email,
+			&user.Username,
+			&user.Password,
+			&user.Id,
+			&user.Verified,
+			&user.Avatar,
+			&user.CreatedAt,
+		)
+		users = append(users, user)
+	}
+	return users
+}
+
+func (p *Postgres) ReadUserByEmail(email string) *models.User {
 	var user models.User
-	if err := db.QueryRow(`SELECT * FROM t_users WHERE email = $1`, email).Scan(
+	if err := p.db.QueryRow(`SELECT * FROM t_users WHERE email = $1`, email).Scan(
 		&user.Email,
 		&user.Username,
 		&user.Password,
@@ -70,9 +98,9 @@ func ReadUserByEmail(email string) *models.User {
 	return &user
 }
 
-func ReadUserById(id string) *models.User {
+func (p *Postgres) ReadUserById(id string) *models.User {
 	var user models.User
-	if err := db.QueryRow(`SELECT * FROM t_users WHERE id = $1`, id).Scan(
+	if err := p.db.QueryRow(`SELECT * FROM t_users WHERE id = $1`, id).Scan(
 		&user.Email,
 		&user.Username,
 		&user.Password,
@@ -87,9 +115,9 @@ func ReadUserById(id string) *models.User {
 	return &user
 }
 
-func IsOAuthUser(id string) bool {
+func (p *Postgres) IsOAuthUser(id string) bool {
 	var count int
-	db.QueryRow(`SELECT COUNT(*) FROM o_users WHERE id = $1`, id).Scan(&count)
+	p.db.QueryRow(`SELECT COUNT(*) FROM o_users WHERE id = $1`, id).Scan(&count)
 	switch count {
 	case 0:
 		return false
@@ -98,15 +126,60 @@ func IsOAuthUser(id string) bool {
 	}
 }
 
-func ReadUsers(username string, limit int, offset int) []models.User {
+// Marks the user as having set a password of their own
+func (p *Postgres) DeleteOAuthUser(id string) bool {
+	if _, err := p.db.Exec(`DELETE FROM o_users WHERE id = $1`, id); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+func (p *Postgres) CreateAdmin(id string) bool {
+	if _, err := p.db.Exec(
+		`INSERT INTO admins(id) VALUES ($1) ON CONFLICT (id) DO NOTHING`, id,
+	); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+func (p *Postgres) DeleteAdmin(id string) bool {
+	if _, err := p.db.Exec(`DELETE FROM admins WHERE id = $1`, id); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+func (p *Postgres) IsAdmin(id string) bool {
+	var count int
+	p.db.QueryRow(`SELECT COUNT(*) FROM admins WHERE id = $1`, id).Scan(&count)
+	switch count {
+	case 0:
+		return false
+	default:
+		return true
+	}
+}
+
+// Returns a page of the users with the text in their username, ordered
+// by username
+func (p *Postgres) ReadUsers(username string, after *models.Cursor, limit int) ([]models.User, *models.Cursor) {
 	var users []models.User
-	rows, err := db.Query(
-		`SELECT * FROM t_users WHERE username LIKE $1 ORDER BY username
-		LIMIT $2 OFFSET $3`,
-		"%"+username+"%", limit, offset)
+	var last string
+	if after != nil {
+		last = after.Id
+	}
+	rows, err := p.db.Query(
+		`SELECT * FROM t_users WHERE username LIKE $1 AND username > $2
+		ORDER BY username
+		LIMIT $3`,
+		"%"+username+"%", last, limit+1)
 	if err != nil {
 		log.Println(err)
-		return nil
+		return nil, nil
 	}
 	defer rows.Close()
 	for rows.Next() {
@@ -122,12 +195,12 @@ func ReadUsers(username string, limit int, offset int) []models.User {
 		)
 		users = append(users, user)
 	}
-	return users
+	return trimPage(users, limit, userCursor)
 }
 
-func UpdateUser(id string, updates map[string]any) bool {
+func (p *Postgres) UpdateUser(id string, updates map[string]any) bool {
 	for column := range updates {
-		if _, err := db.Exec(
+		if _, err := p.db.Exec(
 			fmt.Sprintf(`UPDATE t_users SET %s = $1 WHERE id = $2`, pq.QuoteIdentifier(column)),
 			updates[column], id,
 		); err != nil {
@@ -138,17 +211,17 @@ func UpdateUser(id string, updates map[string]any) bool {
 	return true
 }
 
-func DeleteUser(id string) bool {
-	if _, err := db.Exec(`DELETE FROM t_users WHERE id = $1`, id); err != nil {
+func (p *Postgres) DeleteUser(id string) bool {
+	if _, err := p.db.Exec(`DELETE FROM t_users WHERE id = $1`, id); err != nil {
 		log.Println(err)
 		return false
 	}
 	return true
 }
 
-func Followed(userId string, followId string) bool {
+func (p *Postgres) Followed(userId string, followId string) bool {
 	var count int
-	db.QueryRow(
+	p.db.QueryRow(
 		`SELECT COUNT(*) FROM follows WHERE user_id = $1 AND follow_id = $2`,
 		userId, followId,
 	).Scan(&count)
@@ -161,9 +234,9 @@ func Followed(userId string, followId string) bool {
 	}
 }
 
-func ToggleFollow(userId string, followId string) {
+func (p *Postgres) ToggleFollow(userId string, followId string) {
 	var query string
-	voted := Followed(userId, followId)
+	voted := p.Followed(userId, followId)
 
 	switch voted {
 	case false:
@@ -171,14 +244,14 @@ func ToggleFollow(userId string, followId string) {
 	default:
 		query = `DELETE FROM follows WHERE user_id = $1 AND follow_id = $2`
 	}
-	if _, err := db.Exec(query, userId, followId); err != nil {
+	if _, err := p.db.Exec(query, userId, followId); err != nil {
 		log.Println(err)
 	}
 }
 
-func ReadFollowers(userId string) []string {
+func (p *Postgres) ReadFollowers(userId string) []string {
 	var followers []string
-	rows, err := db.Query(
+	rows, err := p.db.Query(
 		`SELECT username FROM t_users WHERE id in
 		(SELECT user_id FROM follows WHERE follow_id = $1)`,
 		userId,
@@ -197,9 +270,9 @@ func ReadFollowers(userId string) []string {
 	return followers
 }
 
-func ReadFollowersCount(userId string) int {
+func (p *Postgres) ReadFollowersCount(userId string) int {
 	var count int
-	if err := db.QueryRow(
+	if err := p.db.QueryRow(
 		`SELECT COUNT(*) FROM t_users WHERE id in
 		(SELECT user_id FROM follows WHERE follow_id = $1)`,
 		userId,
@@ -209,9 +282,9 @@ func ReadFollowersCount(userId string) int {
 	return count
 }
 
-func ReadFollowing(userId string) []string {
+func (p *Postgres) ReadFollowing(userId string) []string {
 	var followers []string
-	rows, err := db.Query(
+	rows, err := p.db.Query(
 		`SELECT username FROM t_users WHERE id in
 		(SELECT follow_id FROM follows WHERE user_id = $1)`,
 		userId,
@@ -230,9 +303,9 @@ func ReadFollowing(userId string) []string {
 	return followers
 }
 
-func ReadFollowingCount(userId string) int {
+func (p *Postgres) ReadFollowingCount(userId string) int {
 	var count int
-	if err := db.QueryRow(
+	if err := p.db.QueryRow(
 		`SELECT COUNT(*) FROM t_users WHERE id in
 		(SELECT follow_id FROM follows WHERE user_id = $1)`,
 		userId,
@@ -241,3 +314,33 @@ func ReadFollowingCount(userId string) int {
 	}
 	return count
 }
+
+// Returns the follower, following and post counts of the users, and
+// whether userId follows each, in one query
+func (p *Postgres) ReadUserStats(userId string, ids []string) map[string]models.UserStats {
+	stats := make(map[string]models.UserStats, len(ids))
+	rows, err := p.db.Query(
+		`SELECT t_users.id,
+		(SELECT COUNT(*) FROM follows WHERE follow_id = t_users.id),
+		(SELECT COUNT(*) FROM follows WHERE user_id = t_users.id),
+		(SELECT COUNT(*) FROM posts WHERE user_id = t_users.id),
+		EXISTS (SELECT 1 FROM follows WHERE user_id = $2 AND follow_id = t_users.id)
+		FROM t_users WHERE t_users.id = ANY($1)`,
+		pq.Array(ids), userId,
+	)
+	if err != nil {
+		log.Println(err)
+		return stats
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		var stat models.UserStats
+		if err := rows.Scan(&id, &stat.Followers, &stat.Following, &stat.Posts, &stat.Followed); err != nil {
+			log.Println(err)
+			return stats
+		}
+		stats[id] = stat
+	}
+	return stats
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/internal/jobs"
+	"github.com/Bhar8at/bhar8at.github.io/internal/media"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+const (
+	kindMediaGC    = "media.gc"
+	kindMediaTrack = "media.track"
+	gcUsage        = "usage: gc [grace]"
+	// How long a file is kept after its last upload with nothing using it,
+	// so images of posts still being saved aren't collected
+	gcGrace = time.Hour
+	// Files removed per batch
+	gcBatch = 100
+)
+
+// Keys the app stores images and avatars under, as matched by media_key.
+// Private files are left out, quarantined uploads being kept for admins
+// and staged ones removed by their jobs.
+var mediaKeyPattern = regexp.MustCompile(`^((media|posts|avatars)/[^/]+|[0-9][^/]*)$`)
+
+// Records the files in the media store that aren't yet, such as those
+// uploaded before media_objects, so the unused ones are collected too.
+// Returns how many were recorded.
+func trackMedia(ctx context.Context, store database.Store, files media.Store) (int, error) {
+	tracked := 0
+	var batch []models.MediaObject
+	err := files.Walk(ctx, func(key string, modified time.Time) error {
+		if !mediaKeyPattern.MatchString(key) {
+			return nil
+		}
+		batch = append(batch, models.MediaObject{Key: key, TouchedAt: modified})
+		if len(batch) == gcBatch {
+			tracked += store.TrackMediaObjects(batch)
+			batch = nil
+		}
+		return nil
+	})
+	tracked += store.TrackMediaObjects(batch)
+	return tracked, err
+}
+
+// Removes the media files no post image or avatar uses, which weren't
+// uploaded again since before, returning how many were removed
+func collectMedia(ctx context.Context, store database.Store, files media.Store, before time.Time) (int, error) {
+	removed := 0
+	for {
+		objects := store.ReadUnreferencedMediaObjects(before, gcBatch)
+		deleted := 0
+		for _, object := range objects {
+			if ctx.Err() != nil {
+				return removed, ctx.Err()
+			}
+			if store.DeleteMediaObject(object.Key, before, func() error {
+				return files.Delete(ctx, object.Key)
+			}) {
+				deleted++
+			}
+		}
+		removed += deleted
+		// Stop when a batch made no progress, rather than retrying files
+		// that can't be removed for now
+		if len(objects) < gcBatch || deleted == 0 {
+			if deleted < len(objects) {
+				return removed, errors.New("unable to remove some unused media")
+			}
+			return removed, nil
+		}
+	}
+}
+
+// Removes unused media files every hour, looking for files that aren't
+// recorded once a day as listing the whole store is slow
+func scheduleMediaGC(queue *jobs.Queue, store database.Store, files media.Store) {
+	queue.Register(kindMediaGC, jobs.Kind{Run: func(ctx context.Context, payload []byte) error {
+		_, err := collectMedia(ctx, store, files, time.Now().Add(-gcGrace))
+		return err
+	}})
+	queue.Register(kindMediaTrack, jobs.Kind{Run: func(ctx context.Context, payload []byte) error {
+		_, err := trackMedia(ctx, store, files)
+		return err
+	}})
+	queue.Every(kindMediaGC, time.Hour)
+	queue.Every(kindMediaTrack, 24*time.Hour)
+}
+
+// Handles the `gc [grace]` command against POSTGRES_URI and the media store
+// configured as for the server, removing files unused for longer than the
+// grace, an hour unless given
+func runGC(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf(gcUsage)
+	}
+	grace := gcGrace
+	if len(args) == 1 {
+		var err error
+		if grace, err = time.ParseDuration(args[0]); err != nil || grace < 0 {
+			return fmt.Errorf("invalid grace %q", args[0])
+		}
+	}
+	db, err := database.NewPostgres(os.Getenv("POSTGRES_URI"))
+	if err != nil {
+		return err
+	}
+	files, err := media.FromEnv(os.Getenv("BASE_URL"), []byte(os.Getenv("SECRET_KEY")))
+	if err != nil {
+		return err
+	}
+	tracked, err := trackMedia(context.Background(), db, files)
+	fmt.Printf("recorded %d media files found in the store\n", tracked)
+	if err != nil {
+		return err
+	}
+	removed, err := collectMedia(context.Background(), db, files, time.Now().Add(-grace))
+	fmt.Printf("removed %d unused media files\n", removed)
+	return err
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/internal/media"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func TestCollectUntrackedMedia(t *testing.T) {
+	ctx := context.Background()
+	files := &media.Local{Dir: t.TempDir(), BaseURL: "http://localhost:8080/uploads"}
+	old := time.Now().Add(-48 * time.Hour)
+	for _, file := range []struct {
+		key     string
+		written time.Time
+	}{
+		// Legacy uploads from before media_objects, one still an avatar
+		{"1700000000-unused.png", old},
+		{"1700000001-avatar.png", old},
+		{"avatars/unused.png", old},
+		// Untracked but too recent to be collected yet
+		{"1700000002-recent.png", time.Now()},
+		// Never collected whatever their age
+		{"private/quarantine/upload", old},
+		{"notes.txt", old},
+	} {
+		if err := files.Put(ctx, file.key, []byte(file.key), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(files.Dir, filepath.FromSlash(file.key))
+		if err := os.Chtimes(path, file.written, file.written); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := database.NewMemory()
+	avatar := files.URL("1700000001-avatar.png")
+	store.CreateUser(&models.User{Id: "user", Username: "user", Avatar: &avatar})
+
+	tracked, err := trackMedia(ctx, store, files)
+	if err != nil || tracked != 4 {
+		t.Fatalf("trackMedia = %d, %v, want 4", tracked, err)
+	}
+	if tracked, _ := trackMedia(ctx, store, files); tracked != 0 {
+		t.Errorf("second trackMedia = %d, want 0", tracked)
+	}
+	removed, err := collectMedia(ctx, store, files, time.Now().Add(-gcGrace))
+	if err != nil || removed != 2 {
+		t.Fatalf("collectMedia = %d, %v, want 2", removed, err)
+	}
+
+	var left []string
+	files.Walk(ctx, func(key string, modified time.Time) error {
+		left = append(left, key)
+		return nil
+	})
+	sort.Strings(left)
+	want := []string{"1700000001-avatar.png", "1700000002-recent.png", "notes.txt", "private/quarantine/upload"}
+	if len(left) != len(want) {
+		t.Fatalf("left %v, want %v", left, want)
+	}
+	for i := range want {
+		if left[i] != want[i] {
+			t.Fatalf("left %v, want %v", left, want)
+		}
+	}
+}
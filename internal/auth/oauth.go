@@ -0,0 +1,388 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/internal"
+	"github.com/Bhar8at/bhar8at.github.io/internal/verify"
+	"github.com/Bhar8at/bhar8at.github.io/middleware"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+)
+
+// Time allowed for the user to sign in at the provider
+const flowTTL = 10 * time.Minute
+
+// Random characters added to a username from a provider that's taken
+const usernameSuffixLength = 6
+
+var errInvalidState = errors.New("invalid or expired oauth state")
+
+// Handler holds the dependencies of the OAuth routes
+type Handler struct {
+	store    database.Store
+	verifier *verify.Verifier
+	baseURL  string
+}
+
+func NewHandler(store database.Store, verifier *verify.Verifier, baseURL string) *Handler {
+	return &Handler{store: store, verifier: verifier, baseURL: baseURL}
+}
+
+// OAuth client for the provider, built for each request so concurrent
+// logins never share state
+func (h *Handler) config(ctx context.Context, p *Provider) (*oauth2.Config, error) {
+	endpoint, _, err := p.endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &oauth2.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		Scopes:       p.Scopes,
+		Endpoint:     endpoint,
+		RedirectURL:  h.baseURL + "/auth/" + p.Name,
+	}, nil
+}
+
+// Why the user was sent to the provider
+const (
+	modeSignUp = "signup"
+	modeLogin  = "login"
+	modeLink   = "link"
+)
+
+// A login started at the provider, remembered in the session until the
+// provider redirects back
+type flow struct {
+	verifier string
+	mode     string
+	// User linking the provider to their account
+	userId string
+}
+
+// Remembers the flow in the session so the callback can check it was
+// started by this browser, and returns its state value
+func startFlow(c *gin.Context, p *Provider, f flow) (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	state := base64.RawURLEncoding.EncodeToString(random)
+	session := sessions.Default(c)
+	session.Set("oauthState", state)
+	session.Set("oauthVerifier", f.verifier)
+	session.Set("oauthProvider", p.Name)
+	session.Set("oauthMode", f.mode)
+	session.Set("oauthUserId", f.userId)
+	session.Set("oauthSince", time.Now().Unix())
+	return state, session.Save()
+}
+
+// Checks the callback's state against the flow started in this browser
+// and clears it so it can't be replayed
+func finishFlow(c *gin.Context, p *Provider) (*flow, error) {
+	session := sessions.Default(c)
+	state, _ := session.Get("oauthState").(string)
+	provider, _ := session.Get("oauthProvider").(string)
+	since, _ := session.Get("oauthSince").(int64)
+	var f flow
+	f.verifier, _ = session.Get("oauthVerifier").(string)
+	f.mode, _ = session.Get("oauthMode").(string)
+	f.userId, _ = session.Get("oauthUserId").(string)
+	for _, key := range []string{"oauthState", "oauthVerifier", "oauthProvider", "oauthMode", "oauthUserId", "oauthSince"} {
+		session.Delete(key)
+	}
+	session.Save()
+	if state == "" || f.verifier == "" || provider != p.Name || time.Since(time.Unix(since, 0)) > flowTTL ||
+		subtle.ConstantTimeCompare([]byte(state), []byte(c.Query("state"))) != 1 {
+		return nil, errInvalidState
+	}
+	return &f, nil
+}
+
+func (h *Handler) redirect(p *Provider, mode string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f := flow{verifier: oauth2.GenerateVerifier(), mode: mode}
+		if mode == modeLink {
+			session := sessions.Default(c)
+			f.userId, _ = session.Get("userId").(string)
+			if f.userId == "" {
+				c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+					"error":   "401 Unauthorized",
+					"message": "User not logged in.",
+				})
+				return
+			}
+		}
+		config, err := h.config(c.Request.Context(), p)
+		if err != nil {
+			log.Println(err)
+			c.HTML(http.StatusBadGateway, "errorT.html", gin.H{
+				"error":   "502 Bad Gateway",
+				"message": fmt.Sprintf("%s is unavailable, try again later.", p.DisplayName),
+			})
+			return
+		}
+		state, err := startFlow(c, p, f)
+		if err != nil {
+			log.Println(err)
+			c.HTML(http.StatusInternalServerError, "errorT.html", gin.H{
+				"error":   "500 Internal Server Error",
+				"message": "Unable to start login, try again later.",
+			})
+			return
+		}
+		c.Redirect(http.StatusFound, config.AuthCodeURL(state, oauth2.S256ChallengeOption(f.verifier)))
+	}
+}
+
+// Sends the user to the provider to sign up
+func (h *Handler) SignUp(p *Provider) gin.HandlerFunc {
+	return h.redirect(p, modeSignUp)
+}
+
+// Sends the user to the provider to login
+func (h *Handler) Login(p *Provider) gin.HandlerFunc {
+	return h.redirect(p, modeLogin)
+}
+
+// Sends a logged in user to the provider to add it as a way to login,
+// must run after AuthMiddleware
+func (h *Handler) Link(p *Provider) gin.HandlerFunc {
+	return h.redirect(p, modeLink)
+}
+
+// Fetches the user's claims with the access token
+func (h *Handler) fetchUser(ctx context.Context, p *Provider, config *oauth2.Config, token *oauth2.Token) (*models.OAuthUser, error) {
+	_, userInfo, err := p.endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	response, err := config.Client(ctx, token).Get(userInfo)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("userinfo for %s: %s", p.Name, response.Status)
+	}
+	var claims map[string]any
+	if err := json.NewDecoder(response.Body).Decode(&claims); err != nil {
+		return nil, err
+	}
+	return p.mapClaims(claims)
+}
+
+// Handles the provider redirecting back after the user signed in there
+func (h *Handler) Callback(p *Provider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f, err := finishFlow(c, p)
+		if err != nil {
+			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
+				"error":   "400 Bad Request",
+				"message": "Invalid authorization URL.",
+			})
+			return
+		}
+		// The user cancelled or the provider refused the request
+		if reason := c.Query("error"); reason != "" {
+			log.Printf("%s login failed: %s", p.Name, reason)
+			c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+				"error":   "401 Unauthorized",
+				"message": fmt.Sprintf("%s login was cancelled.", p.DisplayName),
+			})
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 15*time.Second)
+		defer cancel()
+		config, err := h.config(ctx, p)
+		if err != nil {
+			log.Println(err)
+			c.HTML(http.StatusBadGateway, "errorT.html", gin.H{
+				"error":   "502 Bad Gateway",
+				"message": fmt.Sprintf("%s is unavailable, try again later.", p.DisplayName),
+			})
+			return
+		}
+		token, err := config.Exchange(ctx, c.Query("code"), oauth2.VerifierOption(f.verifier))
+		if err != nil {
+			log.Println(err)
+			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
+				"error": "400 Bad Request",
+			})
+			return
+		}
+		authUser, err := h.fetchUser(ctx, p, config, token)
+		if err != nil {
+			log.Println(err)
+			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
+				"error":   "400 Bad Request",
+				"message": "Unable to retrieve authorization response, try again later.",
+			})
+			return
+		}
+		switch f.mode {
+		case modeLink:
+			h.link(c, p, f.userId, authUser)
+		case modeLogin:
+			h.login(c, p, authUser)
+		default:
+			h.signUp(c, p, authUser)
+		}
+	}
+}
+
+func newIdentity(userId string, p *Provider, authUser *models.OAuthUser) *models.Identity {
+	return &models.Identity{
+		Id:        uuid.NewString(),
+		UserId:    userId,
+		Provider:  p.Name,
+		Subject:   authUser.Subject,
+		Email:     authUser.Email,
+		CreatedAt: time.Now(),
+	}
+}
+
+// Finds the user the provider login is linked to
+func (h *Handler) linkedUser(p *Provider, authUser *models.OAuthUser) *models.User {
+	if identity := h.store.ReadIdentity(p.Name, authUser.Subject); identity != nil {
+		return h.store.ReadUserById(identity.UserId)
+	}
+	// Accounts created through OAuth before logins were linked by subject
+	// were matched by email, link them on their first login
+	user := h.store.ReadUserByEmail(authUser.Email)
+	if user == nil || !authUser.Verified || !h.store.IsOAuthUser(user.Id) || len(h.store.ReadIdentities(user.Id)) > 0 {
+		return nil
+	}
+	if !h.store.CreateIdentity(newIdentity(user.Id, p, authUser)) {
+		return nil
+	}
+	return user
+}
+
+func (h *Handler) login(c *gin.Context, p *Provider, authUser *models.OAuthUser) {
+	user := h.linkedUser(p, authUser)
+	if user == nil {
+		c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+			"error":   "401 Unauthorized",
+			"message": fmt.Sprintf("No account is linked to this %s login.", p.DisplayName),
+		})
+		return
+	}
+	next, err := middleware.Login(c, h.store, user.Id)
+	if err != nil {
+		log.Println(err)
+		c.HTML(http.StatusInternalServerError, "errorT.html", gin.H{
+			"error":   "500 Internal Server Error",
+			"message": "Unable to login, try again later.",
+		})
+		return
+	}
+	c.Redirect(http.StatusFound, next)
+}
+
+func (h *Handler) signUp(c *gin.Context, p *Provider, authUser *models.OAuthUser) {
+	// Signing up again with a linked login just logs the user in
+	if h.linkedUser(p, authUser) != nil {
+		h.login(c, p, authUser)
+		return
+	}
+	if exists := h.store.ReadUserByEmail(authUser.Email); exists != nil {
+		c.HTML(http.StatusForbidden, "errorT.html", gin.H{
+			"error":   "403 Forbidden",
+			"message": fmt.Sprintf("Account already exists with the given email, login and link %s from your settings.", p.DisplayName),
+		})
+		return
+	}
+	var user models.User
+	user.Username = authUser.Username
+	// Update the username if it already exists in the database, cutting it
+	// short enough for the suffix to fit
+	if result := h.store.ReadUserByName(user.Username); result != nil {
+		if len(user.Username) > maxUsernameLength-usernameSuffixLength {
+			user.Username = user.Username[:maxUsernameLength-usernameSuffixLength]
+		}
+		user.Username += internal.RandomString(usernameSuffixLength)
+	}
+	user.CreatedAt = time.Now()
+	user.Email = &authUser.Email
+	user.Verified = authUser.Verified
+	user.Id = uuid.NewString()
+	// Generate a random password for oauth user
+	user.Password = uuid.NewString()
+	user.HashPassword()
+	if authUser.Avatar != nil {
+		user.Avatar = authUser.Avatar
+	}
+	if res := h.store.CreateUser(&user); !res {
+		c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
+			"error":   "400 Bad Request",
+			"message": "Unable to create account, try again later.",
+		})
+		return
+	}
+	// Add to table that identifies OAuth users
+	h.store.CreateOAuthUser(user.Id)
+	if !h.store.CreateIdentity(newIdentity(user.Id, p, authUser)) {
+		h.store.DeleteUser(user.Id)
+		c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
+			"error":   "400 Bad Request",
+			"message": "Unable to create account, try again later.",
+		})
+		return
+	}
+	token, _ := middleware.CreateToken(c, h.store, user.Id)
+	session := sessions.Default(c)
+	session.Set("Authorization", token)
+	session.Save()
+	if user.Verified {
+		c.Redirect(http.StatusFound, "/feed")
+	} else {
+		if err := h.verifier.Send(&user); err != nil {
+			log.Println(err)
+		}
+		c.Redirect(http.StatusFound, "/auth/verify?signup=true")
+	}
+}
+
+// Adds the provider login to the account that started linking it, as
+// long as the same browser is still logged in as that user
+func (h *Handler) link(c *gin.Context, p *Provider, userId string, authUser *models.OAuthUser) {
+	if userId == "" || middleware.SessionUserId(c, h.store) != userId {
+		c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+			"error":   "401 Unauthorized",
+			"message": "User not logged in.",
+		})
+		return
+	}
+	if identity := h.store.ReadIdentity(p.Name, authUser.Subject); identity != nil {
+		if identity.UserId != userId {
+			c.HTML(http.StatusForbidden, "errorT.html", gin.H{
+				"error":   "403 Forbidden",
+				"message": fmt.Sprintf("This %s login is already linked to another account.", p.DisplayName),
+			})
+			return
+		}
+	} else if !h.store.CreateIdentity(newIdentity(userId, p, authUser)) {
+		c.HTML(http.StatusInternalServerError, "errorT.html", gin.H{
+			"error":   "500 Internal Server Error",
+			"message": "Unable to link login, try again later.",
+		})
+		return
+	}
+	c.Redirect(http.StatusFound, "/user/settings/logins")
+}
@@ -0,0 +1,270 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"golang.org/x/oauth2"
+)
+
+// Names that would clash with the other routes under /auth
+var reservedNames = map[string]bool{
+	"signup": true, "login": true, "verify": true, "2fa": true, "forgot": true, "reset": true,
+}
+
+var validName = regexp.MustCompile(`^[a-z0-9-]{1,32}$`)
+
+// Usernames are letters, digits, periods and underscores, as on the signup
+// form, and fit the 32 characters of the column
+const maxUsernameLength = 32
+
+var invalidUsername = regexp.MustCompile(`[^A-Za-z0-9._]+`)
+
+// Turns a name from a provider into a username, empty when nothing of it
+// can be used
+func normalizeUsername(name string) string {
+	name = strings.Trim(invalidUsername.ReplaceAllString(name, "_"), "_")
+	if len(name) > maxUsernameLength {
+		name = name[:maxUsernameLength]
+	}
+	return name
+}
+
+// Configuration of an OpenID Connect provider, endpoints left empty are
+// read from the issuer's discovery document
+type ProviderConfig struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+	// Font Awesome classes for the login button icon
+	Icon         string   `json:"icon"`
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"clientId"`
+	ClientSecret string   `json:"clientSecret"`
+	Scopes       []string `json:"scopes"`
+	AuthURL      string   `json:"authorizationEndpoint"`
+	TokenURL     string   `json:"tokenEndpoint"`
+	UserInfoURL  string   `json:"userinfoEndpoint"`
+	// Claims holding each user field, defaults to the standard claims
+	Claims ClaimMapping `json:"claims"`
+}
+
+type ClaimMapping struct {
+	Subject  string `json:"subject"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+	Avatar   string `json:"avatar"`
+	Verified string `json:"verified"`
+}
+
+// Provider is an OpenID Connect issuer users can sign up and login with
+type Provider struct {
+	ProviderConfig
+
+	mu       sync.Mutex
+	endpoint *oauth2.Endpoint
+	userInfo string
+}
+
+func newProvider(config ProviderConfig) (*Provider, error) {
+	if !validName.MatchString(config.Name) || reservedNames[config.Name] {
+		return nil, fmt.Errorf("provider %q: name must be lowercase letters, digits or dashes and not a reserved route", config.Name)
+	}
+	if config.ClientID == "" {
+		return nil, fmt.Errorf("provider %q: missing clientId", config.Name)
+	}
+	if config.Issuer == "" && (config.AuthURL == "" || config.TokenURL == "" || config.UserInfoURL == "") {
+		return nil, fmt.Errorf("provider %q: needs an issuer or all three endpoints", config.Name)
+	}
+	if config.DisplayName == "" {
+		config.DisplayName = config.Name
+	}
+	if config.Icon == "" {
+		config.Icon = "fa-solid fa-right-to-bracket"
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	defaults := ClaimMapping{
+		Subject:  "sub",
+		Email:    "email",
+		Username: "preferred_username",
+		Avatar:   "picture",
+		Verified: "email_verified",
+	}
+	for _, field := range []struct {
+		value    *string
+		fallback string
+	}{
+		{&config.Claims.Subject, defaults.Subject},
+		{&config.Claims.Email, defaults.Email},
+		{&config.Claims.Username, defaults.Username},
+		{&config.Claims.Avatar, defaults.Avatar},
+		{&config.Claims.Verified, defaults.Verified},
+	} {
+		if *field.value == "" {
+			*field.value = field.fallback
+		}
+	}
+	return &Provider{ProviderConfig: config}, nil
+}
+
+// Returns the provider's endpoints, fetching the discovery document the
+// first time they are needed so an unreachable issuer doesn't stop boot
+func (p *Provider) endpoints(ctx context.Context) (oauth2.Endpoint, string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.endpoint != nil {
+		return *p.endpoint, p.userInfo, nil
+	}
+	endpoint := oauth2.Endpoint{AuthURL: p.AuthURL, TokenURL: p.TokenURL}
+	userInfo := p.UserInfoURL
+	if endpoint.AuthURL == "" || endpoint.TokenURL == "" || userInfo == "" {
+		var discovery struct {
+			Issuer                string `json:"issuer"`
+			AuthorizationEndpoint string `json:"authorization_endpoint"`
+			TokenEndpoint         string `json:"token_endpoint"`
+			UserinfoEndpoint      string `json:"userinfo_endpoint"`
+		}
+		url := strings.TrimSuffix(p.Issuer, "/") + "/.well-known/openid-configuration"
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return oauth2.Endpoint{}, "", err
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			return oauth2.Endpoint{}, "", err
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return oauth2.Endpoint{}, "", fmt.Errorf("discovery for %s: %s", p.Name, response.Status)
+		}
+		if err := json.NewDecoder(response.Body).Decode(&discovery); err != nil {
+			return oauth2.Endpoint{}, "", err
+		}
+		if strings.TrimSuffix(discovery.Issuer, "/") != strings.TrimSuffix(p.Issuer, "/") {
+			return oauth2.Endpoint{}, "", fmt.Errorf("discovery for %s: issuer mismatch %q", p.Name, discovery.Issuer)
+		}
+		if endpoint.AuthURL == "" {
+			endpoint.AuthURL = discovery.AuthorizationEndpoint
+		}
+		if endpoint.TokenURL == "" {
+			endpoint.TokenURL = discovery.TokenEndpoint
+		}
+		if userInfo == "" {
+			userInfo = discovery.UserinfoEndpoint
+		}
+	}
+	p.endpoint = &endpoint
+	p.userInfo = userInfo
+	return endpoint, userInfo, nil
+}
+
+// Maps the claims returned by the userinfo endpoint onto a user
+func (p *Provider) mapClaims(claims map[string]any) (*models.OAuthUser, error) {
+	str := func(name string) string {
+		value, _ := claims[name].(string)
+		return value
+	}
+	user := models.OAuthUser{
+		Subject:  str(p.Claims.Subject),
+		Email:    str(p.Claims.Email),
+		Username: str(p.Claims.Username),
+	}
+	if avatar := str(p.Claims.Avatar); avatar != "" {
+		user.Avatar = &avatar
+	}
+	// Some issuers send booleans as strings
+	switch verified := claims[p.Claims.Verified].(type) {
+	case bool:
+		user.Verified = verified
+	case string:
+		user.Verified = verified == "true"
+	}
+	if user.Subject == "" || user.Email == "" {
+		return nil, errors.New("provider response is missing the subject or email")
+	}
+	user.Username = normalizeUsername(user.Username)
+	if user.Username == "" {
+		local, _, _ := strings.Cut(user.Email, "@")
+		user.Username = normalizeUsername(local)
+	}
+	if user.Username == "" {
+		user.Username = "user"
+	}
+	return &user, nil
+}
+
+// Registry holds the enabled providers by name
+type Registry struct {
+	providers map[string]*Provider
+	order     []string
+}
+
+// Loads providers from the JSON file at OIDC_PROVIDERS (providers.json by
+// default), values like ${GITLAB_SECRET} are read from the environment.
+// Without a file, Google is enabled when GOOGLE_CLIENT_ID is set.
+func LoadRegistry() (*Registry, error) {
+	path := os.Getenv("OIDC_PROVIDERS")
+	if path == "" {
+		path = "providers.json"
+	}
+	var configs []ProviderConfig
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal([]byte(os.ExpandEnv(string(data))), &configs); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	case errors.Is(err, os.ErrNotExist):
+		if os.Getenv("GOOGLE_CLIENT_ID") != "" {
+			configs = append(configs, ProviderConfig{
+				Name:         "google",
+				DisplayName:  "Google",
+				Icon:         "fa-brands fa-google",
+				Issuer:       "https://accounts.google.com",
+				ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
+				ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+				Claims:       ClaimMapping{Username: "given_name"},
+			})
+		}
+	default:
+		return nil, err
+	}
+	registry := &Registry{providers: make(map[string]*Provider)}
+	for _, config := range configs {
+		provider, err := newProvider(config)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := registry.providers[provider.Name]; ok {
+			return nil, fmt.Errorf("provider %q is configured twice", provider.Name)
+		}
+		registry.providers[provider.Name] = provider
+		registry.order = append(registry.order, provider.Name)
+	}
+	return registry, nil
+}
+
+func (r *Registry) Get(name string) *Provider {
+	return r.providers[name]
+}
+
+// Returns the providers in the order they were configured
+func (r *Registry) List() []*Provider {
+	providers := make([]*Provider, 0, len(r.order))
+	for _, name := range r.order {
+		providers = append(providers, r.providers[name])
+	}
+	return providers
+}
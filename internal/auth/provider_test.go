@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMapClaimsUsername(t *testing.T) {
+	p := &Provider{ProviderConfig: ProviderConfig{Claims: ClaimMapping{
+		Subject:  "sub",
+		Email:    "email",
+		Username: "preferred_username",
+	}}}
+	tests := []struct {
+		name     string
+		username string
+		email    string
+		want     string
+	}{
+		{"kept", "jane.doe_1", "jane@example.com", "jane.doe_1"},
+		{"spaces", "Jane Doe", "jane@example.com", "Jane_Doe"},
+		{"symbols trimmed", "-jane!", "jane@example.com", "jane"},
+		{"truncated", strings.Repeat("a", 40), "jane@example.com", strings.Repeat("a", 32)},
+		{"email fallback", "", "jane+news@example.com", "jane_news"},
+		{"unusable name falls back to email", "李雷", "lei@example.com", "lei"},
+		{"long email truncated", "", strings.Repeat("b", 40) + "@example.com", strings.Repeat("b", 32)},
+		{"nothing usable", "李雷", "李@example.com", "user"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			user, err := p.mapClaims(map[string]any{
+				"sub":                "1",
+				"email":              test.email,
+				"preferred_username": test.username,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.Username != test.want {
+				t.Errorf("username = %q, want %q", user.Username, test.want)
+			}
+		})
+	}
+}
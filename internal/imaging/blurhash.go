@@ -0,0 +1,104 @@
+package imaging
+
+import (
+	"image"
+	"math"
+	"strings"
+)
+
+const base83 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// Components of the blurhash across and down, more across for the usual
+// landscape photo
+const (
+	componentsX = 4
+	componentsY = 3
+)
+
+// Encodes the image as a blurhash, a short string clients can turn into a
+// blurred placeholder while the image loads, see https://blurha.sh
+func blurhash(img *image.NRGBA) string {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	if width == 0 || height == 0 {
+		return ""
+	}
+	linear := make([][3]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			pixel := img.Pix[img.PixOffset(x, y):]
+			linear[y*width+x] = [3]float64{toLinear(pixel[0]), toLinear(pixel[1]), toLinear(pixel[2])}
+		}
+	}
+	var factors [componentsX * componentsY][3]float64
+	for j := 0; j < componentsY; j++ {
+		for i := 0; i < componentsX; i++ {
+			normalisation := 2.0
+			if i == 0 && j == 0 {
+				normalisation = 1
+			}
+			var factor [3]float64
+			for y := 0; y < height; y++ {
+				basisY := math.Cos(math.Pi * float64(j) * float64(y) / float64(height))
+				for x := 0; x < width; x++ {
+					basis := basisY * math.Cos(math.Pi*float64(i)*float64(x)/float64(width))
+					for c, value := range linear[y*width+x] {
+						factor[c] += basis * value
+					}
+				}
+			}
+			for c := range factor {
+				factor[c] *= normalisation / float64(width*height)
+			}
+			factors[j*componentsX+i] = factor
+		}
+	}
+
+	var hash strings.Builder
+	encode83(&hash, (componentsX-1)+(componentsY-1)*9, 1)
+	maximum := 0.0
+	for _, factor := range factors[1:] {
+		for _, value := range factor {
+			maximum = math.Max(maximum, math.Abs(value))
+		}
+	}
+	quantised := int(math.Max(0, math.Min(82, math.Floor(maximum*166-0.5))))
+	encode83(&hash, quantised, 1)
+	maximum = float64(quantised+1) / 166
+
+	dc := factors[0]
+	encode83(&hash, toSRGB(dc[0])<<16|toSRGB(dc[1])<<8|toSRGB(dc[2]), 4)
+	for _, factor := range factors[1:] {
+		value := 0
+		for _, component := range factor {
+			value = value*19 + int(math.Max(0, math.Min(18, math.Floor(signPow(component/maximum, 0.5)*9+9.5))))
+		}
+		encode83(&hash, value, 2)
+	}
+	return hash.String()
+}
+
+func encode83(hash *strings.Builder, value int, length int) {
+	for i := length - 1; i >= 0; i-- {
+		hash.WriteByte(base83[value/int(math.Pow(83, float64(i)))%83])
+	}
+}
+
+func toLinear(value uint8) float64 {
+	v := float64(value) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func toSRGB(value float64) int {
+	v := math.Max(0, math.Min(1, value))
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value float64, exponent float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), exponent), value)
+}
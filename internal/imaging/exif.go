@@ -0,0 +1,163 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+)
+
+var errJPEG = errors.New("imaging: malformed jpeg")
+
+const (
+	markerSOI  = 0xd8
+	markerEOI  = 0xd9
+	markerSOS  = 0xda
+	markerAPP0 = 0xe0
+	markerAPP1 = 0xe1
+	markerAPP2 = 0xe2
+	// Adobe color transform, needed to decode CMYK images
+	markerAPP14 = 0xee
+	markerAPP15 = 0xef
+	markerCOM   = 0xfe
+)
+
+// Calls visit with each marker and segment of the JPEG until the image
+// data starts, returning the offset of the start of scan segment
+func segments(data []byte, visit func(marker byte, segment []byte)) (int, error) {
+	if len(data) < 4 || data[0] != 0xff || data[1] != markerSOI {
+		return 0, errJPEG
+	}
+	pos := 2
+	for {
+		for pos+1 < len(data) && data[pos] == 0xff && data[pos+1] == 0xff {
+			pos++
+		}
+		if pos+4 > len(data) || data[pos] != 0xff {
+			return 0, errJPEG
+		}
+		marker := data[pos+1]
+		if marker == markerSOS {
+			return pos, nil
+		}
+		end := pos + 2 + int(binary.BigEndian.Uint16(data[pos+2:]))
+		if end > len(data) {
+			return 0, errJPEG
+		}
+		visit(marker, data[pos:end])
+		pos = end
+	}
+}
+
+// Value of the EXIF orientation tag, 1 for upright when missing
+func jpegOrientation(data []byte) int {
+	orientation := 1
+	segments(data, func(marker byte, segment []byte) {
+		if marker != markerAPP1 || !bytes.HasPrefix(segment[4:], []byte("Exif\x00\x00")) {
+			return
+		}
+		tiff := segment[10:]
+		if len(tiff) < 8 {
+			return
+		}
+		var order binary.ByteOrder
+		switch string(tiff[:2]) {
+		case "II":
+			order = binary.LittleEndian
+		case "MM":
+			order = binary.BigEndian
+		default:
+			return
+		}
+		ifd := int(order.Uint32(tiff[4:]))
+		if ifd < 8 || ifd+2 > len(tiff) {
+			return
+		}
+		count := int(order.Uint16(tiff[ifd:]))
+		for i := 0; i < count; i++ {
+			entry := ifd + 2 + i*12
+			if entry+12 > len(tiff) {
+				return
+			}
+			if order.Uint16(tiff[entry:]) == 0x0112 {
+				if value := int(order.Uint16(tiff[entry+8:])); value >= 1 && value <= 8 {
+					orientation = value
+				}
+				return
+			}
+		}
+	})
+	return orientation
+}
+
+// Copies the JPEG without EXIF, XMP, IPTC and comments, keeping the
+// segments needed to show it as it was, and without any images appended
+// after it, as cameras do for depth maps and previews
+func stripJPEG(data []byte) ([]byte, error) {
+	stripped := []byte{0xff, markerSOI}
+	pos, err := segments(data, func(marker byte, segment []byte) {
+		switch {
+		case marker == markerAPP0, marker == markerAPP14,
+			marker == markerAPP2 && bytes.HasPrefix(segment[4:], []byte("ICC_PROFILE\x00")):
+			stripped = append(stripped, segment...)
+		case marker >= markerAPP1 && marker <= markerAPP15, marker == markerCOM:
+		default:
+			stripped = append(stripped, segment...)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Progressive images have more tables and scans between the scan data,
+	// so the data is followed until the end of image marker
+	for start := pos; pos+1 < len(data); pos++ {
+		if data[pos] != 0xff || pos == start {
+			continue
+		}
+		next := data[pos+1]
+		if next == 0x00 || next == 0xff || (next >= 0xd0 && next <= 0xd7) {
+			continue
+		}
+		if next == markerEOI {
+			stripped = append(stripped, data[start:pos+2]...)
+			return stripped, nil
+		}
+	}
+	return nil, errJPEG
+}
+
+// Turns the image upright according to its EXIF orientation
+func orient(img *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	outWidth, outHeight := width, height
+	if orientation >= 5 {
+		outWidth, outHeight = height, width
+	}
+	out := image.NewNRGBA(image.Rect(0, 0, outWidth, outHeight))
+	for y := 0; y < outHeight; y++ {
+		for x := 0; x < outWidth; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = width-1-x, y
+			case 3:
+				sx, sy = width-1-x, height-1-y
+			case 4:
+				sx, sy = x, height-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, height-1-x
+			case 7:
+				sx, sy = width-1-y, height-1-x
+			case 8:
+				sx, sy = width-1-y, x
+			}
+			copy(out.Pix[out.PixOffset(x, y):][:4], img.Pix[img.PixOffset(sx, sy):][:4])
+		}
+	}
+	return out
+}
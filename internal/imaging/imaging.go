@@ -0,0 +1,157 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	scale "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// Longest side of the renditions, in pixels
+	ThumbnailSize = 320
+	MediumSize    = 1280
+	// JPEG quality of originals that have to be encoded again, and of renditions
+	originalQuality  = 90
+	renditionQuality = 80
+)
+
+var ErrFormat = errors.New("imaging: unsupported image format")
+
+// Encoded image ready to be stored
+type Rendition struct {
+	Data   []byte
+	Format string
+	Width  int
+	Height int
+}
+
+// Result of processing an upload. Medium and Thumbnail are nil when the
+// original already fits within their size.
+type Result struct {
+	Original  Rendition
+	Medium    *Rendition
+	Thumbnail *Rendition
+	Blurhash  string
+}
+
+// Validates and decodes a JPEG, PNG, GIF or WebP image, turns it upright,
+// drops metadata such as EXIF locations, and makes the smaller renditions
+// and a blurhash placeholder
+func Process(data []byte) (*Result, error) {
+	if _, _, err := Validate(data); err != nil {
+		return nil, err
+	}
+	decoded, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		// Corrupt past the header
+		return nil, ErrFormat
+	}
+	orientation := 1
+	if format == "jpeg" {
+		orientation = jpegOrientation(data)
+	}
+	img := orient(toNRGBA(decoded), orientation)
+
+	result := &Result{}
+	switch {
+	case format == "jpeg" && orientation == 1:
+		// Already upright, so only the metadata has to go, keeping the
+		// pixels as they were
+		stripped, err := stripJPEG(data)
+		if err != nil {
+			return nil, err
+		}
+		result.Original = Rendition{Data: stripped, Format: "jpeg", Width: img.Rect.Dx(), Height: img.Rect.Dy()}
+	case format == "gif":
+		// GIFs carry no EXIF, and encoding them again would lose animations.
+		// The first frame can be smaller than the whole animation.
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		result.Original = Rendition{Data: data, Format: "gif", Width: config.Width, Height: config.Height}
+	case format == "png":
+		// Lossless, so encoding again only loses the metadata chunks
+		result.Original, err = encodePNG(img)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		result.Original, err = encode(img, originalQuality)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	small := img
+	if medium := fit(img, MediumSize); medium != img {
+		rendition, err := encode(medium, renditionQuality)
+		if err != nil {
+			return nil, err
+		}
+		result.Medium, small = &rendition, medium
+	}
+	if thumbnail := fit(small, ThumbnailSize); thumbnail != small {
+		rendition, err := encode(thumbnail, renditionQuality)
+		if err != nil {
+			return nil, err
+		}
+		result.Thumbnail, small = &rendition, thumbnail
+	}
+	result.Blurhash = blurhash(small)
+	return result, nil
+}
+
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Rect.Min == (image.Point{}) {
+		return nrgba
+	}
+	bounds := img.Bounds()
+	nrgba := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(nrgba, nrgba.Rect, img, bounds.Min, draw.Src)
+	return nrgba
+}
+
+// Scales the image down to fit a square of the size, returning it as is
+// when it already fits
+func fit(img *image.NRGBA, size int) *image.NRGBA {
+	width, height := img.Rect.Dx(), img.Rect.Dy()
+	if width <= size && height <= size {
+		return img
+	}
+	if width > height {
+		width, height = size, max(1, height*size/width)
+	} else {
+		width, height = max(1, width*size/height), size
+	}
+	scaled := image.NewNRGBA(image.Rect(0, 0, width, height))
+	scale.CatmullRom.Scale(scaled, scaled.Rect, img, img.Rect, draw.Src, nil)
+	return scaled
+}
+
+// JPEG for opaque images, PNG to keep transparency
+func encode(img *image.NRGBA, quality int) (Rendition, error) {
+	if !img.Opaque() {
+		return encodePNG(img)
+	}
+	var buffer bytes.Buffer
+	if err := jpeg.Encode(&buffer, img, &jpeg.Options{Quality: quality}); err != nil {
+		return Rendition{}, err
+	}
+	return Rendition{Data: buffer.Bytes(), Format: "jpeg", Width: img.Rect.Dx(), Height: img.Rect.Dy()}, nil
+}
+
+func encodePNG(img *image.NRGBA) (Rendition, error) {
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, img); err != nil {
+		return Rendition{}, err
+	}
+	return Rendition{Data: buffer.Bytes(), Format: "png", Width: img.Rect.Dx(), Height: img.Rect.Dy()}, nil
+}
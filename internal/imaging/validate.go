@@ -0,0 +1,69 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+)
+
+const (
+	// Largest file accepted
+	MaxBytes = 10 << 20
+	// Largest image accepted, as decoding takes 4 bytes a pixel
+	MaxSide   = 10000
+	MaxPixels = 40_000_000
+)
+
+var (
+	ErrTooLarge   = errors.New("imaging: file too large")
+	ErrDimensions = errors.New("imaging: image dimensions out of range")
+)
+
+// Formats accepted, recognised by the bytes they start with rather than
+// anything the client says about the file
+var signatures = []struct {
+	format string
+	match  func(data []byte) bool
+}{
+	{"jpeg", func(data []byte) bool { return bytes.HasPrefix(data, []byte("\xff\xd8\xff")) }},
+	{"png", func(data []byte) bool { return bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")) }},
+	{"gif", func(data []byte) bool {
+		return bytes.HasPrefix(data, []byte("GIF87a")) || bytes.HasPrefix(data, []byte("GIF89a"))
+	}},
+	{"webp", func(data []byte) bool {
+		return len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP"
+	}},
+}
+
+// Format of the file by its magic bytes, empty when not an accepted image
+func Sniff(data []byte) string {
+	for _, signature := range signatures {
+		if signature.match(data) {
+			return signature.format
+		}
+	}
+	return ""
+}
+
+// Checks the file is an accepted image within the limits, reading only
+// its header so oversized images are refused before being decoded
+func Validate(data []byte) (image.Config, string, error) {
+	if len(data) > MaxBytes {
+		return image.Config{}, "", ErrTooLarge
+	}
+	format := Sniff(data)
+	if format == "" {
+		return image.Config{}, "", ErrFormat
+	}
+	config, decoded, err := image.DecodeConfig(bytes.NewReader(data))
+	// A file that starts as one format but decodes as another is refused
+	// too, in case it's made to be read differently elsewhere
+	if err != nil || decoded != format {
+		return image.Config{}, "", ErrFormat
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width > MaxSide || config.Height > MaxSide ||
+		config.Width*config.Height > MaxPixels {
+		return image.Config{}, "", ErrDimensions
+	}
+	return config, format, nil
+}
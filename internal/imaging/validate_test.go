@@ -0,0 +1,97 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encoded(t *testing.T, format string, width int, height int) []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	var buffer bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buffer, img)
+	case "jpeg":
+		err = jpeg.Encode(&buffer, img, nil)
+	case "gif":
+		err = gif.Encode(&buffer, img, nil)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+// PNG signature and header claiming the size, without any pixels, which
+// is all that's read to find the dimensions
+func pngHeader(width uint32, height uint32) []byte {
+	chunk := make([]byte, 17)
+	copy(chunk, "IHDR")
+	binary.BigEndian.PutUint32(chunk[4:], width)
+	binary.BigEndian.PutUint32(chunk[8:], height)
+	copy(chunk[12:], []byte{8, 6, 0, 0, 0})
+	data := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0d")
+	data = append(data, chunk...)
+	return binary.BigEndian.AppendUint32(data, crc32.ChecksumIEEE(chunk))
+}
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"png", encoded(t, "png", 1, 1), "png"},
+		{"jpeg", encoded(t, "jpeg", 1, 1), "jpeg"},
+		{"gif87a", []byte("GIF87a"), "gif"},
+		{"gif89a", encoded(t, "gif", 1, 1), "gif"},
+		{"webp", []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), "webp"},
+		{"riff that isn't webp", []byte("RIFF\x00\x00\x00\x00WAVEfmt "), ""},
+		{"text", []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"/>"), ""},
+		{"empty", nil, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := Sniff(test.data); got != test.want {
+				t.Errorf("Sniff = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		wantFormat string
+		wantErr    error
+	}{
+		{"png", encoded(t, "png", 3, 2), "png", nil},
+		{"jpeg", encoded(t, "jpeg", 3, 2), "jpeg", nil},
+		{"gif", encoded(t, "gif", 3, 2), "gif", nil},
+		{"largest side", pngHeader(MaxSide, 1), "png", nil},
+		{"too many bytes", append(encoded(t, "png", 1, 1), make([]byte, MaxBytes)...), "", ErrTooLarge},
+		{"too wide", pngHeader(MaxSide+1, 1), "", ErrDimensions},
+		{"too tall", pngHeader(1, MaxSide+1), "", ErrDimensions},
+		{"too many pixels", pngHeader(MaxSide, MaxPixels/MaxSide+1), "", ErrDimensions},
+		{"empty image", pngHeader(0, 1), "", ErrFormat},
+		{"not an image", []byte("hello"), "", ErrFormat},
+		{"truncated header", encoded(t, "png", 1, 1)[:12], "", ErrFormat},
+		{"webp signature over png", append([]byte("RIFF\x00\x00\x00\x00WEBP"), encoded(t, "png", 1, 1)...), "", ErrFormat},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, format, err := Validate(test.data)
+			if err != test.wantErr || format != test.wantFormat {
+				t.Errorf("Validate = %q, %v, want %q, %v", format, err, test.wantFormat, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+// Package jobs runs work in the background through a queue kept in the
+// database, so jobs survive restarts and are shared by every app process
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultMaxAttempts = 5
+	// How long a worker has a job before it is given to another worker,
+	// which is also as long as the job may run
+	lease = 5 * time.Minute
+	// How often idle workers look for jobs due
+	pollInterval = time.Second
+	// How often recurring jobs are checked
+	scheduleInterval = 30 * time.Second
+	// The wait before a retry doubles with every failed attempt
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+)
+
+// Kind describes how the jobs of one kind are run
+type Kind struct {
+	// Runs the job with its JSON payload, an error has it retried unless
+	// made with Permanent
+	Run func(ctx context.Context, payload []byte) error
+	// Jobs of the kind run at once in each process, one when unset
+	Concurrency int
+	// Tries before a job is moved to the dead jobs, five when unset
+	MaxAttempts int
+	// Called with the payload once the job has failed for good, to clean
+	// up what it would have
+	Buried func(ctx context.Context, payload []byte)
+}
+
+// Fails the job for good rather than retrying it, for errors that would
+// happen again on every attempt
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+type kind struct {
+	Kind
+	// Wakes an idle worker when a job is enqueued by this process
+	wake chan struct{}
+}
+
+type schedule struct {
+	kind     string
+	interval time.Duration
+}
+
+// Queue enqueues jobs and runs the kinds registered with it
+type Queue struct {
+	store     database.JobStore
+	kinds     map[string]*kind
+	schedules []schedule
+}
+
+func New(store database.JobStore) *Queue {
+	return &Queue{store: store, kinds: make(map[string]*kind)}
+}
+
+// Sets how jobs of the kind are run, which must be done before Start
+func (q *Queue) Register(name string, k Kind) {
+	if k.Concurrency < 1 {
+		k.Concurrency = 1
+	}
+	if k.MaxAttempts < 1 {
+		k.MaxAttempts = defaultMaxAttempts
+	}
+	q.kinds[name] = &kind{Kind: k, wake: make(chan struct{}, 1)}
+}
+
+// Enqueues a job of the kind every interval, only once across all the
+// processes sharing the database. Must be called before Start.
+func (q *Queue) Every(kind string, interval time.Duration) {
+	q.schedules = append(q.schedules, schedule{kind: kind, interval: interval})
+}
+
+// Starts the workers of the registered kinds and the recurring jobs
+func (q *Queue) Start() {
+	for name, k := range q.kinds {
+		for i := 0; i < k.Concurrency; i++ {
+			go q.work(name, k)
+		}
+	}
+	for _, s := range q.schedules {
+		go q.schedule(s)
+	}
+}
+
+// Enqueues a job to run as soon as a worker is free
+func (q *Queue) Enqueue(kind string, payload any) bool {
+	return q.EnqueueAt(kind, payload, time.Now())
+}
+
+// Enqueues a job to run at runAt
+func (q *Queue) EnqueueAt(kind string, payload any, runAt time.Time) bool {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	job := models.Job{
+		Id:          uuid.NewString(),
+		Kind:        kind,
+		Payload:     data,
+		MaxAttempts: defaultMaxAttempts,
+		RunAt:       runAt,
+		CreatedAt:   time.Now(),
+	}
+	k := q.kinds[kind]
+	if k != nil {
+		job.MaxAttempts = k.MaxAttempts
+	}
+	if !q.store.CreateJob(&job) {
+		return false
+	}
+	if k != nil && !runAt.After(time.Now()) {
+		select {
+		case k.wake <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+func (q *Queue) work(name string, k *kind) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		job := q.store.ClaimJob(name, time.Now().Add(lease))
+		if job == nil {
+			select {
+			case <-k.wake:
+			case <-ticker.C:
+			}
+			continue
+		}
+		q.run(k, job)
+	}
+}
+
+// Runs a claimed job, then removes it or schedules its retry
+func (q *Queue) run(k *kind, job *models.Job) {
+	// Attempts are counted when claimed, so a job that keeps stopping its
+	// worker, such as by crashing the process, still runs out of them
+	if job.Attempts > job.MaxAttempts {
+		q.bury(k, job, "ran out of attempts without finishing")
+		return
+	}
+	err := call(k, job)
+	var permanent *permanentError
+	switch {
+	case err == nil:
+		q.store.DeleteJob(job.Id)
+	case job.Attempts >= job.MaxAttempts || errors.As(err, &permanent):
+		log.Printf("job %s (%s) failed for good: %v", job.Id, job.Kind, err)
+		q.bury(k, job, err.Error())
+	default:
+		q.store.RetryJob(job.Id, time.Now().Add(backoff(job.Attempts)), err.Error())
+	}
+}
+
+// Moves the job to the dead jobs, then lets its kind clean up after it
+func (q *Queue) bury(k *kind, job *models.Job, reason string) {
+	q.store.BuryJob(job.Id, reason, time.Now())
+	if k.Buried == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("job %s (%s) panicked cleaning up: %v", job.Id, job.Kind, r)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), lease)
+	defer cancel()
+	k.Buried(ctx, job.Payload)
+}
+
+func call(k *kind, job *models.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), lease)
+	defer cancel()
+	return k.Run(ctx, job.Payload)
+}
+
+// Wait before retrying a job that failed the given number of times
+func backoff(attempts int) time.Duration {
+	duration := baseBackoff
+	for i := 1; i < attempts && duration < maxBackoff; i++ {
+		duration *= 2
+	}
+	if duration > maxBackoff {
+		duration = maxBackoff
+	}
+	return duration
+}
+
+func (q *Queue) schedule(s schedule) {
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+	for {
+		now := time.Now()
+		if q.store.ClaimSchedule(s.kind, now, now.Add(s.interval)) {
+			q.Enqueue(s.kind, nil)
+		}
+		<-ticker.C
+	}
+}
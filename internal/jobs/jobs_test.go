@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		maxAttempts int
+		wantQueued  bool
+		wantDead    bool
+	}{
+		{"success is deleted", nil, 5, false, false},
+		{"failure is retried", errors.New("temporary"), 5, true, false},
+		{"last failure is buried", errors.New("temporary"), 1, false, true},
+		{"permanent failure is buried at once", Permanent(errors.New("bad input")), 5, false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := database.NewMemory()
+			q := New(store)
+			buried := false
+			q.Register("test", Kind{
+				Run:         func(ctx context.Context, payload []byte) error { return test.err },
+				Buried:      func(ctx context.Context, payload []byte) { buried = true },
+				MaxAttempts: test.maxAttempts,
+			})
+			if !q.Enqueue("test", nil) {
+				t.Fatal("unable to enqueue")
+			}
+			job := store.ClaimJob("test", time.Now().Add(lease))
+			if job == nil {
+				t.Fatal("no job to claim")
+			}
+			q.run(q.kinds["test"], job)
+			if queued := len(store.ReadJobs(10)) == 1; queued != test.wantQueued {
+				t.Errorf("queued = %v, want %v", queued, test.wantQueued)
+			}
+			if dead := len(store.ReadDeadJobs(10)) == 1; dead != test.wantDead {
+				t.Errorf("dead = %v, want %v", dead, test.wantDead)
+			}
+			if buried != test.wantDead {
+				t.Errorf("Buried called = %v, want %v", buried, test.wantDead)
+			}
+		})
+	}
+}
+
+func TestRunOutOfAttempts(t *testing.T) {
+	store := database.NewMemory()
+	q := New(store)
+	ran, buried := false, false
+	q.Register("test", Kind{
+		Run:         func(ctx context.Context, payload []byte) error { ran = true; return nil },
+		Buried:      func(ctx context.Context, payload []byte) { buried = true },
+		MaxAttempts: 1,
+	})
+	q.Enqueue("test", nil)
+	job := store.ClaimJob("test", time.Now().Add(lease))
+	// As if an earlier worker claimed it and never finished
+	job.Attempts = 2
+	q.run(q.kinds["test"], job)
+	if ran || !buried || len(store.ReadDeadJobs(10)) != 1 {
+		t.Errorf("ran = %v, buried = %v, want the job buried without running", ran, buried)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, baseBackoff},
+		{2, 2 * baseBackoff},
+		{4, 8 * baseBackoff},
+		{100, maxBackoff},
+	}
+	for _, test := range tests {
+		if got := backoff(test.attempts); got != test.want {
+			t.Errorf("backoff(%d) = %s, want %s", test.attempts, got, test.want)
+		}
+	}
+}
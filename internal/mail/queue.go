@@ -0,0 +1,47 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/Bhar8at/bhar8at.github.io/internal/jobs"
+)
+
+const kindMail = "mail"
+
+type email struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Queued sends emails through the job queue, so they are retried while the
+// mail server is down and requests don't wait on it. The body is stored with
+// the job, so emails holding a token are queued as jobs that issue it instead.
+type Queued struct {
+	queue *jobs.Queue
+}
+
+// Queues emails to be sent by mailer
+func Queue(queue *jobs.Queue, mailer Mailer) *Queued {
+	queue.Register(kindMail, jobs.Kind{
+		Run: func(ctx context.Context, payload []byte) error {
+			var message email
+			if err := json.Unmarshal(payload, &message); err != nil {
+				return err
+			}
+			return mailer.Send(message.To, message.Subject, message.Body)
+		},
+		Concurrency: 2,
+		MaxAttempts: 8,
+	})
+	return &Queued{queue: queue}
+}
+
+func (q *Queued) Send(to string, subject string, body string) error {
+	if !q.queue.Enqueue(kindMail, email{To: to, Subject: subject, Body: body}) {
+		return errors.New("unable to queue email")
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLocalPutConcurrent(t *testing.T) {
+	local := &Local{Dir: t.TempDir()}
+	data := bytes.Repeat([]byte("image"), 1<<16)
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- local.Put(context.Background(), "media/abc.png", data, "image/png")
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := local.Get(context.Background(), "media/abc.png")
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("Get = %d bytes, %v, want the %d bytes put", len(got), err, len(data))
+	}
+	entries, err := os.ReadDir(local.path("media"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("media holds %d files, want only abc.png without temp files", len(entries))
+	}
+}
+
+func TestLocalDelete(t *testing.T) {
+	local := &Local{Dir: t.TempDir()}
+	ctx := context.Background()
+	if err := local.Put(ctx, "media/abc.png", []byte("image"), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := local.Delete(ctx, "media/abc.png"); err != nil {
+			t.Errorf("Delete %d = %v", i+1, err)
+		}
+	}
+	if _, err := local.Get(ctx, "media/abc.png"); err == nil {
+		t.Error("Get found a deleted key")
+	}
+}
+
+func TestLocalVerify(t *testing.T) {
+	local := &Local{BaseURL: "http://localhost/uploads", Secret: []byte("secret")}
+	unix := func(d time.Duration) string { return strconv.FormatInt(time.Now().Add(d).Unix(), 10) }
+	valid, expired := unix(time.Minute), unix(-time.Minute)
+	tests := []struct {
+		name      string
+		key       string
+		expires   string
+		signature string
+		want      bool
+	}{
+		{"valid", "private/a", valid, local.sign("private/a", valid), true},
+		{"expired", "private/a", expired, local.sign("private/a", expired), false},
+		{"other key", "private/b", valid, local.sign("private/a", valid), false},
+		{"extended", "private/a", unix(time.Hour), local.sign("private/a", valid), false},
+		{"not a time", "private/a", "soon", local.sign("private/a", "soon"), false},
+	}
+	for _, test := range tests {
+		if got := local.verify(test.key, test.expires, test.signature); got != test.want {
+			t.Errorf("%s: verify = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestLocalWalk(t *testing.T) {
+	ctx := context.Background()
+	local := &Local{Dir: t.TempDir() + "/uploads"}
+	if err := local.Walk(ctx, func(key string, modified time.Time) error {
+		t.Errorf("visited %s in a missing dir", key)
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk of a missing dir = %v", err)
+	}
+	for _, key := range []string{"media/a.png", "private/uploads/b", "c.png"} {
+		if err := local.Put(ctx, key, []byte("image"), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A write in progress
+	if err := os.WriteFile(local.path("media/.d.png.123.tmp"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	visited := map[string]bool{}
+	if err := local.Walk(ctx, func(key string, modified time.Time) error {
+		visited[key] = true
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(visited) != 3 || !visited["media/a.png"] || !visited["private/uploads/b"] || !visited["c.png"] {
+		t.Errorf("visited %v, want the three files put", visited)
+	}
+}
@@ -0,0 +1,120 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Keys under this prefix are never served publicly, only through signed URLs
+const privatePrefix = "private/"
+
+// Store keeps uploaded images and avatars under slash separated keys
+type Store interface {
+	// Saves the data under the key, replacing anything already there
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Reads what was saved under the key
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Removes the key, succeeding when it's already gone
+	Delete(ctx context.Context, key string) error
+	// Public URL of the key
+	URL(key string) string
+	// URL of the key which stops working after the expiry, for private media
+	SignedURL(key string, expiry time.Duration) (string, error)
+	// Calls visit with every key stored and when it was last written,
+	// stopping at the first error
+	Walk(ctx context.Context, visit func(key string, modified time.Time) error) error
+}
+
+// Key for media only reachable through signed URLs
+func PrivateKey(key string) string {
+	return privatePrefix + key
+}
+
+func isPrivate(key string) bool {
+	return strings.HasPrefix(key, privatePrefix)
+}
+
+// Builds the store selected through the MEDIA_STORE variable, "s3" keeps
+// media in S3_BUCKET while anything else writes it to MEDIA_DIR. Both are
+// linked from MEDIA_BASE_URL when set, such as a CDN in front of them.
+func FromEnv(baseURL string, secret []byte) (Store, error) {
+	switch os.Getenv("MEDIA_STORE") {
+	case "s3":
+		region := os.Getenv("S3_REGION")
+		if region == "" {
+			region = "us-east-1"
+		}
+		endpoint := os.Getenv("S3_ENDPOINT")
+		if endpoint == "" {
+			endpoint = "https://s3." + region + ".amazonaws.com"
+		}
+		bucket := os.Getenv("S3_BUCKET")
+		if bucket == "" {
+			return nil, errors.New("S3_BUCKET is required for the s3 media store")
+		}
+		return &S3{
+			Endpoint:  strings.TrimSuffix(endpoint, "/"),
+			Region:    region,
+			Bucket:    bucket,
+			AccessKey: os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			BaseURL:   strings.TrimSuffix(os.Getenv("MEDIA_BASE_URL"), "/"),
+		}, nil
+	default:
+		dir := os.Getenv("MEDIA_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		mediaURL := os.Getenv("MEDIA_BASE_URL")
+		if mediaURL == "" {
+			mediaURL = baseURL + "/uploads"
+		}
+		return &Local{Dir: dir, BaseURL: strings.TrimSuffix(mediaURL, "/"), Secret: secret}, nil
+	}
+}
+
+// Extensions of the images served publicly. Files uploaded before the
+// extension came from the sniffed format may be anything, so others are
+// never served.
+var publicExtensions = map[string]bool{".jpeg": true, ".jpg": true, ".png": true, ".gif": true, ".webp": true}
+
+// Serves /uploads/*filepath, reading local media from disk after checking
+// the signature of private keys, and redirecting to the bucket otherwise
+// so links from before a move to S3 keep working
+func Serve(store Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimPrefix(path.Clean("/"+c.Param("filepath")), "/")
+		if key == "" || !isPrivate(key) && !publicExtensions[strings.ToLower(path.Ext(key))] {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		// Nothing served from here may run as a page of the site, whatever
+		// the file turns out to hold
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Content-Security-Policy", "default-src 'none'; sandbox")
+		if local, ok := store.(*Local); ok {
+			if isPrivate(key) {
+				if !local.verify(key, c.Query("expires"), c.Query("signature")) {
+					c.Status(http.StatusNotFound)
+					return
+				}
+				c.Header("Content-Type", "application/octet-stream")
+				c.Header("Content-Disposition", "attachment")
+			}
+			c.File(local.path(key))
+			return
+		}
+		if isPrivate(key) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Redirect(http.StatusMovedPermanently, store.URL(key))
+	}
+}
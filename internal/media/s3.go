@@ -0,0 +1,260 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Longest expiry S3 accepts for a signed URL
+const maxExpiry = 7 * 24 * time.Hour
+
+// S3 keeps media in a bucket of Amazon S3 or a compatible server such as
+// MinIO, addressed by path as in Endpoint/Bucket/key. Public media is
+// linked from BaseURL when set, leaving the bucket policy or CDN to allow
+// reads of everything outside "private/".
+type S3 struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	BaseURL   string
+	Client    *http.Client
+}
+
+func (s *S3) objectURL(key string) *url.URL {
+	u, _ := url.Parse(s.Endpoint)
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.Bucket + "/" + key
+	return u
+}
+
+func (s *S3) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key).String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", contentType)
+	sum := sha256.Sum256(data)
+	s.sign(request, hex.EncodeToString(sum[:]), time.Now())
+	response, err := s.do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("s3 put of %s failed with %s: %s", key, response.Status, body)
+	}
+	return nil
+}
+
+func (s *S3) Get(ctx context.Context, key string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key).String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(nil)
+	s.sign(request, hex.EncodeToString(sum[:]), time.Now())
+	response, err := s.do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return nil, fmt.Errorf("s3 get of %s failed with %s: %s", key, response.Status, body)
+	}
+	return io.ReadAll(response.Body)
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key).String(), nil)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(nil)
+	s.sign(request, hex.EncodeToString(sum[:]), time.Now())
+	response, err := s.do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	// S3 answers 204 whether or not the key was there, other servers may not
+	if response.StatusCode/100 != 2 && response.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("s3 delete of %s failed with %s: %s", key, response.Status, body)
+	}
+	return nil
+}
+
+// Page of a ListObjectsV2 response
+type listResult struct {
+	Contents []struct {
+		Key          string
+		LastModified time.Time
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+func (s *S3) Walk(ctx context.Context, visit func(key string, modified time.Time) error) error {
+	token := ""
+	for {
+		u := s.objectURL("")
+		query := url.Values{"list-type": {"2"}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		u.RawQuery = canonicalQuery(query)
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(nil)
+		s.sign(request, hex.EncodeToString(sum[:]), time.Now())
+		response, err := s.do(request)
+		if err != nil {
+			return err
+		}
+		var result listResult
+		if response.StatusCode/100 != 2 {
+			body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+			err = fmt.Errorf("s3 list failed with %s: %s", response.Status, body)
+		} else {
+			err = xml.NewDecoder(response.Body).Decode(&result)
+		}
+		response.Body.Close()
+		if err != nil {
+			return err
+		}
+		for _, object := range result.Contents {
+			if err := visit(object.Key, object.LastModified); err != nil {
+				return err
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (s *S3) do(request *http.Request) (*http.Response, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(request)
+}
+
+func (s *S3) URL(key string) string {
+	if s.BaseURL != "" {
+		return s.BaseURL + "/" + (&url.URL{Path: key}).EscapedPath()
+	}
+	return s.objectURL(key).String()
+}
+
+// Presigned GET of the object, served by the bucket itself since a CDN
+// would not pass the signature on
+func (s *S3) SignedURL(key string, expiry time.Duration) (string, error) {
+	if expiry <= 0 || expiry > maxExpiry {
+		return "", fmt.Errorf("signed url expiry must be within %s", maxExpiry)
+	}
+	u := s.objectURL(key)
+	now := time.Now().UTC()
+	query := url.Values{
+		"X-Amz-Algorithm":     {"AWS4-HMAC-SHA256"},
+		"X-Amz-Credential":    {s.AccessKey + "/" + s.scope(now)},
+		"X-Amz-Date":          {now.Format("20060102T150405Z")},
+		"X-Amz-Expires":       {strconv.Itoa(int(expiry / time.Second))},
+		"X-Amz-SignedHeaders": {"host"},
+	}
+	canonical := strings.Join([]string{
+		http.MethodGet,
+		escape(u.Path, false),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	u.RawQuery = canonicalQuery(query) + "&X-Amz-Signature=" + s.signature(now, canonical)
+	return u.String(), nil
+}
+
+// Adds Signature Version 4 headers for the request with the payload hash
+func (s *S3) sign(request *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	request.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		request.Method,
+		escape(request.URL.Path, false),
+		canonicalQuery(request.URL.Query()),
+		"host:" + request.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + now.Format("20060102T150405Z") + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	request.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.AccessKey, s.scope(now), signedHeaders, s.signature(now, canonical),
+	))
+}
+
+func (s *S3) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.Region + "/s3/aws4_request"
+}
+
+// Signs the canonical request with a key derived for the day and region
+func (s *S3) signature(now time.Time, canonical string) string {
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + s.scope(now) + "\n" + hex.EncodeToString(hash[:])
+	key := []byte("AWS4" + s.SecretKey)
+	for _, part := range []string{now.Format("20060102"), s.Region, "s3", "aws4_request", toSign} {
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(part))
+		key = mac.Sum(nil)
+	}
+	return hex.EncodeToString(key)
+}
+
+func canonicalQuery(query url.Values) string {
+	var pairs []string
+	for name, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, escape(name, true)+"="+escape(value, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// Percent encodes everything but unreserved characters, as AWS requires,
+// keeping slashes when they separate a path
+func escape(value string, encodeSlash bool) string {
+	var escaped strings.Builder
+	for _, b := range []byte(value) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '.', b == '_', b == '~', b == '/' && !encodeSlash:
+			escaped.WriteByte(b)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
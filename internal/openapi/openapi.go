@@ -0,0 +1,158 @@
+// Package openapi builds an OpenAPI 3 document describing the app's routes
+// and checks it against the routes registered with gin
+package openapi
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	In          string `json:"in,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type Operation struct {
+	Summary     string                `json:"summary"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	// Access token scope the route needs, OpenAPI 3.0 has no place for
+	// scopes of bearer tokens
+	Scope string `json:"x-required-scope,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+func New(title string, version string, description string) *Document {
+	return &Document{
+		OpenAPI: "3.0.3",
+		Info:    Info{Title: title, Version: version, Description: description},
+		Paths:   make(map[string]map[string]*Operation),
+		Components: Components{
+			Schemas:         make(map[string]*Schema),
+			SecuritySchemes: make(map[string]SecurityScheme),
+		},
+	}
+}
+
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// Converts a gin path like /post/:id to the OpenAPI form /post/{id}
+func Path(path string) string {
+	return ginParam.ReplaceAllString(path, "{$1}")
+}
+
+// Adds the operation for a route given as registered with gin. Path
+// parameters are described automatically.
+func (d *Document) Add(method string, path string, op *Operation) {
+	for _, match := range ginParam.FindAllStringSubmatch(path, -1) {
+		op.Parameters = append([]Parameter{{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   String(),
+		}}, op.Parameters...)
+	}
+	path = Path(path)
+	if d.Paths[path] == nil {
+		d.Paths[path] = make(map[string]*Operation)
+	}
+	d.Paths[path][strings.ToLower(method)] = op
+}
+
+// Compares the document with the routes registered with gin, returning
+// the routes it doesn't describe and the operations that aren't routes
+func (d *Document) Check(routes gin.RoutesInfo) (missing []string, stale []string) {
+	registered := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		path := Path(route.Path)
+		key := fmt.Sprintf("%s %s", route.Method, path)
+		registered[key] = true
+		if d.Paths[path][strings.ToLower(route.Method)] == nil {
+			missing = append(missing, key)
+		}
+	}
+	for path, operations := range d.Paths {
+		for method := range operations {
+			key := fmt.Sprintf("%s %s", strings.ToUpper(method), path)
+			if !registered[key] {
+				stale = append(stale, key)
+			}
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(stale)
+	return missing, stale
+}
+
+// Query parameter, strings unless a schema is given
+func Query(name string, description string, required bool) Parameter {
+	return Parameter{Name: name, In: "query", Required: required, Description: description, Schema: String()}
+}
+
+// Request body of the given media type
+func Body(mediaType string, schema *Schema) *RequestBody {
+	return &RequestBody{Required: true, Content: map[string]MediaType{mediaType: {Schema: schema}}}
+}
+
+// Response with a body of the given media type, or none when it's empty
+func Respond(description string, mediaType string, schema *Schema) Response {
+	if mediaType == "" {
+		return Response{Description: description}
+	}
+	return Response{Description: description, Content: map[string]MediaType{mediaType: {Schema: schema}}}
+}
@@ -0,0 +1,153 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+func String() *Schema {
+	return &Schema{Type: "string"}
+}
+
+func Boolean() *Schema {
+	return &Schema{Type: "boolean"}
+}
+
+func Integer() *Schema {
+	return &Schema{Type: "integer"}
+}
+
+func Binary() *Schema {
+	return &Schema{Type: "string", Format: "binary"}
+}
+
+func Array(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+// Object with the given properties, the named ones being required
+func Object(properties map[string]*Schema, required ...string) *Schema {
+	return &Schema{Type: "object", Properties: properties, Required: required}
+}
+
+// Returns a reference to the schema of v's type, adding it to the
+// components from its json tags the first time
+func (d *Document) JSON(v any) *Schema {
+	return d.reflect(reflect.TypeOf(v), "json")
+}
+
+// Schema of a form bound by gin from v's form tags, inlined since forms
+// aren't shared between routes
+func (d *Document) Form(v any) *Schema {
+	return d.object(reflect.TypeOf(v), "form")
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (d *Document) reflect(t reflect.Type, tag string) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Pointer:
+		schema := d.reflect(t.Elem(), tag)
+		if tag == "form" {
+			// Forms have no null, only missing fields
+			return schema
+		}
+		if schema.Ref != "" {
+			// $ref can't have siblings in OpenAPI 3.0
+			return schema
+		}
+		nullable := *schema
+		nullable.Nullable = true
+		return &nullable
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return String()
+	case reflect.Bool:
+		return Boolean()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Integer()
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return Array(d.reflect(t.Elem(), tag))
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.reflect(t.Elem(), tag)}
+	case reflect.Struct:
+		name := schemaName(t)
+		if _, ok := d.Components.Schemas[name]; !ok {
+			// Reserved first so recursive types end
+			d.Components.Schemas[name] = &Schema{}
+			*d.Components.Schemas[name] = *d.object(t, tag)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+// Describes a struct's fields the way encoding/json or gin's form binding
+// sees them, with embedded structs flattened
+func (d *Document) object(t reflect.Type, tag string) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	schema := Object(make(map[string]*Schema))
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "-" || (tag == "form" && name == "") {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := d.object(field.Type, tag)
+			for key, value := range embedded.Properties {
+				schema.Properties[key] = value
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = d.reflect(field.Type, tag)
+		omitted := strings.Contains(options, "omitempty")
+		if field.Tag.Get("binding") == "required" || (tag == "json" && !omitted) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// Names a type for the components, turning generic instances like
+// Page[api.Post] into PagePost
+func schemaName(t reflect.Type) string {
+	name := t.Name()
+	base, args, generic := strings.Cut(name, "[")
+	if !generic {
+		return name
+	}
+	for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+		base += arg[strings.LastIndex(arg, ".")+1:]
+	}
+	return base
+}
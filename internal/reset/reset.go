@@ -0,0 +1,112 @@
+package reset
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/internal/jobs"
+	"github.com/Bhar8at/bhar8at.github.io/internal/mail"
+	"github.com/Bhar8at/bhar8at.github.io/internal/tokens"
+)
+
+const (
+	purpose = "reset"
+	ttl     = time.Hour
+	// Job that mails the link, queued with only the user id so tokens are
+	// never stored in the queue
+	kindSend = "reset"
+)
+
+var (
+	errUpdatePassword = errors.New("unable to update password")
+	errQueue          = errors.New("unable to queue reset email")
+)
+
+type sendJob struct {
+	UserId string `json:"userId"`
+}
+
+// Store is the storage needed to reset passwords
+type Store interface {
+	database.UserStore
+	database.SessionStore
+}
+
+// Resetter emails users a one-time link to choose a new password
+type Resetter struct {
+	store   Store
+	tokens  *tokens.Issuer
+	mailer  mail.Mailer
+	queue   *jobs.Queue
+	baseURL string
+}
+
+func New(store Store, issuer *tokens.Issuer, mailer mail.Mailer, queue *jobs.Queue, baseURL string) *Resetter {
+	r := &Resetter{store: store, tokens: issuer, mailer: mailer, queue: queue, baseURL: baseURL}
+	queue.Register(kindSend, jobs.Kind{Run: r.deliver, Concurrency: 2, MaxAttempts: 8})
+	return r
+}
+
+// Queues a reset link to the account with the given email, nothing is
+// sent when there is no such account
+func (r *Resetter) Send(email string) error {
+	user := r.store.ReadUserByEmail(email)
+	if user == nil {
+		return nil
+	}
+	if !r.queue.Enqueue(kindSend, sendJob{UserId: user.Id}) {
+		return errQueue
+	}
+	return nil
+}
+
+// Issues the token and mails the link, run by the queue
+func (r *Resetter) deliver(ctx context.Context, payload []byte) error {
+	var job sendJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return jobs.Permanent(err)
+	}
+	user := r.store.ReadUserById(job.UserId)
+	// Nothing to send when the account went or lost its email meanwhile
+	if user == nil || user.Email == nil {
+		return nil
+	}
+	token, err := r.tokens.Issue(user.Id, purpose, ttl)
+	if err != nil {
+		return err
+	}
+	link := r.baseURL + "/auth/reset?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf(
+		"Hi @%s,\n\nA password reset was requested for your account. Choose a new password here:\n\n%s\n\n"+
+			"The link expires in 1 hour and can only be used once. If you didn't request this, ignore this email.\n",
+		user.Username, link,
+	)
+	return r.mailer.Send(*user.Email, "Reset your password", body)
+}
+
+// Sets a new password for the user the token was issued to and logs
+// them out of every existing session
+func (r *Resetter) Reset(token string, password string) error {
+	userId, err := r.tokens.Consume(token, purpose)
+	if err != nil {
+		return err
+	}
+	user := r.store.ReadUserById(userId)
+	if user == nil {
+		return tokens.ErrInvalidToken
+	}
+	user.Password = password
+	if err := user.HashPassword(); err != nil {
+		return err
+	}
+	if !r.store.UpdateUser(user.Id, map[string]any{"password": user.Password}) {
+		return errUpdatePassword
+	}
+	r.store.DeleteSessions(user.Id)
+	return nil
+}
@@ -0,0 +1,110 @@
+package reset
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/internal/jobs"
+	"github.com/Bhar8at/bhar8at.github.io/internal/tokens"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+type message struct {
+	to, subject, body string
+}
+
+// Mailer keeping what was sent
+type recorder struct {
+	sent []message
+}
+
+func (r *recorder) Send(to string, subject string, body string) error {
+	r.sent = append(r.sent, message{to, subject, body})
+	return nil
+}
+
+var link = regexp.MustCompile(`/auth/reset\?token=(\S+)`)
+
+// Resetter with a user alice, logged in once, to reset the password of
+func newResetter(t *testing.T) (*Resetter, *database.Memory, *recorder) {
+	store := database.NewMemory()
+	email := "alice@example.com"
+	user := models.User{Id: "alice", Username: "alice", Email: &email, Password: "old password"}
+	if err := user.HashPassword(); err != nil {
+		t.Fatal(err)
+	}
+	store.CreateUser(&user)
+	store.CreateSession(&models.Session{Id: "session", UserId: "alice", ExpiresAt: time.Now().Add(time.Hour)})
+	mailer := &recorder{}
+	r := New(store, tokens.NewIssuer(store, []byte("secret")), mailer, jobs.New(store), "http://localhost")
+	return r, store, mailer
+}
+
+// Runs the queued email jobs as a worker would
+func deliverAll(t *testing.T, r *Resetter, store *database.Memory) {
+	for job := store.ClaimJob(kindSend, time.Now().Add(time.Minute)); job != nil; job = store.ClaimJob(kindSend, time.Now().Add(time.Minute)) {
+		if strings.Contains(string(job.Payload), "token") {
+			t.Errorf("job payload %s holds a token", job.Payload)
+		}
+		if err := r.deliver(context.Background(), job.Payload); err != nil {
+			t.Fatal(err)
+		}
+		store.DeleteJob(job.Id)
+	}
+}
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		wantSent int
+	}{
+		{"known email", "alice@example.com", 1},
+		{"unknown email", "bob@example.com", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, store, mailer := newResetter(t)
+			if err := r.Send(test.email); err != nil {
+				t.Fatal(err)
+			}
+			deliverAll(t, r, store)
+			if len(mailer.sent) != test.wantSent {
+				t.Fatalf("sent %d emails, want %d", len(mailer.sent), test.wantSent)
+			}
+			if test.wantSent > 0 && (mailer.sent[0].to != test.email || !link.MatchString(mailer.sent[0].body)) {
+				t.Errorf("sent %+v, want a link to %s", mailer.sent[0], test.email)
+			}
+		})
+	}
+}
+
+func TestReset(t *testing.T) {
+	r, store, mailer := newResetter(t)
+	if err := r.Send("alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	deliverAll(t, r, store)
+	token := link.FindStringSubmatch(mailer.sent[0].body)[1]
+
+	if err := r.Reset("forged", "new password"); err != tokens.ErrInvalidToken {
+		t.Errorf("Reset with a forged token = %v, want ErrInvalidToken", err)
+	}
+	if err := r.Reset(token, "new password"); err != nil {
+		t.Fatal(err)
+	}
+	user := store.ReadUserById("alice")
+	if !user.CheckPassword("new password") || user.CheckPassword("old password") {
+		t.Error("password not changed")
+	}
+	if store.ReadSession("session") != nil {
+		t.Error("existing session kept after reset")
+	}
+	if err := r.Reset(token, "another password"); err != tokens.ErrInvalidToken {
+		t.Errorf("second Reset = %v, want ErrInvalidToken", err)
+	}
+}
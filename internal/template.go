@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"html/template"
 	"math/rand"
 	"strings"
 	"time"
@@ -27,4 +28,49 @@ func FormatAsTitle(title string) string {
 
 func FormatAsDate(createdAt time.Time) string {
 	return createdAt.Format(time.RFC822)
-}
\ No newline at end of file
+}
+// Describes the browser and operating system of a user agent, such as
+// "Firefox on Linux"
+func FormatAsDevice(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+	browser := "Unknown browser"
+	for _, known := range []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	} {
+		if strings.Contains(userAgent, known.token) {
+			browser = known.name
+			break
+		}
+	}
+	os := ""
+	for _, known := range []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"CrOS", "ChromeOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	} {
+		if strings.Contains(userAgent, known.token) {
+			os = known.name
+			break
+		}
+	}
+	if os == "" {
+		return browser
+	}
+	return browser + " on " + os
+}
+
+// Hidden form field carrying the CSRF token
+func CSRFField(token string) template.HTML {
+	return template.HTML(`<input type="hidden" name="csrf_token" value="` + template.HTMLEscapeString(token) + `" />`)
+}
@@ -0,0 +1,144 @@
+package throttle
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/internal/mail"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+const (
+	// Failures older than this are forgotten
+	window = 15 * time.Minute
+	// Failures before the account or IP is locked out
+	accountMaxFailures = 5
+	ipMaxFailures      = 20
+	// The lockout doubles with every failure past the maximum
+	baseLockout = time.Minute
+	maxLockout  = time.Hour
+)
+
+// Store is the storage needed to throttle logins
+type Store interface {
+	database.UserStore
+	database.LoginAttemptStore
+}
+
+// Throttle slows down password guessing by locking accounts and IP
+// addresses out for longer after every failed login past a few
+type Throttle struct {
+	store   Store
+	mailer  mail.Mailer
+	baseURL string
+}
+
+func New(store Store, mailer mail.Mailer, baseURL string) *Throttle {
+	return &Throttle{store: store, mailer: mailer, baseURL: baseURL}
+}
+
+// Returns how long until a login to the account from the IP may be tried,
+// zero when it can be tried now
+func (t *Throttle) Wait(username string, ip string) time.Duration {
+	var wait time.Duration
+	for _, lockout := range []*models.Lockout{
+		t.store.ReadLockout(models.LockoutAccount, username),
+		t.store.ReadLockout(models.LockoutIp, ip),
+	} {
+		if lockout != nil && time.Until(lockout.LockedUntil) > wait {
+			wait = time.Until(lockout.LockedUntil)
+		}
+	}
+	return wait
+}
+
+// Length of the lockout after the given number of failures
+func lockoutFor(failures int, limit int) time.Duration {
+	if failures < limit {
+		return 0
+	}
+	duration := baseLockout
+	for i := limit; i < failures && duration < maxLockout; i++ {
+		duration *= 2
+	}
+	if duration > maxLockout {
+		duration = maxLockout
+	}
+	return duration
+}
+
+// Describes the wait in words, rounded up to the minute
+func Describe(wait time.Duration) string {
+	minutes := int((wait + time.Minute - 1) / time.Minute)
+	if minutes <= 1 {
+		return "1 minute"
+	}
+	return fmt.Sprintf("%d minutes", minutes)
+}
+
+// Records a wrong password or unknown username, locking the account or IP
+// out once it has failed too often
+func (t *Throttle) Failed(username string, ip string, userAgent string) {
+	now := time.Now()
+	t.store.CreateLoginAttempt(&models.LoginAttempt{
+		Username:  username,
+		Ip:        ip,
+		UserAgent: userAgent,
+		CreatedAt: now,
+	})
+	failures := t.store.ReadLoginFailuresCount(username, now.Add(-window))
+	if duration := lockoutFor(failures, accountMaxFailures); duration > 0 {
+		t.store.CreateLockout(&models.Lockout{
+			Scope:       models.LockoutAccount,
+			Key:         username,
+			Failures:    failures,
+			LockedUntil: now.Add(duration),
+			CreatedAt:   now,
+		})
+		// Only the first lockout is emailed so guesses can't flood the inbox
+		if failures == accountMaxFailures {
+			t.notify(username, ip, duration)
+		}
+	}
+	failures = t.store.ReadIpLoginFailuresCount(ip, now.Add(-window))
+	if duration := lockoutFor(failures, ipMaxFailures); duration > 0 {
+		t.store.CreateLockout(&models.Lockout{
+			Scope:       models.LockoutIp,
+			Key:         ip,
+			Failures:    failures,
+			LockedUntil: now.Add(duration),
+			CreatedAt:   now,
+		})
+	}
+}
+
+// Records a successful login, which clears the account's failures
+func (t *Throttle) Succeeded(username string, ip string, userAgent string) {
+	t.store.CreateLoginAttempt(&models.LoginAttempt{
+		Username:  username,
+		Ip:        ip,
+		UserAgent: userAgent,
+		Success:   true,
+		CreatedAt: time.Now(),
+	})
+	t.store.DeleteLockout(models.LockoutAccount, username)
+}
+
+// Tells the owner of the account it was locked
+func (t *Throttle) notify(username string, ip string, duration time.Duration) {
+	user := t.store.ReadUserByName(username)
+	if user == nil || user.Email == nil {
+		return
+	}
+	body := fmt.Sprintf(
+		"Hi @%s,\n\nThere were %d failed attempts to login to your account, the latest from %s, "+
+			"so logging in has been paused for %s.\n\n"+
+			"If this wasn't you, someone may be guessing your password. You can choose a new one here:\n\n%s\n",
+		user.Username, accountMaxFailures, ip, Describe(duration), t.baseURL+"/auth/forgot",
+	)
+	if err := t.mailer.Send(*user.Email, "Failed login attempts on your account", body); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,109 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+// Mailer recording who was sent what
+type recorder struct {
+	sent []string
+}
+
+func (r *recorder) Send(to string, subject string, body string) error {
+	r.sent = append(r.sent, to)
+	return nil
+}
+
+func TestLockoutFor(t *testing.T) {
+	tests := []struct {
+		failures int
+		limit    int
+		want     time.Duration
+	}{
+		{0, 5, 0},
+		{4, 5, 0},
+		{5, 5, time.Minute},
+		{6, 5, 2 * time.Minute},
+		{7, 5, 4 * time.Minute},
+		{10, 5, 32 * time.Minute},
+		{11, 5, time.Hour},
+		{100, 5, time.Hour},
+		{20, 20, time.Minute},
+	}
+	for _, test := range tests {
+		if got := lockoutFor(test.failures, test.limit); got != test.want {
+			t.Errorf("lockoutFor(%d, %d) = %v, want %v", test.failures, test.limit, got, test.want)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		wait time.Duration
+		want string
+	}{
+		{0, "1 minute"},
+		{time.Second, "1 minute"},
+		{time.Minute, "1 minute"},
+		{time.Minute + time.Second, "2 minutes"},
+		{time.Hour, "60 minutes"},
+	}
+	for _, test := range tests {
+		if got := Describe(test.wait); got != test.want {
+			t.Errorf("Describe(%v) = %q, want %q", test.wait, got, test.want)
+		}
+	}
+}
+
+func TestAccountLockout(t *testing.T) {
+	store := database.NewMemory()
+	email := "alice@example.com"
+	store.CreateUser(&models.User{Id: "alice", Username: "alice", Email: &email, CreatedAt: time.Now()})
+	mailer := &recorder{}
+	throttle := New(store, mailer, "http://localhost")
+
+	for i := 1; i < accountMaxFailures; i++ {
+		throttle.Failed("alice", "10.0.0.1", "test")
+		if wait := throttle.Wait("alice", "10.0.0.1"); wait != 0 {
+			t.Fatalf("locked out for %v after %d failures", wait, i)
+		}
+	}
+	throttle.Failed("alice", "10.0.0.1", "test")
+	if wait := throttle.Wait("alice", "10.0.0.2"); wait <= 0 || wait > time.Minute {
+		t.Errorf("Wait from another IP = %v, want up to a minute", wait)
+	}
+	if wait := throttle.Wait("bob", "10.0.0.2"); wait != 0 {
+		t.Errorf("other account locked out for %v", wait)
+	}
+	throttle.Failed("alice", "10.0.0.1", "test")
+	if wait := throttle.Wait("alice", "10.0.0.1"); wait <= time.Minute {
+		t.Errorf("Wait after another failure = %v, want the lockout doubled", wait)
+	}
+	if len(mailer.sent) != 1 || mailer.sent[0] != email {
+		t.Errorf("sent %v, want one email to %s", mailer.sent, email)
+	}
+
+	throttle.Succeeded("alice", "10.0.0.1", "test")
+	if wait := throttle.Wait("alice", "10.0.0.1"); wait != 0 {
+		t.Errorf("Wait after success = %v, want 0", wait)
+	}
+}
+
+func TestIpLockout(t *testing.T) {
+	store := database.NewMemory()
+	throttle := New(store, &recorder{}, "http://localhost")
+	// Spread over accounts so only the IP reaches its limit
+	for i := 0; i < ipMaxFailures; i++ {
+		throttle.Failed(string(rune('a'+i)), "10.0.0.1", "test")
+	}
+	if wait := throttle.Wait("someone", "10.0.0.1"); wait <= 0 {
+		t.Errorf("Wait from the IP = %v, want a lockout", wait)
+	}
+	if wait := throttle.Wait("someone", "10.0.0.2"); wait != 0 {
+		t.Errorf("Wait from another IP = %v, want 0", wait)
+	}
+}
@@ -0,0 +1,95 @@
+package timeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/internal/jobs"
+)
+
+const (
+	// Followers past which an account's posts are no longer pushed to every
+	// follower, and are read from the posts of followed accounts instead
+	maxFanOut = 10000
+
+	kindPosted   = "timeline.posted"
+	kindFollowed = "timeline.followed"
+)
+
+// Store is the storage needed to keep timelines up to date
+type Store interface {
+	database.FollowStore
+	database.TimelineStore
+}
+
+// Timeline keeps the precomputed home timelines up to date. Changes are
+// applied by background jobs so posting and following don't wait on
+// writing to every follower's timeline.
+type Timeline struct {
+	store Store
+	queue *jobs.Queue
+}
+
+type posted struct {
+	UserId string `json:"userId"`
+	PostId string `json:"postId"`
+}
+
+type followed struct {
+	UserId   string `json:"userId"`
+	FollowId string `json:"followId"`
+}
+
+func New(store Store, queue *jobs.Queue) *Timeline {
+	t := &Timeline{store: store, queue: queue}
+	queue.Register(kindPosted, jobs.Kind{Run: t.fanOut, Concurrency: 4})
+	queue.Register(kindFollowed, jobs.Kind{Run: t.sync})
+	return t
+}
+
+// Pushes a new post to the timelines of its author's followers, unless the
+// author has too many to push to
+func (t *Timeline) Posted(userId string, postId string) {
+	t.queue.Enqueue(kindPosted, posted{UserId: userId, PostId: postId})
+}
+
+// Backfills or prunes the user's timeline after they follow or unfollow
+// followId
+func (t *Timeline) Followed(userId string, followId string) {
+	t.queue.Enqueue(kindFollowed, followed{UserId: userId, FollowId: followId})
+}
+
+func (t *Timeline) fanOut(ctx context.Context, payload []byte) error {
+	var job posted
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+	if t.store.IsFanOutOnRead(job.UserId) {
+		return nil
+	}
+	// Accounts stay fanned out on read once they get there, as going back
+	// would mean pushing all their posts to every follower
+	if t.store.ReadFollowersCount(job.UserId) >= maxFanOut {
+		if !t.store.SetFanOutOnRead(job.UserId) {
+			return errors.New("unable to fan out on read")
+		}
+		return nil
+	}
+	if !t.store.FanOutPost(job.PostId) {
+		return errors.New("unable to fan out post")
+	}
+	return nil
+}
+
+func (t *Timeline) sync(ctx context.Context, payload []byte) error {
+	var job followed
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return err
+	}
+	if !t.store.SyncTimeline(job.UserId, job.FollowId) {
+		return errors.New("unable to sync timeline")
+	}
+	return nil
+}
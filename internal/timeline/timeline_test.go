@@ -0,0 +1,125 @@
+package timeline
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/internal/jobs"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func newTimeline(t *testing.T, usernames ...string) (*Timeline, *database.Memory) {
+	store := database.NewMemory()
+	for _, username := range usernames {
+		if !store.CreateUser(&models.User{Id: username, Username: username}) {
+			t.Fatalf("unable to create %s", username)
+		}
+	}
+	return New(store, jobs.New(store)), store
+}
+
+// Runs the queued timeline jobs as a worker would
+func runAll(t *testing.T, tl *Timeline, store *database.Memory) {
+	runs := map[string]func(context.Context, []byte) error{kindPosted: tl.fanOut, kindFollowed: tl.sync}
+	for kind, run := range runs {
+		for job := store.ClaimJob(kind, time.Now().Add(time.Minute)); job != nil; job = store.ClaimJob(kind, time.Now().Add(time.Minute)) {
+			if err := run(context.Background(), job.Payload); err != nil {
+				t.Fatal(err)
+			}
+			store.DeleteJob(job.Id)
+		}
+	}
+}
+
+func post(t *testing.T, tl *Timeline, store *database.Memory, userId string, postId string) {
+	if !store.CreatePost(userId, &models.Post{Id: postId, Body: postId, CreatedAt: time.Now()}) {
+		t.Fatalf("unable to create %s", postId)
+	}
+	tl.Posted(userId, postId)
+}
+
+func follow(tl *Timeline, store *database.Memory, userId string, followId string) {
+	store.ToggleFollow(userId, followId)
+	tl.Followed(userId, followId)
+}
+
+func feed(store *database.Memory, userId string) []string {
+	posts, _ := store.ReadFeedPosts(userId, nil, 100)
+	ids := make([]string, len(posts))
+	for index, post := range posts {
+		ids[index] = post.Id
+	}
+	return ids
+}
+
+func TestPosted(t *testing.T) {
+	tl, store := newTimeline(t, "alice", "bob", "carol")
+	follow(tl, store, "alice", "bob")
+	runAll(t, tl, store)
+
+	post(t, tl, store, "bob", "post")
+	if ids := feed(store, "alice"); len(ids) != 0 {
+		t.Errorf("feed %v before the fan out ran", ids)
+	}
+	runAll(t, tl, store)
+	if ids := feed(store, "alice"); len(ids) != 1 || ids[0] != "post" {
+		t.Errorf("follower's feed %v, want [post]", ids)
+	}
+	if ids := feed(store, "carol"); len(ids) != 0 {
+		t.Errorf("feed of someone not following %v, want none", ids)
+	}
+}
+
+func TestFollowed(t *testing.T) {
+	tl, store := newTimeline(t, "alice", "bob")
+	post(t, tl, store, "bob", "first")
+	post(t, tl, store, "bob", "second")
+	runAll(t, tl, store)
+
+	follow(tl, store, "alice", "bob")
+	runAll(t, tl, store)
+	if ids := feed(store, "alice"); len(ids) != 2 {
+		t.Errorf("feed after following %v, want both posts", ids)
+	}
+
+	follow(tl, store, "alice", "bob")
+	runAll(t, tl, store)
+	if ids := feed(store, "alice"); len(ids) != 0 {
+		t.Errorf("feed after unfollowing %v, want none", ids)
+	}
+}
+
+func TestFanOutOnRead(t *testing.T) {
+	tl, store := newTimeline(t, "star", "alice")
+	follow(tl, store, "alice", "star")
+	for index := 1; index < maxFanOut; index++ {
+		id := fmt.Sprintf("fan%d", index)
+		store.CreateUser(&models.User{Id: id, Username: id})
+		store.ToggleFollow(id, "star")
+	}
+	runAll(t, tl, store)
+
+	post(t, tl, store, "star", "post")
+	runAll(t, tl, store)
+	if !store.IsFanOutOnRead("star") {
+		t.Fatalf("account with %d followers still fanned out on write", maxFanOut)
+	}
+	// Read from the followed account's posts rather than pushed
+	if ids := feed(store, "alice"); len(ids) != 1 || ids[0] != "post" {
+		t.Errorf("follower's feed %v, want [post]", ids)
+	}
+
+	// Staying fanned out on read once followers drop
+	store.ToggleFollow("fan1", "star")
+	post(t, tl, store, "star", "later")
+	runAll(t, tl, store)
+	if !store.IsFanOutOnRead("star") {
+		t.Errorf("account went back to fanning out on write")
+	}
+	if ids := feed(store, "alice"); len(ids) != 2 {
+		t.Errorf("follower's feed %v, want both posts", ids)
+	}
+}
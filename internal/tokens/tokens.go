@@ -0,0 +1,83 @@
+package tokens
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid or expired token")
+	errIssueToken   = errors.New("unable to store token")
+)
+
+// Issuer hands out single-use tokens of the form <id>.<expiry>.<signature>,
+// the signature lets forged tokens be rejected before touching the store
+// while the stored id makes each token usable only once
+type Issuer struct {
+	store  database.TokenStore
+	secret []byte
+}
+
+func NewIssuer(store database.TokenStore, secret []byte) *Issuer {
+	return &Issuer{store: store, secret: secret}
+}
+
+func (i *Issuer) sign(id string, purpose string, expires string) string {
+	mac := hmac.New(sha256.New, i.secret)
+	mac.Write([]byte(purpose + "." + id + "." + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Creates a token for the user valid for ttl, replacing any earlier
+// tokens issued to them for the same purpose
+func (i *Issuer) Issue(userId string, purpose string, ttl time.Duration) (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	token := models.Token{
+		Id:        base64.RawURLEncoding.EncodeToString(random),
+		UserId:    userId,
+		Purpose:   purpose,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+	i.store.DeleteTokens(userId, purpose)
+	if !i.store.CreateToken(&token) {
+		return "", errIssueToken
+	}
+	expires := strconv.FormatInt(token.ExpiresAt.Unix(), 10)
+	return token.Id + "." + expires + "." + i.sign(token.Id, purpose, expires), nil
+}
+
+// Checks the token and uses it up, returning the id of the user it
+// was issued to
+func (i *Issuer) Consume(token string, purpose string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", ErrInvalidToken
+	}
+	id, expires, signature := parts[0], parts[1], parts[2]
+	if !hmac.Equal([]byte(signature), []byte(i.sign(id, purpose, expires))) {
+		return "", ErrInvalidToken
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().After(time.Unix(unix, 0)) {
+		return "", ErrInvalidToken
+	}
+	stored := i.store.ConsumeToken(id, purpose)
+	if stored == nil {
+		return "", ErrInvalidToken
+	}
+	return stored.UserId, nil
+}
@@ -0,0 +1,104 @@
+package tokens
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+// Memory store holding the user tokens are issued to
+func newStore(t *testing.T) *database.Memory {
+	store := database.NewMemory()
+	if !store.CreateUser(&models.User{Id: "user", Username: "user", CreatedAt: time.Now()}) {
+		t.Fatal("unable to create user")
+	}
+	return store
+}
+
+func TestConsume(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		purpose string
+		tamper  func(token string) string
+		wantErr bool
+	}{
+		{"valid", time.Hour, "reset", nil, false},
+		{"expired", -time.Minute, "reset", nil, true},
+		{"other purpose", time.Hour, "verify", nil, true},
+		{"tampered signature", time.Hour, "reset", func(token string) string { return token + "x" }, true},
+		{"extended expiry", time.Hour, "reset", func(token string) string {
+			parts := strings.Split(token, ".")
+			return parts[0] + ".99999999999." + parts[2]
+		}, true},
+		{"malformed", time.Hour, "reset", func(token string) string { return "not-a-token" }, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			issuer := NewIssuer(newStore(t), []byte("secret"))
+			token, err := issuer.Issue("user", "reset", test.ttl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.tamper != nil {
+				token = test.tamper(token)
+			}
+			userId, err := issuer.Consume(token, test.purpose)
+			if test.wantErr {
+				if err != ErrInvalidToken {
+					t.Errorf("Consume = %q, %v, want ErrInvalidToken", userId, err)
+				}
+				return
+			}
+			if err != nil || userId != "user" {
+				t.Errorf("Consume = %q, %v, want user", userId, err)
+			}
+		})
+	}
+}
+
+func TestConsumeOnce(t *testing.T) {
+	issuer := NewIssuer(newStore(t), []byte("secret"))
+	token, err := issuer.Issue("user", "reset", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := issuer.Consume(token, "reset"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := issuer.Consume(token, "reset"); err != ErrInvalidToken {
+		t.Errorf("second Consume = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestIssueReplacesEarlier(t *testing.T) {
+	issuer := NewIssuer(newStore(t), []byte("secret"))
+	first, err := issuer.Issue("user", "reset", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := issuer.Issue("user", "reset", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := issuer.Consume(first, "reset"); err != ErrInvalidToken {
+		t.Errorf("Consume of replaced token = %v, want ErrInvalidToken", err)
+	}
+	if _, err := issuer.Consume(second, "reset"); err != nil {
+		t.Errorf("Consume of latest token = %v", err)
+	}
+}
+
+func TestOtherSecret(t *testing.T) {
+	store := newStore(t)
+	token, err := NewIssuer(store, []byte("secret")).Issue("user", "reset", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewIssuer(store, []byte("other")).Consume(token, "reset"); err != ErrInvalidToken {
+		t.Errorf("Consume with another secret = %v, want ErrInvalidToken", err)
+	}
+}
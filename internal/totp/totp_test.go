@@ -0,0 +1,108 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// The SHA1 secret from RFC 6238, "12345678901234567890" in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// Test vectors from RFC 6238 appendix B, truncated to 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, test := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(test.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code at %d: %v", test.unix, err)
+		}
+		if got != test.want {
+			t.Errorf("Code at %d = %q, want %q", test.unix, got, test.want)
+		}
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("Code accepted a secret that isn't base32")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := Step(now)
+	code := func(offset int64) string {
+		value, err := Code(rfcSecret, step+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return value
+	}
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOk   bool
+	}{
+		{"current step", code(0), step, true},
+		{"previous step", code(-1), step - 1, true},
+		{"next step", code(1), step + 1, true},
+		{"two steps ago", code(-2), 0, false},
+		{"two steps ahead", code(2), 0, false},
+		{"spaces ignored", code(0)[:3] + " " + code(0)[3:], step, true},
+		{"too short", code(0)[:5], 0, false},
+		{"too long", code(0) + "0", 0, false},
+		{"empty", "", 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gotStep, gotOk := Validate(rfcSecret, test.code, now)
+			if gotStep != test.wantStep || gotOk != test.wantOk {
+				t.Errorf("Validate(%q) = %d, %v, want %d, %v", test.code, gotStep, gotOk, test.wantStep, test.wantOk)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Code(secret, 1); err != nil {
+		t.Errorf("generated secret %q can't make codes: %v", secret, err)
+	}
+	other, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == other {
+		t.Error("GenerateSecret returned the same secret twice")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Connect", "alice", rfcSecret)
+	for _, want := range []string{
+		"otpauth://totp/Connect:alice?",
+		"secret=" + rfcSecret,
+		"issuer=Connect",
+		"digits=6",
+		"period=30",
+	} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("URI %q is missing %q", uri, want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/internal/jobs"
+	"github.com/Bhar8at/bhar8at.github.io/internal/mail"
+	"github.com/Bhar8at/bhar8at.github.io/internal/tokens"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+const (
+	purpose = "verify"
+	ttl     = 24 * time.Hour
+	// Job that mails the link, queued with only the user id so tokens are
+	// never stored in the queue
+	kindSend = "verify"
+)
+
+var (
+	ErrNoEmail         = errors.New("account has no email address")
+	ErrAlreadyVerified = errors.New("account is already verified")
+	errUpdateUser      = errors.New("unable to mark account as verified")
+	errQueue           = errors.New("unable to queue verification email")
+)
+
+type sendJob struct {
+	UserId string `json:"userId"`
+}
+
+// Verifier emails users a link that marks their account as verified
+type Verifier struct {
+	users   database.UserStore
+	tokens  *tokens.Issuer
+	mailer  mail.Mailer
+	queue   *jobs.Queue
+	baseURL string
+}
+
+func New(users database.UserStore, issuer *tokens.Issuer, mailer mail.Mailer, queue *jobs.Queue, baseURL string) *Verifier {
+	v := &Verifier{users: users, tokens: issuer, mailer: mailer, queue: queue, baseURL: baseURL}
+	queue.Register(kindSend, jobs.Kind{Run: v.deliver, Concurrency: 2, MaxAttempts: 8})
+	return v
+}
+
+// Queues a new verification link to the user, which invalidates earlier
+// ones once sent
+func (v *Verifier) Send(user *models.User) error {
+	if user.Verified {
+		return ErrAlreadyVerified
+	}
+	if user.Email == nil {
+		return ErrNoEmail
+	}
+	if !v.queue.Enqueue(kindSend, sendJob{UserId: user.Id}) {
+		return errQueue
+	}
+	return nil
+}
+
+// Issues the token and mails the link, run by the queue
+func (v *Verifier) deliver(ctx context.Context, payload []byte) error {
+	var job sendJob
+	if err := json.Unmarshal(payload, &job); err != nil {
+		return jobs.Permanent(err)
+	}
+	user := v.users.ReadUserById(job.UserId)
+	// Nothing to send when the account went or was verified meanwhile
+	if user == nil || user.Verified || user.Email == nil {
+		return nil
+	}
+	token, err := v.tokens.Issue(user.Id, purpose, ttl)
+	if err != nil {
+		return err
+	}
+	link := v.baseURL + "/auth/verify?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf(
+		"Hi @%s,\n\nConfirm your email address by opening the link below:\n\n%s\n\n"+
+			"The link expires in 24 hours. If you didn't create an account, ignore this email.\n",
+		user.Username, link,
+	)
+	return v.mailer.Send(*user.Email, "Verify your account", body)
+}
+
+// Marks the user the token was issued to as verified
+func (v *Verifier) Confirm(token string) (*models.User, error) {
+	userId, err := v.tokens.Consume(token, purpose)
+	if err != nil {
+		return nil, err
+	}
+	user := v.users.ReadUserById(userId)
+	if user == nil {
+		return nil, tokens.ErrInvalidToken
+	}
+	if !v.users.UpdateUser(user.Id, map[string]any{"verified": true}) {
+		return nil, errUpdateUser
+	}
+	user.Verified = true
+	return user, nil
+}
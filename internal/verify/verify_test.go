@@ -0,0 +1,121 @@
+package verify
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/internal/jobs"
+	"github.com/Bhar8at/bhar8at.github.io/internal/tokens"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+type message struct {
+	to, subject, body string
+}
+
+// Mailer keeping what was sent
+type recorder struct {
+	sent []message
+}
+
+func (r *recorder) Send(to string, subject string, body string) error {
+	r.sent = append(r.sent, message{to, subject, body})
+	return nil
+}
+
+var link = regexp.MustCompile(`/auth/verify\?token=(\S+)`)
+
+func newVerifier(t *testing.T) (*Verifier, *database.Memory, *recorder) {
+	store := database.NewMemory()
+	mailer := &recorder{}
+	v := New(store, tokens.NewIssuer(store, []byte("secret")), mailer, jobs.New(store), "http://localhost")
+	return v, store, mailer
+}
+
+// Runs the queued email jobs as a worker would
+func deliverAll(t *testing.T, v *Verifier, store *database.Memory) {
+	for job := store.ClaimJob(kindSend, time.Now().Add(time.Minute)); job != nil; job = store.ClaimJob(kindSend, time.Now().Add(time.Minute)) {
+		if strings.Contains(string(job.Payload), "token") {
+			t.Errorf("job payload %s holds a token", job.Payload)
+		}
+		if err := v.deliver(context.Background(), job.Payload); err != nil {
+			t.Fatal(err)
+		}
+		store.DeleteJob(job.Id)
+	}
+}
+
+func TestSend(t *testing.T) {
+	email := "alice@example.com"
+	tests := []struct {
+		name     string
+		user     models.User
+		wantErr  error
+		wantSent int
+	}{
+		{"unverified", models.User{Id: "alice", Username: "alice", Email: &email}, nil, 1},
+		{"already verified", models.User{Id: "alice", Username: "alice", Email: &email, Verified: true}, ErrAlreadyVerified, 0},
+		{"no email", models.User{Id: "alice", Username: "alice"}, ErrNoEmail, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, store, mailer := newVerifier(t)
+			store.CreateUser(&test.user)
+			if err := v.Send(&test.user); err != test.wantErr {
+				t.Fatalf("Send = %v, want %v", err, test.wantErr)
+			}
+			deliverAll(t, v, store)
+			if len(mailer.sent) != test.wantSent {
+				t.Fatalf("sent %d emails, want %d", len(mailer.sent), test.wantSent)
+			}
+			if test.wantSent > 0 && (mailer.sent[0].to != email || !link.MatchString(mailer.sent[0].body)) {
+				t.Errorf("sent %+v, want a link to %s", mailer.sent[0], email)
+			}
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	v, store, mailer := newVerifier(t)
+	email := "alice@example.com"
+	user := models.User{Id: "alice", Username: "alice", Email: &email}
+	store.CreateUser(&user)
+	if err := v.Send(&user); err != nil {
+		t.Fatal(err)
+	}
+	deliverAll(t, v, store)
+	token := link.FindStringSubmatch(mailer.sent[0].body)[1]
+
+	if _, err := v.Confirm("forged"); err != tokens.ErrInvalidToken {
+		t.Errorf("Confirm of a forged token = %v, want ErrInvalidToken", err)
+	}
+	confirmed, err := v.Confirm(token)
+	if err != nil || confirmed.Id != user.Id {
+		t.Fatalf("Confirm = %v, %v", confirmed, err)
+	}
+	if !store.ReadUserById(user.Id).Verified {
+		t.Error("user not marked as verified")
+	}
+	if _, err := v.Confirm(token); err != tokens.ErrInvalidToken {
+		t.Errorf("second Confirm = %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestDeliverVerifiedMeanwhile(t *testing.T) {
+	v, store, mailer := newVerifier(t)
+	email := "alice@example.com"
+	user := models.User{Id: "alice", Username: "alice", Email: &email}
+	store.CreateUser(&user)
+	if err := v.Send(&user); err != nil {
+		t.Fatal(err)
+	}
+	store.UpdateUser(user.Id, map[string]any{"verified": true})
+	deliverAll(t, v, store)
+	if len(mailer.sent) != 0 {
+		t.Errorf("sent %d emails to a verified user", len(mailer.sent))
+	}
+}
@@ -1,17 +1,27 @@
 package main
 
 import (
+	"context"
 	"html/template"
+	"log"
 	"net/http"
 	"os"
 
+	"github.com/Bhar8at/bhar8at.github.io/api"
+	"github.com/Bhar8at/bhar8at.github.io/database"
 	"github.com/Bhar8at/bhar8at.github.io/internal"
 	socials "github.com/Bhar8at/bhar8at.github.io/internal/auth"
-	"github.com/Bhar8at/bhar8at.github.io/middleware"
+	"github.com/Bhar8at/bhar8at.github.io/internal/jobs"
+	"github.com/Bhar8at/bhar8at.github.io/internal/mail"
+	"github.com/Bhar8at/bhar8at.github.io/internal/media"
+	"github.com/Bhar8at/bhar8at.github.io/internal/reset"
+	"github.com/Bhar8at/bhar8at.github.io/internal/throttle"
+	"github.com/Bhar8at/bhar8at.github.io/internal/timeline"
+	"github.com/Bhar8at/bhar8at.github.io/internal/tokens"
+	"github.com/Bhar8at/bhar8at.github.io/internal/verify"
 	"github.com/Bhar8at/bhar8at.github.io/routes"
-	"github.com/gin-contrib/sessions"
-	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
 )
 
 // Root HTML Page
@@ -21,6 +31,10 @@ func index(c *gin.Context) {
 
 // Error to show when page isn't found
 func notFound(c *gin.Context) {
+	if api.IsAPIRequest(c) {
+		api.NotFound(c)
+		return
+	}
 
 	c.HTML(http.StatusNotFound, "errorT.html", gin.H{
 		"error":   "404 Not Found",
@@ -29,104 +43,119 @@ func notFound(c *gin.Context) {
 
 }
 
-func main() {
-
-	gin.SetMode(gin.ReleaseMode)
+// Storage backend selected through the STORE variable, Postgres by default
+func newStore() (database.Store, error) {
+	switch os.Getenv("STORE") {
+	case "memory":
+		return database.NewMemory(), nil
+	default:
+		db, err := database.NewPostgres(os.Getenv("POSTGRES_URI"))
+		if err != nil {
+			return nil, err
+		}
+		// Bring the schema up to date before serving requests
+		applied, err := db.MigrateUp(context.Background())
+		for _, migration := range applied {
+			log.Printf("applied migration %04d_%s", migration.Version, migration.Name)
+		}
+		return db, err
+	}
+}
 
-	app := gin.Default()
+func main() {
 
-	// Redirects all URL's with trailing slash to the same URL without the trailing slash
-	app.RedirectTrailingSlash = true
+	godotenv.Load(".env")
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		if err := runAdmin(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "gc" {
+		if err := runGC(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	// Makes it so that unavailable methods do not go ignored (405 status code returned)
-	app.HandleMethodNotAllowed = true
+	db, err := newStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+	baseURL := os.Getenv("BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	issuer := tokens.NewIssuer(db, []byte(os.Getenv("SECRET_KEY")))
+	queue := jobs.New(db)
+	// Emails holding tokens are sent by their own jobs, which issue the
+	// token when they run, so only the lockout notices are queued whole
+	sender := mail.FromEnv()
+	mailer := mail.Queue(queue, sender)
+	verifier := verify.New(db, issuer, sender, queue, baseURL)
+	providers, err := socials.LoadRegistry()
+	if err != nil {
+		log.Fatal(err)
+	}
+	timelines := timeline.New(db, queue)
+	mediaStore, err := media.FromEnv(baseURL, []byte(os.Getenv("SECRET_KEY")))
+	if err != nil {
+		log.Fatal(err)
+	}
+	handler := routes.NewHandler(routes.Config{
+		Store:     db,
+		Verifier:  verifier,
+		Resetter:  reset.New(db, issuer, sender, queue, baseURL),
+		Providers: providers.List(),
+		Throttle:  throttle.New(db, mailer, baseURL),
+		Timeline:  timelines,
+		Queue:     queue,
+		Media:     mediaStore,
+	})
+	oauth := socials.NewHandler(db, verifier, baseURL)
+	v1 := api.NewHandler(api.Config{
+		Store:           db,
+		RequireVerified: os.Getenv("REQUIRE_VERIFIED") == "true",
+		Timeline:        timelines,
+	})
 
-	// if route doesn't match any that's given
-	app.NoRoute(notFound)
+	gin.SetMode(gin.ReleaseMode)
 
-	app.Static("/static", "./static")
-	app.Static("/uploads", "./uploads")
+	app := gin.Default()
 
 	// mapping keywords to functions for HTML pages
 	app.SetFuncMap(template.FuncMap{
-		"formatAsTitle": internal.FormatAsTitle,
-		"formatAsDate":  internal.FormatAsDate,
+		"formatAsTitle":  internal.FormatAsTitle,
+		"formatAsDate":   internal.FormatAsDate,
+		"formatAsDevice": internal.FormatAsDevice,
+		"csrfField":      internal.CSRFField,
 	})
 
 	// Load HTML files in the templates folder
 	app.LoadHTMLGlob("templates/*")
 
-	// Storing the current session in a cookie
-	store := cookie.NewStore([]byte(os.Getenv("SECRET_KEY")))
-	app.Use(sessions.Sessions("cookie", store))
-
-	// used to Recover from unexpected errors during handling of HTTP requests
-	app.Use(middleware.RecoveryMiddleware())
-
-	// Routes
-
-	// Basic routes
-	app.GET("/", index)
-	app.GET("/signup", routes.SignUp)
-	app.GET("/login", routes.Login)
-	app.GET("/logout", routes.Logout)
-	app.GET("/feed", middleware.AuthMiddleware(), routes.UserFeed)
-	app.GET("/feed/more", middleware.AuthMiddleware(), routes.LoadMoreFeed)
-
-	// Authentication related routes
-	auth := app.Group("/auth")
-	{
-		auth.GET("/signup/google", socials.GoogleSignUp)
-		auth.GET("/login/google", socials.GoogleLogin)
-		auth.GET("/google", socials.GoogleAuth)
-
-		auth.POST("/signup", routes.SignUp)
-		auth.POST("/login", routes.Login)
-
-	}
-
-	user := app.Group("/user")
-	user.GET("/:username", routes.GetUserByName)
-	user.GET("/:username/posts", routes.GetUserPosts)
-	user.GET("/:username/posts/more", routes.LoadMorePosts)
-	user.Use(middleware.AuthMiddleware())
-	{
-		user.GET("/", routes.GetUser)
-		user.GET("/settings/avatar", routes.UpdateAvatar)
-		user.GET("/settings/username", routes.UpdateUsername)
-		user.GET("/settings/password", routes.UpdatePassword)
-		user.GET("/settings/delete", routes.DeleteUser)
-
-		user.POST("/:username/toggle-follow", routes.ToggleFollow)
-		user.POST("/settings/avatar", routes.UpdateAvatar)
-		user.POST("/settings/username", routes.UpdateUsername)
-		user.POST("/settings/password", routes.UpdatePassword)
-		user.POST("/settings/delete", routes.DeleteUser)
+	if err := registerRoutes(app, services{
+		store:           db,
+		handler:         handler,
+		oauth:           oauth,
+		v1:              v1,
+		providers:       providers.List(),
+		media:           mediaStore,
+		secret:          []byte(os.Getenv("SECRET_KEY")),
+		requireVerified: os.Getenv("REQUIRE_VERIFIED") == "true",
+	}); err != nil {
+		log.Fatal(err)
 	}
 
-	search := app.Group("/search")
-	{
-		search.GET("/", routes.SearchUser)
-		search.GET("/more", routes.LoadMoreUsers)
-
-		search.POST("/", routes.SearchUser)
-		search.POST("/:username/toggle-follow", middleware.AuthMiddleware(), routes.ToggleSearchFollow)
-	}
-
-	// CRUD functionality for posts
-	post := app.Group("/post")
-	post.GET("/:id", routes.GetPost)
-	post.Use(middleware.AuthMiddleware())
-	{
-		post.GET("/", routes.NewPost)
-		post.GET("/:id/toggle-vote", routes.ToggleVote)
-		post.GET("/:id/delete", routes.DeletePost)
-		post.GET("/:id/comments", routes.LoadMoreComments)
-		post.GET("/:id/comment/delete", routes.DeleteComment)
-
-		post.POST("/", routes.NewPost)
-		post.POST("/:id/comment", routes.Comment)
-	}
+	scheduleCleanup(queue, db)
+	scheduleMediaGC(queue, db, mediaStore)
+	queue.Start()
 
 	if err := app.Run("0.0.0.0:8080"); err != nil {
 		panic(err)
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// Marks personal access tokens so they're recognisable if leaked
+const accessTokenPrefix = "cnx_"
+
+// AuthStore is the storage needed to authenticate sessions and tokens
+type AuthStore interface {
+	database.SessionStore
+	database.AccessTokenStore
+}
+
+// Generates a personal access token, returned for showing once along
+// with the hash to store
+func GenerateAccessToken() (string, string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", "", err
+	}
+	token := accessTokenPrefix + base64.RawURLEncoding.EncodeToString(random)
+	return token, HashAccessToken(token), nil
+}
+
+func HashAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Returns the token sent in an Authorization: Bearer header, if any
+func BearerToken(c *gin.Context) (string, bool) {
+	header := c.GetHeader("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// Looks up the bearer token, tracking when it was last used, nil when
+// it is unknown or revoked
+func AuthenticateAccessToken(c *gin.Context, store database.AccessTokenStore, token string) *models.AccessToken {
+	record := store.ReadAccessTokenByHash(HashAccessToken(token))
+	if record == nil {
+		return nil
+	}
+	now := time.Now()
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) > touchInterval {
+		store.TouchAccessToken(record.Id, now)
+	}
+	c.Set("accessToken", record)
+	return record
+}
+
+// Lets requests authenticated with a personal access token through when
+// the token has the scope. Tokens only act as their user on routes using
+// this, so everything else stays limited to browser sessions.
+func Scope(scope string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		value, ok := c.Get("accessToken")
+		if !ok {
+			c.Next()
+			return
+		}
+		token := value.(*models.AccessToken)
+		if !token.HasScope(scope) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "token is missing the " + scope + " scope",
+			})
+			return
+		}
+		// Set for this request only, the session cookie isn't saved
+		session := sessions.Default(c)
+		session.Set("userId", token.UserId)
+		c.Set("userId", token.UserId)
+		c.Next()
+	}
+}
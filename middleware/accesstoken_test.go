@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/gin-gonic/gin"
+)
+
+func TestScope(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      *models.AccessToken
+		want       int
+		wantUserId string
+	}{
+		{"session request", nil, http.StatusOK, ""},
+		{"token with scope", &models.AccessToken{UserId: "user", Scopes: []string{"read", "write"}}, http.StatusOK, "user"},
+		{"token without scope", &models.AccessToken{UserId: "user", Scopes: []string{"read"}}, http.StatusForbidden, ""},
+		{"token without scopes", &models.AccessToken{UserId: "user"}, http.StatusForbidden, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := newEngine()
+			app.Use(func(c *gin.Context) {
+				if test.token != nil {
+					c.Set("accessToken", test.token)
+				}
+			})
+			app.POST("/posts", Scope("write"), func(c *gin.Context) {
+				c.String(http.StatusOK, c.GetString("userId"))
+			})
+			response := httptest.NewRecorder()
+			app.ServeHTTP(response, httptest.NewRequest(http.MethodPost, "/posts", nil))
+			if response.Code != test.want {
+				t.Errorf("status = %d, want %d", response.Code, test.want)
+			}
+			if test.want == http.StatusOK && response.Body.String() != test.wantUserId {
+				t.Errorf("userId = %q, want %q", response.Body.String(), test.wantUserId)
+			}
+		})
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		header    string
+		wantToken string
+		wantOk    bool
+	}{
+		{"Bearer cnx_abc", "cnx_abc", true},
+		{"bearer  cnx_abc ", "cnx_abc", true},
+		{"Basic dXNlcjpwYXNz", "", false},
+		{"Bearer", "", false},
+		{"", "", false},
+	}
+	for _, test := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		c.Request.Header.Set("Authorization", test.header)
+		token, ok := BearerToken(c)
+		if token != test.wantToken || ok != test.wantOk {
+			t.Errorf("BearerToken(%q) = %q, %v, want %q, %v", test.header, token, ok, test.wantToken, test.wantOk)
+		}
+	}
+}
@@ -6,9 +6,12 @@ import (
 	"os"
 	"time"
 
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/models"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 )
 
@@ -17,10 +20,21 @@ type JWTClaims struct {
 	jwt.StandardClaims
 }
 
+const (
+	// Lifetime of a signed token before it has to be refreshed
+	accessTokenTTL = 15 * time.Minute
+	// Sessions expire after this long without activity
+	sessionTTL = 7 * 24 * time.Hour
+	// Minimum time between writes of a session's last seen time
+	touchInterval = time.Minute
+)
+
 var (
-	issuer          string
-	secretKey       []byte
-	errInvalidToken = errors.New("invalid token")
+	issuer           string
+	secretKey        []byte
+	errInvalidToken  = errors.New("invalid token")
+	errRevokedToken  = errors.New("revoked token")
+	errCreateSession = errors.New("unable to create session")
 )
 
 func init() {
@@ -29,14 +43,35 @@ func init() {
 	secretKey = []byte(os.Getenv("SECRET_KEY"))
 }
 
-func CreateToken(id string) (string, error) {
+// Starts a new session for the user on the requesting device and
+// returns its token
+func CreateToken(c *gin.Context, store database.SessionStore, id string) (string, error) {
+	now := time.Now()
+	session := models.Session{
+		Id:         uuid.NewString(),
+		UserId:     id,
+		UserAgent:  c.Request.UserAgent(),
+		Ip:         c.ClientIP(),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(sessionTTL),
+	}
+	if !store.CreateSession(&session) {
+		return "", errCreateSession
+	}
+	return signToken(session.Id, id, now)
+}
+
+func signToken(sessionId string, id string, now time.Time) (string, error) {
 
 	// adding the arguments for the JWTclaims
 	claims := JWTClaims{
 		id,
 		jwt.StandardClaims{
-			Issuer:   issuer,
-			IssuedAt: time.Now().Unix(),
+			Id:        sessionId,
+			Issuer:    issuer,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(accessTokenTTL).Unix(),
 		},
 	}
 
@@ -47,28 +82,95 @@ func CreateToken(id string) (string, error) {
 	return token.SignedString(secretKey)
 }
 
-func ParseToken(token string) (*JWTClaims, error) {
+// Checks the token's signature and returns its claims, along with
+// whether it has expired and needs to be refreshed
+func ParseToken(token string) (*JWTClaims, bool, error) {
 
 	// parses the JWT token
 	// token -- > JWT token to be parsed
 	// &JWTClaims{} --> placeholder to unmarshall the claims present in token
 	// func(t *jwt.Token) (interface{}, error) --> callback function that returns the secret key used for verification
 	parsedToken, err := jwt.ParseWithClaims(token, &JWTClaims{}, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errInvalidToken
+		}
 		return secretKey, nil
 	})
+	expired := false
 	if err != nil {
-		return nil, err
+		// An expired token is still usable to refresh its session
+		validationErr, ok := err.(*jwt.ValidationError)
+		if !ok || validationErr.Errors != jwt.ValidationErrorExpired {
+			return nil, false, err
+		}
+		expired = true
 	}
 	// checks if the parsing was successful and the token is valid
-	if claims, ok := parsedToken.Claims.(*JWTClaims); ok && parsedToken.Valid {
-		return claims, nil
+	if claims, ok := parsedToken.Claims.(*JWTClaims); ok && (parsedToken.Valid || expired) {
+		if claims.Id == "" {
+			return nil, false, errInvalidToken
+		}
+		return claims, expired, nil
+	}
+	return nil, false, errInvalidToken
+}
+
+// Validates the session's token against the sessions table, refreshing
+// the token once it expires and sliding the session's expiry forward
+func authenticate(c *gin.Context, store database.SessionStore) (*JWTClaims, error) {
+	session := sessions.Default(c)
+	token, ok := session.Get("Authorization").(string)
+	if !ok {
+		return nil, errInvalidToken
+	}
+	claims, expired, err := ParseToken(token)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	record := store.ReadSession(claims.Id)
+	if record == nil || record.UserId != claims.UserId || now.After(record.ExpiresAt) {
+		return nil, errRevokedToken
 	}
-	return nil, errInvalidToken
+	if expired {
+		refreshed, err := signToken(record.Id, record.UserId, now)
+		if err != nil {
+			return nil, err
+		}
+		session.Set("Authorization", refreshed)
+		session.Save()
+	}
+	if expired || now.Sub(record.LastSeenAt) > touchInterval {
+		store.TouchSession(record.Id, c.ClientIP(), now, now.Add(sessionTTL))
+	}
+	return claims, nil
 }
 
-func AuthMiddleware() func(c *gin.Context) {
+// Ends the session the token belongs to, even if the token has expired
+func RevokeToken(store database.SessionStore, token string) {
+	if claims, _, err := ParseToken(token); err == nil {
+		store.DeleteSession(claims.Id)
+	}
+}
+
+// Requires a logged in session, or a personal access token sent as a
+// bearer header which Scope then checks for the route
+func AuthMiddleware(store AuthStore) func(c *gin.Context) {
 	return func(c *gin.Context) {
 		session := sessions.Default(c)
+		if bearer, ok := BearerToken(c); ok {
+			// Never fall back to the cookie when a token was sent
+			session.Delete("userId")
+			session.Delete("sessionId")
+			if AuthenticateAccessToken(c, store, bearer) == nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+					"error": "invalid access token",
+				})
+				return
+			}
+			c.Next()
+			return
+		}
 		token := session.Get("Authorization")
 		if token == nil {
 			c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
@@ -78,9 +180,11 @@ func AuthMiddleware() func(c *gin.Context) {
 			c.Abort()
 			return
 		}
-		parsedToken, err := ParseToken(token.(string))
+		parsedToken, err := authenticate(c, store)
 		if err != nil {
-			c.HTML(http.StatusUnauthorized, "error.tmpl.html", gin.H{
+			session.Clear()
+			session.Save()
+			c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
 				"error":   "401 Unauthorized",
 				"message": "Invalid authorization token, try logging in again.",
 			})
@@ -88,7 +192,28 @@ func AuthMiddleware() func(c *gin.Context) {
 			return
 		}
 		session.Set("userId", parsedToken.UserId)
+		session.Set("sessionId", parsedToken.Id)
 		session.Save()
+		c.Set("userId", parsedToken.UserId)
 		c.Next()
 	}
 }
+
+// Returns the id of the logged in user from the session's authorization
+// token, or an empty string when there is none. A token that no longer
+// validates, such as one of a revoked session, is dropped from the cookie.
+func SessionUserId(c *gin.Context, store database.SessionStore) string {
+	session := sessions.Default(c)
+	if session.Get("Authorization") == nil {
+		return ""
+	}
+	claims, err := authenticate(c, store)
+	if err != nil {
+		session.Delete("Authorization")
+		session.Delete("userId")
+		session.Delete("sessionId")
+		session.Save()
+		return ""
+	}
+	return claims.UserId
+}
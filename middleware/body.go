@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Refuses request bodies over the limit, checking the declared length up
+// front so an oversized upload fails with a clear error before it's read,
+// and cutting off bodies which turn out longer than declared
+func BodyLimitMiddleware(limit int64) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			message := fmt.Sprintf("Requests can be at most %d MB.", limit>>20)
+			if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+				// In the API's error format
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+					"error": gin.H{"code": "payload_too_large", "message": message},
+				})
+				return
+			}
+			c.HTML(http.StatusRequestEntityTooLarge, "errorT.html", gin.H{
+				"error":   "413 Payload Too Large",
+				"message": message,
+			})
+			c.Abort()
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
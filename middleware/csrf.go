@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// Form field and header the token is sent back in
+	CSRFField  = "csrf_token"
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// Gives every session a random token that must be sent back with each
+// POST, DELETE and the like, so other sites can't submit forms as the user
+func CSRFMiddleware() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		// The API only accepts access tokens, never the session cookie
+		if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+			c.Next()
+			return
+		}
+		session := sessions.Default(c)
+		token, _ := session.Get("csrfToken").(string)
+		if token == "" {
+			random := make([]byte, 32)
+			if _, err := rand.Read(random); err != nil {
+				log.Println(err)
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			token = base64.RawURLEncoding.EncodeToString(random)
+			session.Set("csrfToken", token)
+			session.Save()
+		}
+		c.Set("csrfToken", token)
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		// Browsers can't attach a bearer token to a cross site request
+		if _, ok := BearerToken(c); ok {
+			c.Next()
+			return
+		}
+		sent := c.GetHeader(CSRFHeader)
+		if sent == "" {
+			sent = c.PostForm(CSRFField)
+		}
+		if subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+			c.HTML(http.StatusForbidden, "errorT.html", gin.H{
+				"error":   "403 Forbidden",
+				"message": "Form expired, go back, reload the page and try again.",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Token to include in the forms rendered for the request
+func CSRFToken(c *gin.Context) string {
+	return c.GetString("csrfToken")
+}
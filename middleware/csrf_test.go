@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+)
+
+// Engine with sessions and a stand-in error page, as the middleware
+// expects from the app
+func newEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	app := gin.New()
+	app.SetHTMLTemplate(template.Must(template.New("errorT.html").Parse("{{ .error }}")))
+	app.Use(sessions.Sessions("cookie", cookie.NewStore([]byte("secret"))))
+	return app
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	app := newEngine()
+	app.Use(CSRFMiddleware())
+	app.GET("/form", func(c *gin.Context) { c.String(http.StatusOK, CSRFToken(c)) })
+	app.POST("/form", func(c *gin.Context) { c.Status(http.StatusOK) })
+	app.POST("/api/v1/posts", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	response := httptest.NewRecorder()
+	app.ServeHTTP(response, httptest.NewRequest(http.MethodGet, "/form", nil))
+	token := response.Body.String()
+	if response.Code != http.StatusOK || token == "" {
+		t.Fatalf("GET /form = %d %q, want a token", response.Code, token)
+	}
+	session := response.Result().Cookies()
+
+	tests := []struct {
+		name     string
+		path     string
+		form     url.Values
+		header   http.Header
+		noCookie bool
+		want     int
+	}{
+		{"form field", "/form", url.Values{CSRFField: {token}}, nil, false, http.StatusOK},
+		{"header", "/form", nil, http.Header{CSRFHeader: {token}}, false, http.StatusOK},
+		{"missing token", "/form", nil, nil, false, http.StatusForbidden},
+		{"wrong token", "/form", url.Values{CSRFField: {token + "x"}}, nil, false, http.StatusForbidden},
+		{"token without session", "/form", url.Values{CSRFField: {token}}, nil, true, http.StatusForbidden},
+		{"bearer token", "/form", nil, http.Header{"Authorization": {"Bearer cnx_token"}}, false, http.StatusOK},
+		{"api", "/api/v1/posts", nil, nil, true, http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, test.path, strings.NewReader(test.form.Encode()))
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			for name, values := range test.header {
+				request.Header.Set(name, values[0])
+			}
+			if !test.noCookie {
+				for _, c := range session {
+					request.AddCookie(c)
+				}
+			}
+			response := httptest.NewRecorder()
+			app.ServeHTTP(response, request)
+			if response.Code != test.want {
+				t.Errorf("POST %s = %d, want %d", test.path, response.Code, test.want)
+			}
+		})
+	}
+}
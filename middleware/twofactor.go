@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// Time allowed between the password and the two factor code
+	pendingLoginTTL = 5 * time.Minute
+	// Wrong codes allowed before the password has to be entered again
+	pendingLoginAttempts = 5
+)
+
+var (
+	errNoPendingLogin     = errors.New("no pending login")
+	errCreatePendingLogin = errors.New("unable to create pending login")
+)
+
+// LoginStore is the storage needed to log users in
+type LoginStore interface {
+	database.SessionStore
+	database.TwoFactorStore
+}
+
+// Logs the user in after their password or OAuth provider was checked and
+// returns where to redirect them. Users with two factor authentication
+// are only remembered as pending until they enter a code at /auth/2fa.
+func Login(c *gin.Context, store LoginStore, id string) (string, error) {
+	session := sessions.Default(c)
+	if twoFactor := store.ReadTwoFactor(id); twoFactor != nil && twoFactor.Enabled {
+		now := time.Now()
+		pending := models.PendingLogin{
+			Id:        uuid.NewString(),
+			UserId:    id,
+			ExpiresAt: now.Add(pendingLoginTTL),
+			CreatedAt: now,
+		}
+		if !store.CreatePendingLogin(&pending) {
+			return "", errCreatePendingLogin
+		}
+		session.Delete("Authorization")
+		session.Set("pendingLogin", pending.Id)
+		session.Save()
+		return "/auth/2fa", nil
+	}
+	token, err := CreateToken(c, store, id)
+	if err != nil {
+		return "", err
+	}
+	session.Set("Authorization", token)
+	session.Save()
+	return "/feed", nil
+}
+
+// Returns the user waiting on the second login step, counting the call
+// as an attempt at entering their code. The attempts are kept with the
+// pending login in the store, the cookie only names it.
+func PendingLogin(c *gin.Context, store LoginStore) (string, error) {
+	session := sessions.Default(c)
+	id, _ := session.Get("pendingLogin").(string)
+	pending := store.AttemptPendingLogin(id, time.Now(), pendingLoginAttempts)
+	if id == "" || pending == nil {
+		clearPendingLogin(session)
+		return "", errNoPendingLogin
+	}
+	return pending.UserId, nil
+}
+
+// Finishes a pending login once the user's code was checked
+func CompleteLogin(c *gin.Context, store LoginStore, id string) error {
+	session := sessions.Default(c)
+	token, err := CreateToken(c, store, id)
+	if err != nil {
+		return err
+	}
+	if pendingId, ok := session.Get("pendingLogin").(string); ok {
+		store.DeletePendingLogin(pendingId)
+	}
+	clearPendingLogin(session)
+	session.Set("Authorization", token)
+	session.Save()
+	return nil
+}
+
+func clearPendingLogin(session sessions.Session) {
+	session.Delete("pendingLogin")
+	session.Save()
+}
+
+// TwoFactorStore is the storage needed to enforce two factor authentication
+type TwoFactorStore interface {
+	IsAdmin(id string) bool
+	ReadTwoFactor(userId string) *models.TwoFactor
+}
+
+// Sends admins without two factor authentication to enroll before they
+// can do anything else, must run after AuthMiddleware
+func TwoFactorMiddleware(store TwoFactorStore) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		session := sessions.Default(c)
+		id, _ := session.Get("userId").(string)
+		if !store.IsAdmin(id) || strings.HasPrefix(c.FullPath(), "/user/settings/2fa") {
+			c.Next()
+			return
+		}
+		if twoFactor := store.ReadTwoFactor(id); twoFactor == nil || !twoFactor.Enabled {
+			c.Redirect(http.StatusFound, "/user/settings/2fa")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// Limits the routes to admins, must run after AuthMiddleware
+func AdminMiddleware(store TwoFactorStore) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		session := sessions.Default(c)
+		id, _ := session.Get("userId").(string)
+		if !store.IsAdmin(id) {
+			c.HTML(http.StatusForbidden, "errorT.html", gin.H{
+				"error":   "403 Forbidden",
+				"message": "Only admins can view this page.",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// Only lets users with a verified email through, must run after AuthMiddleware
+func VerifiedMiddleware(users database.UserStore) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		session := sessions.Default(c)
+		id, _ := session.Get("userId").(string)
+		user := users.ReadUserById(id)
+		if user == nil || !user.Verified {
+			c.HTML(http.StatusForbidden, "errorT.html", gin.H{
+				"error":   "403 Forbidden",
+				"message": "Verify your email address before posting.",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// What a personal access token may be used for
+const (
+	ScopeRead         = "read"
+	ScopeWritePosts   = "write:posts"
+	ScopeWriteFollows = "write:follows"
+)
+
+// Scopes users can choose from when creating a token
+var Scopes = []string{ScopeRead, ScopeWritePosts, ScopeWriteFollows}
+
+// Personal access token for scripts, sent as a bearer token. Only the
+// hash of the token is stored.
+type AccessToken struct {
+	Id         string
+	UserId     string
+	Name       string
+	Hash       string
+	Scopes     []string
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
+
+func (t *AccessToken) HasScope(scope string) bool {
+	for _, granted := range t.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
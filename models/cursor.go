@@ -0,0 +1,68 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks the last item of a page, the next page starts after it.
+// Lists are ordered by (CreatedAt, Id), or by Id alone for lists without
+// a creation time, so rows added while paging don't shift the pages.
+type Cursor struct {
+	CreatedAt time.Time
+	Id        string
+}
+
+// Opaque form given to clients, empty for a nil cursor
+func (c *Cursor) Encode() string {
+	if c == nil {
+		return ""
+	}
+	var nanos int64
+	if !c.CreatedAt.IsZero() {
+		nanos = c.CreatedAt.UnixNano()
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(nanos, 10) + ":" + c.Id))
+}
+
+// Decodes a cursor from Encode, an empty string is the first page and
+// decodes to nil
+func DecodeCursor(value string) (*Cursor, error) {
+	if value == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	nanos, id, found := strings.Cut(string(raw), ":")
+	if !found || id == "" {
+		return nil, ErrInvalidCursor
+	}
+	parsed, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	cursor := Cursor{Id: id}
+	if parsed != 0 {
+		cursor.CreatedAt = time.Unix(0, parsed).UTC()
+	}
+	return &cursor, nil
+}
+
+// Whether an item comes after the cursor in newest first order, which
+// every item does for a nil cursor
+func (c *Cursor) After(createdAt time.Time, id string) bool {
+	if c == nil {
+		return true
+	}
+	if !createdAt.Equal(c.CreatedAt) {
+		return createdAt.Before(c.CreatedAt)
+	}
+	return id < c.Id
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor Cursor
+	}{
+		{"time and id", Cursor{CreatedAt: time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC), Id: "abc"}},
+		{"id only", Cursor{Id: "abc"}},
+		{"id with colon", Cursor{CreatedAt: time.Unix(1, 0).UTC(), Id: "a:b"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decoded, err := DecodeCursor(test.cursor.Encode())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !decoded.CreatedAt.Equal(test.cursor.CreatedAt) || decoded.Id != test.cursor.Id {
+				t.Errorf("DecodeCursor(Encode()) = %+v, want %+v", *decoded, test.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantNil bool
+		wantErr bool
+	}{
+		{"empty is first page", "", true, false},
+		{"not base64", "!!!", true, true},
+		{"no separator", "MTIz", true, true},
+		{"no id", "MTIzOg", true, true},
+		{"time not a number", "YWJjOmlk", true, true},
+		{"valid", "MTIzOmlk", false, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cursor, err := DecodeCursor(test.value)
+			if (err != nil) != test.wantErr {
+				t.Errorf("DecodeCursor(%q) error = %v, want error %v", test.value, err, test.wantErr)
+			}
+			if err != nil && err != ErrInvalidCursor {
+				t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", test.value, err)
+			}
+			if (cursor == nil) != test.wantNil {
+				t.Errorf("DecodeCursor(%q) = %v, want nil %v", test.value, cursor, test.wantNil)
+			}
+		})
+	}
+}
+
+func TestNilCursorEncode(t *testing.T) {
+	var cursor *Cursor
+	if got := cursor.Encode(); got != "" {
+		t.Errorf("nil Encode = %q, want empty", got)
+	}
+}
+
+func TestCursorAfter(t *testing.T) {
+	at := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	cursor := &Cursor{CreatedAt: at, Id: "m"}
+	tests := []struct {
+		name      string
+		cursor    *Cursor
+		createdAt time.Time
+		id        string
+		want      bool
+	}{
+		{"nil cursor", nil, at, "m", true},
+		{"older", cursor, at.Add(-time.Second), "z", true},
+		{"newer", cursor, at.Add(time.Second), "a", false},
+		{"same time lower id", cursor, at, "a", true},
+		{"same time higher id", cursor, at, "z", false},
+		{"the cursor itself", cursor, at, "m", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.cursor.After(test.createdAt, test.id); got != test.want {
+				t.Errorf("After(%v, %q) = %v, want %v", test.createdAt, test.id, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Login at an OAuth provider linked to a user, identified by the
+// provider's subject claim which unlike the email never changes
+type Identity struct {
+	Id        string
+	UserId    string
+	Provider  string
+	Subject   string
+	Email     string
+	CreatedAt time.Time
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Job states shown to admins, worked out from the job's times
+const (
+	JobQueued    = "queued"
+	JobScheduled = "scheduled"
+	JobRunning   = "running"
+	JobRetrying  = "retrying"
+)
+
+// Work queued to run in the background, payload holding its JSON arguments
+type Job struct {
+	Id          string
+	Kind        string
+	Payload     []byte
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time
+	// Set while a worker has the job, which is run again by another worker
+	// if this passes before it finishes
+	LockedUntil *time.Time
+	LastError   *string
+	CreatedAt   time.Time
+	// Set on jobs moved to the dead jobs after running out of attempts
+	FailedAt *time.Time
+}
+
+func (j *Job) Status() string {
+	now := time.Now()
+	switch {
+	case j.LockedUntil != nil && j.LockedUntil.After(now):
+		return JobRunning
+	case j.RunAt.After(now) && j.Attempts > 0:
+		return JobRetrying
+	case j.RunAt.After(now):
+		return JobScheduled
+	default:
+		return JobQueued
+	}
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Password login, kept for throttling guesses and the admin log
+type LoginAttempt struct {
+	Id        int64
+	Username  string
+	Ip        string
+	UserAgent string
+	Success   bool
+	CreatedAt time.Time
+}
+
+// Scopes a lockout applies to
+const (
+	LockoutAccount = "account"
+	LockoutIp      = "ip"
+)
+
+// Temporary block on logging in to an account or from an IP address after
+// repeated failures
+type Lockout struct {
+	Scope       string
+	Key         string
+	Failures    int
+	LockedUntil time.Time
+	CreatedAt   time.Time
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// File kept in the media store, new ones under the hash of their content so
+// identical uploads share them. Refs counts the post images and avatars
+// using it.
+type MediaObject struct {
+	Key string
+	// Zero when unknown, as for files stored before these were kept
+	Size      int64
+	Refs      int
+	CreatedAt time.Time
+	// Last stored or reused, files just used are never collected even
+	// before anything refers to them
+	TouchedAt time.Time
+}
+
+// Upload refused as not a usable image, kept in the media store under a
+// private key so admins can look into it
+type QuarantinedUpload struct {
+	Id     string
+	UserId string
+	// File name given by the client
+	Name      string
+	Key       string
+	Reason    string
+	Size      int64
+	CreatedAt time.Time
+}
@@ -9,7 +9,26 @@ type Post struct {
 	Username  string
 	Avatar    *string
 	CreatedAt time.Time
-	Images    string
+	// Set once the body has been edited
+	EditedAt *time.Time
+	Media    []PostMedia
+}
+
+// Image attached to a post
+type PostMedia struct {
+	Id     string
+	PostId string
+	// Original with its metadata removed, and copies scaled down for
+	// showing in feeds, which are the original when it's small enough
+	URL          string
+	MediumURL    string
+	ThumbnailURL string
+	// Placeholder to show while the image loads, empty when unknown
+	Blurhash string
+	AltText  string
+	// Zero when unknown
+	Width  int
+	Height int
 }
 
 type Comment struct {
@@ -18,6 +37,21 @@ type Comment struct {
 	Id        string
 	Body      string `form:"body" binding:"required"`
 	Username  string
+	Avatar    *string
 	Self      bool
 	CreatedAt time.Time
 }
+
+// Earlier body of an edited post, CreatedAt being when it was written
+type PostRevision struct {
+	Id        string
+	PostId    string
+	Body      string
+	CreatedAt time.Time
+}
+
+// Vote count of a post, along with whether the reading user voted
+type PostVotes struct {
+	Count int
+	Voted bool
+}
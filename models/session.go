@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Server side record of a login, identified by the JWT's token ID
+type Session struct {
+	Id         string
+	UserId     string
+	UserAgent  string
+	Ip         string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	// Set when listing sessions for the session making the request
+	Current bool
+}
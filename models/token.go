@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Single-use token sent to a user, such as an email verification link
+type Token struct {
+	Id        string
+	UserId    string
+	Purpose   string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// TOTP secret of a user, only enforced at login once Enabled is set
+type TwoFactor struct {
+	UserId  string
+	Secret  string
+	Enabled bool
+	// Time step of the last accepted code, codes can't be reused
+	LastStep  int64
+	CreatedAt time.Time
+}
+
+// Login that passed the password or OAuth provider and waits on a two
+// factor code, identified by the id kept in the cookie session
+type PendingLogin struct {
+	Id     string
+	UserId string
+	// Codes tried so far
+	Attempts  int
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
@@ -16,11 +16,22 @@ type User struct {
 	CreatedAt time.Time
 }
 
-type GoogleUser struct {
-	Email    string  `json:"email"`
-	Username string  `json:"given_name"`
-	Avatar   *string `json:"picture"`
-	Verified bool    `json:"email_verified"`
+// Counts shown alongside a user, and whether the reading user follows them
+type UserStats struct {
+	Followers int
+	Following int
+	Posts     int
+	Followed  bool
+}
+
+// User information returned by an OpenID Connect provider, mapped from
+// the provider's claims
+type OAuthUser struct {
+	Subject  string
+	Email    string
+	Username string
+	Avatar   *string
+	Verified bool
 }
 
 type Login struct {
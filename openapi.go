@@ -0,0 +1,359 @@
+package main
+
+import (
+	"github.com/Bhar8at/bhar8at.github.io/api"
+	"github.com/Bhar8at/bhar8at.github.io/internal/auth"
+	"github.com/Bhar8at/bhar8at.github.io/internal/openapi"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+const (
+	htmlType      = "text/html"
+	jsonType      = "application/json"
+	multipartType = "multipart/form-data"
+)
+
+// Builds the OpenAPI document served at /api/openapi.json. Every route
+// registered in registerRoutes has to be described here, which
+// openapi_test.go checks.
+func buildSpec(providers []*auth.Provider) *openapi.Document {
+	doc := openapi.New(
+		"Connectify",
+		"1.0.0",
+		"Pages are rendered as HTML and use the session cookie, forms must send the csrf_token from the page. "+
+			"The JSON API under /api/v1 only accepts personal access tokens, created at /user/settings/tokens.",
+	)
+	doc.Components.SecuritySchemes["session"] = openapi.SecurityScheme{
+		Type:        "apiKey",
+		In:          "cookie",
+		Name:        "cookie",
+		Description: "Session cookie set on login",
+	}
+	doc.Components.SecuritySchemes["token"] = openapi.SecurityScheme{
+		Type:        "http",
+		Scheme:      "bearer",
+		Description: "Personal access token, limited to the scopes chosen when it was created",
+	}
+	specPages(doc, providers)
+	specAPI(doc)
+	return doc
+}
+
+// Operation rendering an HTML page
+func page(summary string, tag string) *openapi.Operation {
+	return &openapi.Operation{
+		Summary: summary,
+		Tags:    []string{tag},
+		Responses: map[string]openapi.Response{
+			"200": openapi.Respond("Page", htmlType, openapi.String()),
+		},
+	}
+}
+
+// Operation that redirects when it succeeds
+func redirect(summary string, tag string) *openapi.Operation {
+	return &openapi.Operation{
+		Summary: summary,
+		Tags:    []string{tag},
+		Responses: map[string]openapi.Response{
+			"302": openapi.Respond("Redirect to the resulting page", "", nil),
+		},
+	}
+}
+
+// Marks the operation as needing a logged in session, or also an access
+// token with the scope when one is given
+func loggedIn(op *openapi.Operation, scope string) *openapi.Operation {
+	op.Security = []map[string][]string{{"session": {}}}
+	if scope != "" {
+		op.Security = append(op.Security, map[string][]string{"token": {}})
+		op.Scope = scope
+	}
+	op.Responses["401"] = openapi.Respond("Not logged in", htmlType, openapi.String())
+	return op
+}
+
+// Adds a form body, with the CSRF token every form has to send
+func form(op *openapi.Operation, schema *openapi.Schema) *openapi.Operation {
+	if schema == nil {
+		schema = openapi.Object(map[string]*openapi.Schema{})
+	}
+	schema.Properties["csrf_token"] = openapi.String()
+	schema.Required = append(schema.Required, "csrf_token")
+	op.RequestBody = openapi.Body(multipartType, schema)
+	op.Responses["403"] = openapi.Respond("Missing or expired CSRF token", htmlType, openapi.String())
+	return op
+}
+
+// Page of items returned to the page's scripts, superseded by /api/v1
+func legacyJSON(summary string, tag string) *openapi.Operation {
+	return &openapi.Operation{
+		Summary: summary,
+		Tags:    []string{tag},
+		Responses: map[string]openapi.Response{
+			"200": openapi.Respond("Items for the page's scripts, use /api/v1 instead", jsonType,
+				openapi.Object(map[string]*openapi.Schema{
+					"data":       openapi.Array(&openapi.Schema{Type: "object"}),
+					"nextCursor": {Type: "string", Description: "Empty on the last page"},
+				}, "data", "nextCursor")),
+		},
+	}
+}
+
+// Page after the cursor from the previous one, for the "More" buttons
+func more(summary string, tag string) *openapi.Operation {
+	op := query(legacyJSON(summary, tag), openapi.Query("cursor", "nextCursor of the previous page", false))
+	op.Responses["400"] = openapi.Respond("Malformed cursor", jsonType, &openapi.Schema{Type: "object"})
+	return op
+}
+
+// Adds a query parameter
+func query(op *openapi.Operation, params ...openapi.Parameter) *openapi.Operation {
+	op.Parameters = append(op.Parameters, params...)
+	return op
+}
+
+func fields(names ...string) *openapi.Schema {
+	properties := make(map[string]*openapi.Schema, len(names))
+	for _, name := range names {
+		properties[name] = openapi.String()
+	}
+	return openapi.Object(properties, names...)
+}
+
+func specPages(doc *openapi.Document, providers []*auth.Provider) {
+	doc.Add("GET", "/", page("Landing page", "pages"))
+	doc.Add("GET", "/signup", page("Signup page", "auth"))
+	doc.Add("GET", "/login", page("Login page", "auth"))
+	doc.Add("GET", "/logout", page("Asks to confirm logging out", "auth"))
+	doc.Add("POST", "/logout", form(page("Logs out of the current session", "auth"), nil))
+	doc.Add("GET", "/feed", loggedIn(page("Posts of followed users", "posts"), models.ScopeRead))
+	doc.Add("GET", "/feed/more", loggedIn(more("Next feed posts", "posts"), models.ScopeRead))
+
+	for _, provider := range providers {
+		doc.Add("GET", "/auth/signup/"+provider.Name, redirect("Signs up with "+provider.DisplayName, "auth"))
+		doc.Add("GET", "/auth/login/"+provider.Name, redirect("Logs in with "+provider.DisplayName, "auth"))
+		doc.Add("GET", "/auth/"+provider.Name, query(
+			redirect("Callback from "+provider.DisplayName, "auth"),
+			openapi.Query("code", "Authorization code", false),
+			openapi.Query("state", "State sent with the authorization request", true),
+			openapi.Query("error", "Set when the user cancelled", false),
+		))
+		doc.Add("POST", "/user/settings/link/"+provider.Name,
+			loggedIn(form(redirect("Links a "+provider.DisplayName+" login to the account", "settings"), nil), ""))
+	}
+	doc.Add("GET", "/auth/verify", query(
+		page("Verifies the email with a token, or shows the verification status", "auth"),
+		openapi.Query("token", "Token from the verification email", false),
+		openapi.Query("signup", "Set to true right after signing up", false),
+	))
+	doc.Add("GET", "/auth/2fa", page("Two factor login page", "auth"))
+	doc.Add("GET", "/auth/forgot", page("Password reset request page", "auth"))
+	doc.Add("GET", "/auth/reset", query(
+		page("New password page", "auth"),
+		openapi.Query("token", "Token from the reset email", true),
+	))
+	doc.Add("POST", "/auth/signup", form(redirect("Creates an account", "auth"), doc.Form(models.User{})))
+	login := form(redirect("Logs in with a password", "auth"), doc.Form(models.Login{}))
+	login.Responses["429"] = openapi.Response{
+		Description: "Too many failed logins",
+		Headers: map[string]openapi.Header{
+			"Retry-After": {Description: "Seconds until logging in may be tried", Schema: openapi.Integer()},
+		},
+	}
+	doc.Add("POST", "/auth/login", login)
+	doc.Add("POST", "/auth/verify/resend", form(page("Sends the verification email again", "auth"), nil))
+	twoFactor := fields("code", "recovery")
+	twoFactor.Required = nil
+	doc.Add("POST", "/auth/2fa", form(redirect("Completes a login with a code or recovery code", "auth"), twoFactor))
+	doc.Add("POST", "/auth/forgot", form(page("Emails a password reset link", "auth"), fields("email")))
+	doc.Add("POST", "/auth/reset", form(page("Sets a new password", "auth"), fields("token", "password")))
+
+	doc.Add("GET", "/user/:username", page("Profile", "users"))
+	doc.Add("GET", "/user/:username/posts", page("Posts of a user", "users"))
+	doc.Add("GET", "/user/:username/posts/more", more("Next posts of a user", "users"))
+	doc.Add("GET", "/user/", loggedIn(page("Own profile and settings", "settings"), ""))
+	for path, summary := range map[string]string{
+		"avatar":   "Avatar upload page",
+		"username": "Username change page",
+		"password": "Password change page",
+		"2fa":      "Two factor status, or enrollment with a new secret",
+		"sessions": "Devices the account is logged in on",
+		"logins":   "Password and linked providers",
+		"tokens":   "Personal access tokens",
+		"logout":   "Asks to confirm logging out everywhere",
+		"delete":   "Asks to confirm deleting the account",
+	} {
+		doc.Add("GET", "/user/settings/"+path, loggedIn(page(summary, "settings"), ""))
+	}
+	qr := loggedIn(page("QR code of the secret being enrolled", "settings"), "")
+	qr.Responses["200"] = openapi.Respond("QR code", "image/png", openapi.Binary())
+	doc.Add("GET", "/user/settings/2fa/qr", qr)
+	doc.Add("POST", "/user/:username/toggle-follow",
+		loggedIn(form(redirect("Follows or unfollows the user", "users"), nil), models.ScopeWriteFollows))
+	doc.Add("POST", "/user/settings/avatar", loggedIn(form(redirect("Uploads an avatar", "settings"),
+		openapi.Object(map[string]*openapi.Schema{"avatar": openapi.Binary()}, "avatar")), ""))
+	doc.Add("POST", "/user/settings/username", loggedIn(form(redirect("Changes the username", "settings"), fields("username")), ""))
+	doc.Add("POST", "/user/settings/password", loggedIn(form(page("Sets or changes the password", "settings"), fields("password")), ""))
+	doc.Add("POST", "/user/settings/2fa/enable", loggedIn(form(page("Confirms enrollment, showing recovery codes", "settings"), fields("code")), ""))
+	disable := fields("password", "code")
+	disable.Required = nil
+	doc.Add("POST", "/user/settings/2fa/disable", loggedIn(form(page("Turns off two factor authentication", "settings"), disable), ""))
+	doc.Add("POST", "/user/settings/sessions/:id/revoke", loggedIn(form(redirect("Logs a device out", "settings"), nil), ""))
+	doc.Add("POST", "/user/settings/sessions/revoke-others", loggedIn(form(redirect("Logs out every other device", "settings"), nil), ""))
+	doc.Add("POST", "/user/settings/logout", loggedIn(form(page("Logs out everywhere", "settings"), nil), ""))
+	deleteAccount := fields("password")
+	deleteAccount.Required = nil
+	doc.Add("POST", "/user/settings/delete", loggedIn(form(page("Deletes the account", "settings"), deleteAccount), ""))
+	doc.Add("POST", "/user/settings/logins/:id/unlink", loggedIn(form(redirect("Unlinks a provider login", "settings"), nil), ""))
+	doc.Add("POST", "/user/settings/tokens", loggedIn(form(page("Creates a token, showing it once", "settings"),
+		openapi.Object(map[string]*openapi.Schema{
+			"name":   openapi.String(),
+			"scopes": openapi.Array(&openapi.Schema{Type: "string", Enum: models.Scopes}),
+		}, "name", "scopes")), ""))
+	doc.Add("POST", "/user/settings/tokens/:id/revoke", loggedIn(form(redirect("Revokes a token", "settings"), nil), ""))
+
+	doc.Add("GET", "/search/", page("Search page", "users"))
+	doc.Add("GET", "/search/more", more("Next users matching the last search", "users"))
+	doc.Add("POST", "/search/", form(legacyJSON("Users matching the search", "users"), fields("search")))
+	search := loggedIn(form(&openapi.Operation{
+		Summary:   "Follows or unfollows a user from the search page",
+		Tags:      []string{"users"},
+		Responses: map[string]openapi.Response{"200": openapi.Respond("Toggled", "", nil)},
+	}, nil), models.ScopeWriteFollows)
+	doc.Add("POST", "/search/:username/toggle-follow", search)
+
+	doc.Add("GET", "/post/:id", page("Post with its comments", "posts"))
+	doc.Add("GET", "/post/", loggedIn(page("New post page", "posts"), ""))
+	doc.Add("GET", "/post/:id/comments", loggedIn(more("Next comments of a post", "posts"), models.ScopeRead))
+	doc.Add("GET", "/post/:id/edit", loggedIn(page("Edit page of the caller's post", "posts"), ""))
+	newPost := doc.Form(models.Post{})
+	newPost.Properties["images[]"] = openapi.Array(openapi.Binary())
+	newPost.Properties["images[]"].Description = "Up to 4 JPEG, PNG, GIF or WebP images of at most 10 MB each, in order. Metadata such as EXIF locations is removed."
+	newPost.Properties["alt[]"] = openapi.Array(openapi.String())
+	newPost.Properties["alt[]"].Description = "Alt text of the image at the same position"
+	doc.Add("POST", "/post/", loggedIn(form(redirect("Creates a post", "posts"), newPost), models.ScopeWritePosts))
+	doc.Add("POST", "/post/:id/toggle-vote", loggedIn(form(redirect("Votes or removes the vote", "posts"), nil), models.ScopeWritePosts))
+	doc.Add("POST", "/post/:id/edit", loggedIn(form(redirect("Changes the body of the caller's post", "posts"), fields("body")), models.ScopeWritePosts))
+	doc.Add("POST", "/post/:id/delete", loggedIn(form(page("Deletes the post", "posts"), nil), models.ScopeWritePosts))
+	doc.Add("POST", "/post/:id/comment", loggedIn(form(redirect("Comments on the post", "posts"), doc.Form(models.Comment{})), models.ScopeWritePosts))
+	doc.Add("POST", "/post/:id/comment/delete", loggedIn(query(
+		form(redirect("Deletes a comment", "posts"), nil),
+		openapi.Query("commentId", "Comment to delete", true),
+	), models.ScopeWritePosts))
+
+	doc.Add("GET", "/admin/login-attempts", loggedIn(query(
+		page("Failed logins and active lockouts, for admins", "admin"),
+		openapi.Query("page", "Page number, from 1", false),
+	), ""))
+	doc.Add("GET", "/admin/jobs", loggedIn(page("Background jobs waiting, running and dead, for admins", "admin"), ""))
+	doc.Add("POST", "/admin/jobs/:id/retry", loggedIn(form(redirect("Queues a dead job to run again", "admin"), nil), ""))
+	doc.Add("GET", "/admin/quarantine", loggedIn(page("Uploads refused as unusable images with links to them, for admins", "admin"), ""))
+
+	for _, method := range []string{"GET", "HEAD"} {
+		doc.Add(method, "/static/*filepath", &openapi.Operation{
+			Summary: "Serves a file",
+			Tags:    []string{"files"},
+			Responses: map[string]openapi.Response{
+				"200": openapi.Respond("File", "application/octet-stream", openapi.Binary()),
+			},
+		})
+		doc.Add(method, "/uploads/*filepath", &openapi.Operation{
+			Summary: "Serves uploaded media, which needs a signed URL under private/",
+			Tags:    []string{"files"},
+			Parameters: []openapi.Parameter{
+				openapi.Query("expires", "Unix time the signed URL stops working", false),
+				openapi.Query("signature", "Signature of the key and expiry", false),
+			},
+			Responses: map[string]openapi.Response{
+				"200": openapi.Respond("File", "application/octet-stream", openapi.Binary()),
+				"301": openapi.Respond("Redirect to the media in S3", "", nil),
+				"404": openapi.Respond("No such media, or the signature is invalid or expired", "", nil),
+			},
+		})
+	}
+}
+
+// Operation of the JSON API returning the given body
+func endpoint(doc *openapi.Document, summary string, status string, body any) *openapi.Operation {
+	op := &openapi.Operation{
+		Summary:   summary,
+		Tags:      []string{"api"},
+		Responses: map[string]openapi.Response{},
+	}
+	if body == nil {
+		op.Responses[status] = openapi.Respond("Done", "", nil)
+	} else {
+		op.Responses[status] = openapi.Respond("Success", jsonType, doc.JSON(body))
+	}
+	failure := doc.JSON(api.ErrorResponse{})
+	op.Responses["400"] = openapi.Respond("Invalid request", jsonType, failure)
+	op.Responses["404"] = openapi.Respond("Not found", jsonType, failure)
+	return op
+}
+
+// Marks an API operation as needing an access token with the scope
+func scoped(doc *openapi.Document, op *openapi.Operation, scope string) *openapi.Operation {
+	op.Security = []map[string][]string{{"token": {}}}
+	op.Scope = scope
+	failure := doc.JSON(api.ErrorResponse{})
+	op.Responses["401"] = openapi.Respond("Missing or invalid access token", jsonType, failure)
+	op.Responses["403"] = openapi.Respond("Token lacks the scope, or the caller isn't allowed", jsonType, failure)
+	return op
+}
+
+// Adds the cursor and limit parameters of list endpoints
+func paged(op *openapi.Operation) *openapi.Operation {
+	return query(op,
+		openapi.Query("cursor", "nextCursor from the previous page", false),
+		openapi.Parameter{
+			Name:        "limit",
+			In:          "query",
+			Description: "Items per page, 20 by default and at most 100",
+			Schema:      openapi.Integer(),
+		},
+	)
+}
+
+func specAPI(doc *openapi.Document) {
+	doc.Add("GET", "/api/openapi.json", &openapi.Operation{
+		Summary: "This document",
+		Tags:    []string{"meta"},
+		Responses: map[string]openapi.Response{
+			"200": openapi.Respond("OpenAPI document", jsonType, &openapi.Schema{Type: "object"}),
+		},
+	})
+
+	text := openapi.Object(map[string]*openapi.Schema{"body": openapi.String()}, "body")
+	const v1 = "/api/v1"
+	doc.Add("GET", v1+"/me", scoped(doc, endpoint(doc, "Account of the token", "200", api.Item[api.Me]{}), models.ScopeRead))
+	doc.Add("GET", v1+"/feed", scoped(doc, paged(endpoint(doc, "Posts of followed users", "200", api.Page[api.Post]{})), models.ScopeRead))
+	doc.Add("GET", v1+"/search/users", paged(query(
+		endpoint(doc, "Users with the query in their username", "200", api.Page[api.User]{}),
+		openapi.Query("q", "Text to search for", true),
+	)))
+
+	doc.Add("GET", v1+"/users/:username", endpoint(doc, "Profile", "200", api.Item[api.User]{}))
+	doc.Add("GET", v1+"/users/:username/posts", paged(endpoint(doc, "Posts of the user, newest first", "200", api.Page[api.Post]{})))
+	doc.Add("GET", v1+"/users/:username/followers", paged(endpoint(doc, "Followers of the user", "200", api.Page[api.User]{})))
+	doc.Add("GET", v1+"/users/:username/following", paged(endpoint(doc, "Users the user follows", "200", api.Page[api.User]{})))
+	doc.Add("PUT", v1+"/users/:username/follow", scoped(doc, endpoint(doc, "Follows the user", "200", api.Item[api.Follow]{}), models.ScopeWriteFollows))
+	doc.Add("DELETE", v1+"/users/:username/follow", scoped(doc, endpoint(doc, "Unfollows the user", "200", api.Item[api.Follow]{}), models.ScopeWriteFollows))
+
+	create := scoped(doc, endpoint(doc, "Creates a text post", "201", api.Item[api.Post]{}), models.ScopeWritePosts)
+	create.RequestBody = openapi.Body(jsonType, text)
+	doc.Add("POST", v1+"/posts", create)
+	doc.Add("GET", v1+"/posts/:id", endpoint(doc, "Post", "200", api.Item[api.Post]{}))
+	edit := scoped(doc, endpoint(doc, "Changes the body of the caller's post, keeping the old one as a revision", "200", api.Item[api.Post]{}), models.ScopeWritePosts)
+	edit.RequestBody = openapi.Body(jsonType, text)
+	doc.Add("PATCH", v1+"/posts/:id", edit)
+	doc.Add("GET", v1+"/posts/:id/revisions", paged(endpoint(doc, "Earlier bodies of the post, newest first", "200", api.Page[api.Revision]{})))
+	doc.Add("DELETE", v1+"/posts/:id", scoped(doc, endpoint(doc, "Deletes the caller's post", "204", nil), models.ScopeWritePosts))
+	doc.Add("PUT", v1+"/posts/:id/vote", scoped(doc, endpoint(doc, "Votes for the post", "200", api.Item[api.Vote]{}), models.ScopeWritePosts))
+	doc.Add("DELETE", v1+"/posts/:id/vote", scoped(doc, endpoint(doc, "Removes the vote", "200", api.Item[api.Vote]{}), models.ScopeWritePosts))
+	doc.Add("GET", v1+"/posts/:id/comments", paged(endpoint(doc, "Comments on the post, newest first", "200", api.Page[api.Comment]{})))
+	comment := scoped(doc, endpoint(doc, "Comments on the post", "201", api.Item[api.Comment]{}), models.ScopeWritePosts)
+	comment.RequestBody = openapi.Body(jsonType, text)
+	doc.Add("POST", v1+"/posts/:id/comments", comment)
+	doc.Add("DELETE", v1+"/posts/:id/comments/:commentId", scoped(doc, endpoint(doc, "Deletes the caller's comment", "204", nil), models.ScopeWritePosts))
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Bhar8at/bhar8at.github.io/api"
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	socials "github.com/Bhar8at/bhar8at.github.io/internal/auth"
+	"github.com/Bhar8at/bhar8at.github.io/internal/jobs"
+	"github.com/Bhar8at/bhar8at.github.io/internal/media"
+	"github.com/Bhar8at/bhar8at.github.io/routes"
+	"github.com/gin-gonic/gin"
+)
+
+func TestSpecDescribesEveryRoute(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := database.NewMemory()
+	// Providers add routes of their own, so one is configured
+	providers := []*socials.Provider{{ProviderConfig: socials.ProviderConfig{Name: "example"}}}
+	app := gin.New()
+	if err := registerRoutes(app, services{
+		store:     db,
+		handler:   routes.NewHandler(routes.Config{Store: db, Providers: providers, Queue: jobs.New(db)}),
+		oauth:     socials.NewHandler(db, nil, "http://localhost:8080"),
+		v1:        api.NewHandler(api.Config{Store: db}),
+		providers: providers,
+		media:     &media.Local{Dir: t.TempDir(), BaseURL: "http://localhost:8080/uploads"},
+		secret:    []byte("secret"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	missing, stale := buildSpec(providers).Check(app.Routes())
+	for _, route := range missing {
+		t.Errorf("route %s is not described in openapi.go", route)
+	}
+	for _, route := range stale {
+		t.Errorf("openapi.go describes %s which is not registered", route)
+	}
+}
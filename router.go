@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Bhar8at/bhar8at.github.io/api"
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	socials "github.com/Bhar8at/bhar8at.github.io/internal/auth"
+	"github.com/Bhar8at/bhar8at.github.io/internal/media"
+	"github.com/Bhar8at/bhar8at.github.io/middleware"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/Bhar8at/bhar8at.github.io/routes"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+)
+
+// What the routes are served with, built from the environment in main
+type services struct {
+	store     database.Store
+	handler   *routes.Handler
+	oauth     *socials.Handler
+	v1        *api.Handler
+	providers []*socials.Provider
+	media     media.Store
+	// Signs the cookie sessions
+	secret []byte
+	// Posting is limited to verified users when set
+	requireVerified bool
+}
+
+// Registers the middleware and every route of the app, each of which
+// openapi.go has to describe
+func registerRoutes(app *gin.Engine, s services) error {
+	// Posting is limited to verified users when REQUIRE_VERIFIED is set
+	posting := func(c *gin.Context) { c.Next() }
+	if s.requireVerified {
+		posting = middleware.VerifiedMiddleware(s.store)
+	}
+
+	// Redirects all URL's with trailing slash to the same URL without the trailing slash
+	app.RedirectTrailingSlash = true
+
+	// Makes it so that unavailable methods do not go ignored (405 status code returned)
+	app.HandleMethodNotAllowed = true
+
+	// if route doesn't match any that's given
+	app.NoRoute(notFound)
+	app.NoMethod(func(c *gin.Context) {
+		if api.IsAPIRequest(c) {
+			api.MethodNotAllowed(c)
+		}
+	})
+
+	app.Static("/static", "./static")
+	app.GET("/uploads/*filepath", media.Serve(s.media))
+	app.HEAD("/uploads/*filepath", media.Serve(s.media))
+
+	// Storing the current session in a cookie
+	store := cookie.NewStore(s.secret)
+	app.Use(sessions.Sessions("cookie", store))
+
+	// Limits uploads before the CSRF check reads the form
+	app.Use(middleware.BodyLimitMiddleware(routes.MaxRequestSize))
+
+	// Checks the CSRF token of every form submission
+	app.Use(middleware.CSRFMiddleware())
+
+	// used to Recover from unexpected errors during handling of HTTP requests
+	app.Use(middleware.RecoveryMiddleware())
+
+	// Routes
+
+	// Basic routes
+	app.GET("/", index)
+	app.GET("/signup", s.handler.SignUp)
+	app.GET("/login", s.handler.Login)
+	app.GET("/logout", s.handler.Logout)
+	app.POST("/logout", s.handler.Logout)
+	app.GET("/feed", middleware.AuthMiddleware(s.store), middleware.TwoFactorMiddleware(s.store), middleware.Scope(models.ScopeRead), s.handler.UserFeed)
+	app.GET("/feed/more", middleware.AuthMiddleware(s.store), middleware.TwoFactorMiddleware(s.store), middleware.Scope(models.ScopeRead), s.handler.LoadMoreFeed)
+
+	// Authentication related routes
+	auth := app.Group("/auth")
+	{
+		// Sign in with each configured OAuth provider
+		for _, provider := range s.providers {
+			auth.GET("/signup/"+provider.Name, s.oauth.SignUp(provider))
+			auth.GET("/login/"+provider.Name, s.oauth.Login(provider))
+			auth.GET("/"+provider.Name, s.oauth.Callback(provider))
+		}
+
+		auth.GET("/verify", s.handler.Verify)
+		auth.GET("/2fa", s.handler.TwoFactorLogin)
+		auth.GET("/forgot", s.handler.ForgotPassword)
+		auth.GET("/reset", s.handler.ResetPassword)
+
+		auth.POST("/signup", s.handler.SignUp)
+		auth.POST("/login", s.handler.Login)
+		auth.POST("/verify/resend", s.handler.ResendVerification)
+		auth.POST("/2fa", s.handler.TwoFactorLogin)
+		auth.POST("/forgot", s.handler.ForgotPassword)
+		auth.POST("/reset", s.handler.ResetPassword)
+
+	}
+
+	user := app.Group("/user")
+	user.GET("/:username", s.handler.GetUserByName)
+	user.GET("/:username/posts", s.handler.GetUserPosts)
+	user.GET("/:username/posts/more", s.handler.LoadMorePosts)
+	user.Use(middleware.AuthMiddleware(s.store), middleware.TwoFactorMiddleware(s.store))
+	{
+		user.GET("/", s.handler.GetUser)
+		user.GET("/settings/avatar", s.handler.UpdateAvatar)
+		user.GET("/settings/username", s.handler.UpdateUsername)
+		user.GET("/settings/password", s.handler.UpdatePassword)
+		user.GET("/settings/2fa", s.handler.TwoFactorSettings)
+		user.GET("/settings/2fa/qr", s.handler.TwoFactorQR)
+		user.GET("/settings/sessions", s.handler.GetSessions)
+		user.GET("/settings/logins", s.handler.GetLogins)
+		user.GET("/settings/tokens", s.handler.GetAccessTokens)
+		user.GET("/settings/logout", s.handler.LogoutEverywhere)
+		user.GET("/settings/delete", s.handler.DeleteUser)
+
+		user.POST("/:username/toggle-follow", middleware.Scope(models.ScopeWriteFollows), s.handler.ToggleFollow)
+		user.POST("/settings/avatar", s.handler.UpdateAvatar)
+		user.POST("/settings/username", s.handler.UpdateUsername)
+		user.POST("/settings/password", s.handler.UpdatePassword)
+		user.POST("/settings/2fa/enable", s.handler.EnableTwoFactor)
+		user.POST("/settings/2fa/disable", s.handler.DisableTwoFactor)
+		user.POST("/settings/sessions/:id/revoke", s.handler.RevokeSession)
+		user.POST("/settings/sessions/revoke-others", s.handler.RevokeOtherSessions)
+		user.POST("/settings/logout", s.handler.LogoutEverywhere)
+		user.POST("/settings/delete", s.handler.DeleteUser)
+		user.POST("/settings/logins/:id/unlink", s.handler.UnlinkLogin)
+		user.POST("/settings/tokens", s.handler.CreateAccessToken)
+		user.POST("/settings/tokens/:id/revoke", s.handler.RevokeAccessToken)
+		for _, provider := range s.providers {
+			user.POST("/settings/link/"+provider.Name, s.oauth.Link(provider))
+		}
+	}
+
+	search := app.Group("/search")
+	{
+		search.GET("/", s.handler.SearchUser)
+		search.GET("/more", s.handler.LoadMoreUsers)
+
+		search.POST("/", s.handler.SearchUser)
+		search.POST("/:username/toggle-follow", middleware.AuthMiddleware(s.store), middleware.TwoFactorMiddleware(s.store), middleware.Scope(models.ScopeWriteFollows), s.handler.ToggleSearchFollow)
+	}
+
+	// CRUD functionality for posts
+	post := app.Group("/post")
+	post.GET("/:id", s.handler.GetPost)
+	post.Use(middleware.AuthMiddleware(s.store), middleware.TwoFactorMiddleware(s.store))
+	{
+		post.GET("/", posting, s.handler.NewPost)
+		post.GET("/:id/comments", middleware.Scope(models.ScopeRead), s.handler.LoadMoreComments)
+		post.GET("/:id/edit", s.handler.EditPost)
+
+		post.POST("/", middleware.Scope(models.ScopeWritePosts), posting, s.handler.NewPost)
+		post.POST("/:id/toggle-vote", middleware.Scope(models.ScopeWritePosts), s.handler.ToggleVote)
+		post.POST("/:id/edit", middleware.Scope(models.ScopeWritePosts), s.handler.EditPost)
+		post.POST("/:id/delete", middleware.Scope(models.ScopeWritePosts), s.handler.DeletePost)
+		post.POST("/:id/comment", middleware.Scope(models.ScopeWritePosts), posting, s.handler.Comment)
+		post.POST("/:id/comment/delete", middleware.Scope(models.ScopeWritePosts), s.handler.DeleteComment)
+	}
+
+	admin := app.Group("/admin")
+	admin.Use(middleware.AuthMiddleware(s.store), middleware.TwoFactorMiddleware(s.store), middleware.AdminMiddleware(s.store))
+	{
+		admin.GET("/login-attempts", s.handler.LoginAttempts)
+		admin.GET("/jobs", s.handler.Jobs)
+		admin.GET("/quarantine", s.handler.Quarantine)
+		admin.POST("/jobs/:id/retry", s.handler.RetryJob)
+	}
+
+	// Machine readable description of every route
+	spec, err := json.Marshal(buildSpec(s.providers))
+	if err != nil {
+		return err
+	}
+	app.GET("/api/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", spec)
+	})
+
+	// JSON API for mobile and command line clients
+	apiV1 := app.Group("/api/v1")
+	apiV1.Use(s.v1.Authenticate)
+	{
+		apiV1.GET("/me", api.Require(models.ScopeRead), s.v1.GetMe)
+		apiV1.GET("/feed", api.Require(models.ScopeRead), s.v1.GetFeed)
+		apiV1.GET("/search/users", s.v1.SearchUsers)
+
+		apiV1.GET("/users/:username", s.v1.GetUser)
+		apiV1.GET("/users/:username/posts", s.v1.GetUserPosts)
+		apiV1.GET("/users/:username/followers", s.v1.GetFollowers)
+		apiV1.GET("/users/:username/following", s.v1.GetFollowing)
+		apiV1.PUT("/users/:username/follow", api.Require(models.ScopeWriteFollows), s.v1.Follow)
+		apiV1.DELETE("/users/:username/follow", api.Require(models.ScopeWriteFollows), s.v1.Unfollow)
+
+		apiV1.POST("/posts", api.Require(models.ScopeWritePosts), s.v1.CreatePost)
+		apiV1.GET("/posts/:id", s.v1.GetPost)
+		apiV1.PATCH("/posts/:id", api.Require(models.ScopeWritePosts), s.v1.EditPost)
+		apiV1.DELETE("/posts/:id", api.Require(models.ScopeWritePosts), s.v1.DeletePost)
+		apiV1.GET("/posts/:id/revisions", s.v1.GetRevisions)
+		apiV1.PUT("/posts/:id/vote", api.Require(models.ScopeWritePosts), s.v1.Vote)
+		apiV1.DELETE("/posts/:id/vote", api.Require(models.ScopeWritePosts), s.v1.Unvote)
+		apiV1.GET("/posts/:id/comments", s.v1.GetComments)
+		apiV1.POST("/posts/:id/comments", api.Require(models.ScopeWritePosts), s.v1.CreateComment)
+		apiV1.DELETE("/posts/:id/comments/:commentId", api.Require(models.ScopeWritePosts), s.v1.DeleteComment)
+	}
+
+	return nil
+}
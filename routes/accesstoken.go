@@ -0,0 +1,116 @@
+package routes
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/middleware"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const maxTokenName = 64
+
+// Lists the user's personal access tokens, along with a newly created
+// token which is only ever shown once
+func (h *Handler) renderAccessTokens(c *gin.Context, status int, userId string, created string) {
+	c.HTML(status, "tokensT.html", gin.H{
+		"csrf":    middleware.CSRFToken(c),
+		"tokens":  h.store.ReadAccessTokens(userId),
+		"scopes":  models.Scopes,
+		"created": created,
+	})
+}
+
+func (h *Handler) GetAccessTokens(c *gin.Context) {
+	session := sessions.Default(c)
+	id := session.Get("userId")
+	if id == nil {
+		c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+			"error":   "401 Unauthorized",
+			"message": "User not logged in.",
+		})
+		return
+	}
+	h.renderAccessTokens(c, http.StatusOK, id.(string), "")
+}
+
+func (h *Handler) CreateAccessToken(c *gin.Context) {
+	session := sessions.Default(c)
+	id := session.Get("userId")
+	if id == nil {
+		c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+			"error":   "401 Unauthorized",
+			"message": "User not logged in.",
+		})
+		return
+	}
+	name := strings.TrimSpace(c.PostForm("name"))
+	if name == "" || len(name) > maxTokenName {
+		c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
+			"error":   "400 Bad Request",
+			"message": "Token name must be between 1 and 64 characters.",
+		})
+		return
+	}
+	var scopes []string
+	chosen := c.PostFormArray("scopes")
+	for _, scope := range models.Scopes {
+		for _, value := range chosen {
+			if value == scope {
+				scopes = append(scopes, scope)
+				break
+			}
+		}
+	}
+	if len(scopes) == 0 {
+		c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
+			"error":   "400 Bad Request",
+			"message": "Choose at least one scope.",
+		})
+		return
+	}
+	token, hash, err := middleware.GenerateAccessToken()
+	if err != nil || !h.store.CreateAccessToken(&models.AccessToken{
+		Id:        uuid.NewString(),
+		UserId:    id.(string),
+		Name:      name,
+		Hash:      hash,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}) {
+		if err != nil {
+			log.Println(err)
+		}
+		c.HTML(http.StatusInternalServerError, "errorT.html", gin.H{
+			"error":   "500 Internal Server Error",
+			"message": "Unable to create token, try again later.",
+		})
+		return
+	}
+	h.renderAccessTokens(c, http.StatusCreated, id.(string), token)
+}
+
+func (h *Handler) RevokeAccessToken(c *gin.Context) {
+	session := sessions.Default(c)
+	id := session.Get("userId")
+	if id == nil {
+		c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+			"error":   "401 Unauthorized",
+			"message": "User not logged in.",
+		})
+		return
+	}
+	if result := h.store.DeleteAccessToken(id.(string), c.Param("id")); !result {
+		c.HTML(http.StatusNotFound, "errorT.html", gin.H{
+			"error":   "404 Not Found",
+			"message": "Token not found.",
+		})
+		return
+	}
+	c.Redirect(http.StatusFound, "/user/settings/tokens")
+}
@@ -0,0 +1,80 @@
+package routes
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/middleware"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/gin-gonic/gin"
+)
+
+const loginAttemptsLimit = 50
+
+// Log of failed logins and the accounts and IPs currently locked out
+func (h *Handler) LoginAttempts(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	attempts := h.store.ReadFailedLoginAttempts(loginAttemptsLimit, (page-1)*loginAttemptsLimit)
+	c.HTML(http.StatusOK, "attemptsT.html", gin.H{
+		"attempts": attempts,
+		"lockouts": h.store.ReadLockouts(),
+		"page":     page,
+		"previous": page - 1,
+		"next":     page + 1,
+		"more":     len(attempts) == loginAttemptsLimit,
+	})
+}
+
+const jobsLimit = 50
+
+// Jobs waiting or running, and those that ran out of attempts
+func (h *Handler) Jobs(c *gin.Context) {
+	c.HTML(http.StatusOK, "jobsT.html", gin.H{
+		"csrf":     middleware.CSRFToken(c),
+		"jobs":     h.store.ReadJobs(jobsLimit),
+		"deadJobs": h.store.ReadDeadJobs(jobsLimit),
+	})
+}
+
+// Queues a dead job to run again with fresh attempts
+func (h *Handler) RetryJob(c *gin.Context) {
+	if !h.store.RetryDeadJob(c.Param("id"), time.Now()) {
+		c.HTML(http.StatusNotFound, "errorT.html", gin.H{
+			"error":   "404 Not Found",
+			"message": "Job not found.",
+		})
+		return
+	}
+	c.Redirect(http.StatusFound, "/admin/jobs")
+}
+
+const (
+	quarantineLimit = 50
+	// How long the links to quarantined files work
+	quarantineLinkTTL = 15 * time.Minute
+)
+
+// Uploads refused as unusable images, each linked through a signed URL
+// as they're kept privately
+func (h *Handler) Quarantine(c *gin.Context) {
+	type quarantined struct {
+		models.QuarantinedUpload
+		URL string
+	}
+	var uploads []quarantined
+	for _, upload := range h.store.ReadQuarantinedUploads(quarantineLimit) {
+		url, err := h.media.SignedURL(upload.Key, quarantineLinkTTL)
+		if err != nil {
+			log.Println(err)
+		}
+		uploads = append(uploads, quarantined{QuarantinedUpload: upload, URL: url})
+	}
+	c.HTML(http.StatusOK, "quarantineT.html", gin.H{
+		"uploads": uploads,
+	})
+}
@@ -1,11 +1,14 @@
 package routes
 
 import (
+	"fmt"
+	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
-	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/internal/throttle"
 	"github.com/Bhar8at/bhar8at.github.io/middleware"
 	"github.com/Bhar8at/bhar8at.github.io/models"
 	"github.com/gin-contrib/sessions"
@@ -27,11 +30,13 @@ func init() {
 	secretKey = []byte(os.Getenv("SECRET_KEY"))
 }
 
-func SignUp(c *gin.Context) {
+func (h *Handler) SignUp(c *gin.Context) {
 	switch c.Request.Method {
 	case "GET":
 		c.HTML(http.StatusOK, "authT.html", gin.H{
-			"type": "signup",
+			"csrf":      middleware.CSRFToken(c),
+			"type":      "signup",
+			"providers": h.providers,
 		})
 	case "POST":
 		var user models.User // creating a user
@@ -54,7 +59,7 @@ func SignUp(c *gin.Context) {
 		}
 
 		// Checking whether the current user is present in database or not
-		if user := database.ReadUserByName(user.Username); user != nil {
+		if user := h.store.ReadUserByName(user.Username); user != nil {
 			c.HTML(http.StatusForbidden, "errorT.html", gin.H{
 				"error":   "403 Forbidden",
 				"message": "Account already exists with the given username.",
@@ -67,7 +72,7 @@ func SignUp(c *gin.Context) {
 		user.HashPassword()
 
 		// storing user data in database
-		if res := database.CreateUser(&user); !res {
+		if res := h.store.CreateUser(&user); !res {
 			// error returned since email is the unique key
 			c.HTML(http.StatusForbidden, "errorT.html", gin.H{
 				"error":   "403 Forbidden",
@@ -76,21 +81,28 @@ func SignUp(c *gin.Context) {
 			return
 		}
 
+		// Email a link to verify the account
+		if err := h.verifier.Send(&user); err != nil {
+			log.Println(err)
+		}
+
 		// Set authorization token for user
-		token, _ := middleware.CreateToken(user.Id)
+		token, _ := middleware.CreateToken(c, h.store, user.Id)
 		// initializes a session for the current user
 		session := sessions.Default(c)
 		session.Set("Authorization", token)
 		session.Save()
-		c.Redirect(http.StatusFound, "/login")
+		c.Redirect(http.StatusFound, "/auth/verify?signup=true")
 	}
 }
 
-func Login(c *gin.Context) {
+func (h *Handler) Login(c *gin.Context) {
 	switch c.Request.Method {
 	case "GET":
 		c.HTML(http.StatusOK, "authT.html", gin.H{
-			"type": "login",
+			"csrf":      middleware.CSRFToken(c),
+			"type":      "login",
+			"providers": h.providers,
 		})
 	case "POST":
 		var login models.Login
@@ -108,8 +120,18 @@ func Login(c *gin.Context) {
 			})
 			return
 		}
-		user := database.ReadUserByName(login.Username)
+		// Refuse guesses while the account or IP is locked out
+		if wait := h.throttle.Wait(login.Username, c.ClientIP()); wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			c.HTML(http.StatusTooManyRequests, "errorT.html", gin.H{
+				"error":   "429 Too Many Requests",
+				"message": fmt.Sprintf("Too many failed login attempts, try again in %s.", throttle.Describe(wait)),
+			})
+			return
+		}
+		user := h.store.ReadUserByName(login.Username)
 		if user == nil {
+			h.throttle.Failed(login.Username, c.ClientIP(), c.Request.UserAgent())
 			c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
 				"error":   "401 Unauthorized",
 				"message": "User does not exist.",
@@ -118,23 +140,63 @@ func Login(c *gin.Context) {
 		}
 		// Checking if the passwords match
 		if !user.CheckPassword(login.Password) {
+			h.throttle.Failed(login.Username, c.ClientIP(), c.Request.UserAgent())
 			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
 				"error":   "401 Unauthorized",
 				"message": "Incorrect password.",
 			})
 			return
 		}
+		// The failures are only cleared once the login is complete, which
+		// with two factor authentication is after the code
+		if twoFactor := h.store.ReadTwoFactor(user.Id); twoFactor == nil || !twoFactor.Enabled {
+			h.throttle.Succeeded(login.Username, c.ClientIP(), c.Request.UserAgent())
+		}
 
-		// initializing token
-		token, _ := middleware.CreateToken(user.Id)
-		session := sessions.Default(c)
-		session.Set("Authorization", token)
+		// initializing token, or asking for a code first with two factor auth
+		next, err := middleware.Login(c, h.store, user.Id)
+		if err != nil {
+			log.Println(err)
+			c.HTML(http.StatusInternalServerError, "errorT.html", gin.H{
+				"error":   "500 Internal Server Error",
+				"message": "Unable to login, try again later.",
+			})
+			return
+		}
+		c.Redirect(http.StatusFound, next)
+	}
+}
+
+func (h *Handler) Logout(c *gin.Context) {
+	session := sessions.Default(c)
+	token, ok := session.Get("Authorization").(string)
+	if !ok {
+		c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+			"error":   "401 Unauthorized",
+			"message": "User not logged in.",
+		})
+		return
+	}
+	switch c.Request.Method {
+	case "GET":
+		c.HTML(http.StatusOK, "logoutT.html", gin.H{
+			"csrf": middleware.CSRFToken(c),
+		})
+	case "POST":
+		// End the session on the server so its token can't be reused
+		middleware.RevokeToken(h.store, token)
+		// Remove all session headers
+		session.Clear()
+		session.Options(sessions.Options{MaxAge: -1})
 		session.Save()
-		c.Redirect(http.StatusFound, "/feed")
+		c.HTML(http.StatusOK, "responseT.html", gin.H{
+			"message": "Logged out successfully.",
+		})
 	}
 }
 
-func Logout(c *gin.Context) {
+// Ends every session of the user, including the current one
+func (h *Handler) LogoutEverywhere(c *gin.Context) {
 	session := sessions.Default(c)
 	id := session.Get("userId")
 	if id == nil {
@@ -144,11 +206,25 @@ func Logout(c *gin.Context) {
 		})
 		return
 	}
-	// Remove all session headers
-	session.Clear()
-	session.Options(sessions.Options{MaxAge: -1})
-	session.Save()
-	c.HTML(http.StatusOK, "responseT.html", gin.H{
-		"message": "Logged out successfully.",
-	})
+	switch c.Request.Method {
+	case "GET":
+		c.HTML(http.StatusOK, "logoutT.html", gin.H{
+			"csrf":       middleware.CSRFToken(c),
+			"everywhere": true,
+		})
+	case "POST":
+		if result := h.store.DeleteSessions(id.(string)); !result {
+			c.HTML(http.StatusInternalServerError, "errorT.html", gin.H{
+				"error":   "500 Internal Server Error",
+				"message": "Unable to log out, try again later.",
+			})
+			return
+		}
+		session.Clear()
+		session.Options(sessions.Options{MaxAge: -1})
+		session.Save()
+		c.HTML(http.StatusOK, "responseT.html", gin.H{
+			"message": "Logged out of all devices successfully.",
+		})
+	}
 }
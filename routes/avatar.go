@@ -0,0 +1,81 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/Bhar8at/bhar8at.github.io/internal/imaging"
+	"github.com/Bhar8at/bhar8at.github.io/internal/jobs"
+	"github.com/Bhar8at/bhar8at.github.io/internal/media"
+	"github.com/google/uuid"
+)
+
+const kindAvatar = "avatar.upload"
+
+// The upload itself waits in the media store under Key, so the job's
+// payload, which admins can see, holds no image data
+type avatarUpload struct {
+	UserId string `json:"userId"`
+	Key    string `json:"key"`
+}
+
+// Keeps an uploaded avatar where only signed URLs reach it until the job
+// processes it, returning the key
+func (h *Handler) stageAvatar(ctx context.Context, data []byte) (string, error) {
+	key := media.PrivateKey("uploads/" + uuid.NewString())
+	if err := h.media.Put(ctx, key, data, "application/octet-stream"); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Saves a new avatar to the media store and sets it on the user. Avatars
+// are only shown small, so just the thumbnail is kept.
+func (h *Handler) uploadAvatar(ctx context.Context, payload []byte) error {
+	var upload avatarUpload
+	if err := json.Unmarshal(payload, &upload); err != nil {
+		return jobs.Permanent(err)
+	}
+	data, err := h.media.Get(ctx, upload.Key)
+	if err != nil {
+		return err
+	}
+	processed, err := imaging.Process(data)
+	if err != nil {
+		// Processing the same image again would fail the same way
+		h.quarantine(ctx, upload.UserId, upload.Key, data, err)
+		return jobs.Permanent(err)
+	}
+	rendition := &processed.Original
+	if processed.Thumbnail != nil {
+		rendition = processed.Thumbnail
+	}
+	url, err := h.saveRendition(ctx, rendition)
+	if err != nil {
+		return err
+	}
+	if !h.store.UpdateUser(upload.UserId, map[string]any{"avatar": url}) {
+		return errors.New("unable to update avatar")
+	}
+	h.unstageAvatar(ctx, upload.Key)
+	return nil
+}
+
+// Deletes the staged upload of a job that failed for good, which would
+// otherwise be left in the media store
+func (h *Handler) avatarBuried(ctx context.Context, payload []byte) {
+	var upload avatarUpload
+	if err := json.Unmarshal(payload, &upload); err != nil {
+		log.Println(err)
+		return
+	}
+	h.unstageAvatar(ctx, upload.Key)
+}
+
+func (h *Handler) unstageAvatar(ctx context.Context, key string) {
+	if err := h.media.Delete(ctx, key); err != nil {
+		log.Println(err)
+	}
+}
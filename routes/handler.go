@@ -0,0 +1,57 @@
+package routes
+
+import (
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/internal/auth"
+	"github.com/Bhar8at/bhar8at.github.io/internal/jobs"
+	"github.com/Bhar8at/bhar8at.github.io/internal/media"
+	"github.com/Bhar8at/bhar8at.github.io/internal/reset"
+	"github.com/Bhar8at/bhar8at.github.io/internal/throttle"
+	"github.com/Bhar8at/bhar8at.github.io/internal/timeline"
+	"github.com/Bhar8at/bhar8at.github.io/internal/verify"
+)
+
+// Handler holds the dependencies shared by the route handlers
+type Handler struct {
+	store     database.Store
+	verifier  *verify.Verifier
+	resetter  *reset.Resetter
+	providers []*auth.Provider
+	throttle  *throttle.Throttle
+	timeline  *timeline.Timeline
+	queue     *jobs.Queue
+	media     media.Store
+}
+
+type Config struct {
+	Store database.Store
+	// Sends email verification links to new users
+	Verifier *verify.Verifier
+	// Sends password reset links
+	Resetter *reset.Resetter
+	// OAuth providers offered on the signup and login pages
+	Providers []*auth.Provider
+	// Locks out repeated failed logins
+	Throttle *throttle.Throttle
+	// Pushes new posts and follows to home timelines
+	Timeline *timeline.Timeline
+	// Runs slow work such as avatar uploads in the background
+	Queue *jobs.Queue
+	// Keeps uploaded images and avatars
+	Media media.Store
+}
+
+func NewHandler(config Config) *Handler {
+	h := &Handler{
+		store:     config.Store,
+		verifier:  config.Verifier,
+		resetter:  config.Resetter,
+		providers: config.Providers,
+		throttle:  config.Throttle,
+		timeline:  config.Timeline,
+		queue:     config.Queue,
+		media:     config.Media,
+	}
+	config.Queue.Register(kindAvatar, jobs.Kind{Run: h.uploadAvatar, Buried: h.avatarBuried, Concurrency: 2})
+	return h
+}
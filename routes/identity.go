@@ -0,0 +1,73 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Bhar8at/bhar8at.github.io/middleware"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// Lists the ways the user can login and the providers they can link
+func (h *Handler) GetLogins(c *gin.Context) {
+	session := sessions.Default(c)
+	id := session.Get("userId")
+	if id == nil {
+		c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+			"error":   "401 Unauthorized",
+			"message": "User not logged in.",
+		})
+		return
+	}
+	names := make(map[string]string)
+	for _, provider := range h.providers {
+		names[provider.Name] = provider.DisplayName
+	}
+	var linked []gin.H
+	for _, identity := range h.store.ReadIdentities(id.(string)) {
+		name, ok := names[identity.Provider]
+		// Logins with providers that were since removed can still be unlinked
+		if !ok {
+			name = identity.Provider
+		}
+		linked = append(linked, gin.H{
+			"identity": identity,
+			"provider": name,
+		})
+	}
+	c.HTML(http.StatusOK, "loginsT.html", gin.H{
+		"csrf":      middleware.CSRFToken(c),
+		"password":  !h.store.IsOAuthUser(id.(string)),
+		"linked":    linked,
+		"providers": h.providers,
+	})
+}
+
+func (h *Handler) UnlinkLogin(c *gin.Context) {
+	session := sessions.Default(c)
+	id := session.Get("userId")
+	if id == nil {
+		c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+			"error":   "401 Unauthorized",
+			"message": "User not logged in.",
+		})
+		return
+	}
+	userId := id.(string)
+	// Users without a password would be locked out by removing their last login
+	if h.store.IsOAuthUser(userId) && len(h.store.ReadIdentities(userId)) <= 1 {
+		c.HTML(http.StatusForbidden, "errorT.html", gin.H{
+			"error":   "403 Forbidden",
+			"message": "Set a password before unlinking your only login.",
+		})
+		return
+	}
+	if result := h.store.DeleteIdentity(userId, c.Param("id")); !result {
+		c.HTML(http.StatusNotFound, "errorT.html", gin.H{
+			"error":   "404 Not Found",
+			"message": "Login not found.",
+		})
+		return
+	}
+	c.Redirect(http.StatusFound, "/user/settings/logins")
+}
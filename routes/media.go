@@ -0,0 +1,175 @@
+package routes
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Bhar8at/bhar8at.github.io/internal/imaging"
+	"github.com/Bhar8at/bhar8at.github.io/internal/media"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/google/uuid"
+)
+
+const (
+	// Images a post can have
+	maxMedia         = 4
+	maxAltTextLength = 1000
+	// Largest request body accepted, enough for a post with every image
+	// at the largest size
+	MaxRequestSize = maxMedia*imaging.MaxBytes + 1<<20
+)
+
+// Numbers of the images a post can have, from 1, for the alt text fields
+func mediaSlots() []int {
+	slots := make([]int, maxMedia)
+	for index := range slots {
+		slots[index] = index + 1
+	}
+	return slots
+}
+
+type upload struct {
+	processed *imaging.Result
+	media     models.PostMedia
+}
+
+// Saves the "images[]" uploaded with a new post, in order, each described by
+// the "alt[]" text at the same position. Every image is processed before
+// any is saved so nothing is stored unless all of them can be used.
+func (h *Handler) saveMedia(ctx context.Context, userId string, form *multipart.Form) ([]models.PostMedia, error) {
+	if form == nil {
+		return nil, nil
+	}
+	files := form.File["images[]"]
+	if len(files) > maxMedia {
+		return nil, fmt.Errorf("Posts can have at most %d images.", maxMedia)
+	}
+	alts := form.Value["alt[]"]
+	var uploads []upload
+	for index, header := range files {
+		var alt string
+		if index < len(alts) {
+			alt = strings.TrimSpace(alts[index])
+		}
+		if utf8.RuneCountInString(alt) > maxAltTextLength {
+			return nil, fmt.Errorf("Alt text can be at most %d characters.", maxAltTextLength)
+		}
+		data, err := readUpload(header)
+		if err != nil {
+			return nil, err
+		}
+		processed, err := imaging.Process(data)
+		if err != nil {
+			h.quarantine(ctx, userId, header.Filename, data, err)
+			return nil, errors.New(imageError(err))
+		}
+		uploads = append(uploads, upload{processed: processed, media: models.PostMedia{
+			Id:       uuid.NewString(),
+			Blurhash: processed.Blurhash,
+			AltText:  alt,
+			Width:    processed.Original.Width,
+			Height:   processed.Original.Height,
+		}})
+	}
+	var media []models.PostMedia
+	for _, upload := range uploads {
+		var err error
+		upload.media.URL, err = h.saveRendition(ctx, &upload.processed.Original)
+		upload.media.MediumURL, upload.media.ThumbnailURL = upload.media.URL, upload.media.URL
+		if err == nil && upload.processed.Medium != nil {
+			upload.media.MediumURL, err = h.saveRendition(ctx, upload.processed.Medium)
+			upload.media.ThumbnailURL = upload.media.MediumURL
+		}
+		if err == nil && upload.processed.Thumbnail != nil {
+			upload.media.ThumbnailURL, err = h.saveRendition(ctx, upload.processed.Thumbnail)
+		}
+		if err != nil {
+			log.Println(err)
+			return nil, errors.New("Unable to save image, try again later.")
+		}
+		media = append(media, upload.media)
+	}
+	return media, nil
+}
+
+// Stores the rendition under the hash of its content with its format's
+// extension and returns its URL, so identical images share one file. It's
+// recorded before being written, which keeps the garbage collector off it
+// until whatever uses it has been saved.
+func (h *Handler) saveRendition(ctx context.Context, rendition *imaging.Rendition) (string, error) {
+	sum := sha256.Sum256(rendition.Data)
+	key := "media/" + hex.EncodeToString(sum[:]) + "." + rendition.Format
+	if !h.store.TouchMediaObject(&models.MediaObject{
+		Key:       key,
+		Size:      int64(len(rendition.Data)),
+		TouchedAt: time.Now(),
+	}) {
+		return "", errors.New("unable to record media " + key)
+	}
+	// Written again even when already there, in case it was collected just
+	// before being touched
+	if err := h.media.Put(ctx, key, rendition.Data, "image/"+rendition.Format); err != nil {
+		return "", err
+	}
+	return h.media.URL(key), nil
+}
+
+// Reads an uploaded file, refusing any too large to be an image without
+// reading it
+func readUpload(header *multipart.FileHeader) ([]byte, error) {
+	if header.Size > imaging.MaxBytes {
+		return nil, errors.New(imageError(imaging.ErrTooLarge))
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, errors.New("Unable to read image data.")
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, errors.New("Unable to read image data.")
+	}
+	return data, nil
+}
+
+// Message for an image that can't be used
+func imageError(err error) string {
+	switch {
+	case errors.Is(err, imaging.ErrTooLarge):
+		return fmt.Sprintf("Images can be at most %d MB.", imaging.MaxBytes>>20)
+	case errors.Is(err, imaging.ErrDimensions):
+		return fmt.Sprintf("Images can be at most %d pixels across and %d megapixels.",
+			imaging.MaxSide, imaging.MaxPixels/1_000_000)
+	default:
+		return "Images must be JPEG, PNG, GIF or WebP files."
+	}
+}
+
+// Keeps a rejected upload where only signed URLs reach it, so what was
+// sent can be looked into by admins
+func (h *Handler) quarantine(ctx context.Context, userId string, name string, data []byte, reason error) {
+	upload := models.QuarantinedUpload{
+		Id:        uuid.NewString(),
+		UserId:    userId,
+		Name:      name,
+		Reason:    reason.Error(),
+		Size:      int64(len(data)),
+		CreatedAt: time.Now(),
+	}
+	upload.Key = media.PrivateKey("quarantine/" + upload.Id)
+	if err := h.media.Put(ctx, upload.Key, data, "application/octet-stream"); err != nil {
+		log.Println(err)
+		return
+	}
+	h.store.CreateQuarantinedUpload(&upload)
+	log.Printf("quarantined upload %q from user %s as %s: %v", name, userId, upload.Key, reason)
+}
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Items per page of the lists with a "More" button
+const pageSize = 10
+
+// Reads the cursor the "load more" scripts send back, responding with a
+// 400 when it's malformed
+func moreCursor(c *gin.Context) (*models.Cursor, bool) {
+	after, err := models.DecodeCursor(c.Query("cursor"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+		return nil, false
+	}
+	return after, true
+}
+
+// Responds with a page for the "load more" scripts, nextCursor is empty
+// on the last page
+func morePage(c *gin.Context, items any, next *models.Cursor) {
+	c.JSON(http.StatusOK, gin.H{
+		"data":       items,
+		"nextCursor": next.Encode(),
+	})
+}
@@ -1,14 +1,12 @@
 package routes
 
 import (
-	"fmt"
-	"io/ioutil"
 	"net/http"
-	"os"
-	"path/filepath"
+	"strings"
 	"time"
+	"unicode/utf8"
 
-	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/middleware"
 	"github.com/Bhar8at/bhar8at.github.io/models"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
@@ -16,9 +14,13 @@ import (
 	"github.com/google/uuid"
 )
 
-var commentLimit = 10
+const (
+	maxPostLength = 320
+	// Earlier versions shown on an edited post
+	revisionsLimit = 20
+)
 
-func NewPost(c *gin.Context) {
+func (h *Handler) NewPost(c *gin.Context) {
 	session := sessions.Default(c)
 	id := session.Get("userId")
 	// Checking if the user is logged in
@@ -32,11 +34,15 @@ func NewPost(c *gin.Context) {
 
 	switch c.Request.Method {
 	case "GET":
-		c.HTML(http.StatusOK, "makepostT.html", nil)
+		c.HTML(http.StatusOK, "makepostT.html", gin.H{
+			"csrf":       middleware.CSRFToken(c),
+			"mediaSlots": mediaSlots(),
+		})
 	case "POST":
 		var post models.Post
 
-		if err := c.Request.ParseMultipartForm(10 << 20); err != nil {
+		// Images are optional, so a form sent without any file is fine too
+		if err := c.Request.ParseMultipartForm(10 << 20); err != nil && err != http.ErrNotMultipart {
 			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
 				"error":   "400 Bad Request",
 				"message": "Unable to parse form.",
@@ -55,62 +61,33 @@ func NewPost(c *gin.Context) {
 		post.Id = uuid.NewString()
 		post.CreatedAt = time.Now()
 
-		file, header, _ := c.Request.FormFile("images[]")
-		defer file.Close()
-
-		// Read the file content into a byte slice
-		fileBytes, err := ioutil.ReadAll(file)
+		media, err := h.saveMedia(c.Request.Context(), id.(string), c.Request.MultipartForm)
 		if err != nil {
 			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
 				"error":   "400 Bad Request",
-				"message": "Unable to read image data.",
-			})
-			return
-		}
-
-		filename := fmt.Sprintf("%d%s", time.Now().UnixNano(), filepath.Ext(header.Filename))
-		path := filepath.Join("uploads", filename)
-
-		err = os.MkdirAll("uploads", 0755)
-		if err != nil {
-			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
-				"error":   "400 Bad Request",
-				"message": "Unable to read image data.",
+				"message": err.Error(),
 			})
 			return
 		}
+		post.Media = media
 
-		err = os.WriteFile(path, fileBytes, 0644)
-		if err != nil {
-			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
-				"error":   "400 Bad Request",
-				"message": "Unable to read image data.",
-			})
-			return
-		}
-
-		baseURL := "http://localhost:8080"
-		imageURL := baseURL + "/" + path
-		fmt.Println("HEre is the Image URL : ", imageURL)
-		post.Images = imageURL
-
-		if result := database.CreatePost(id.(string), &post); !result {
+		if result := h.store.CreatePost(id.(string), &post); !result {
 			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
 				"error":   "400 Bad Request",
 				"message": "Unable to create post, please try again later.",
 			})
 			return
 		}
+		h.timeline.Posted(id.(string), post.Id)
 		c.Redirect(http.StatusFound, "/post/"+post.Id)
 	}
 }
 
-func GetPost(c *gin.Context) {
+func (h *Handler) GetPost(c *gin.Context) {
 	var self, voted bool
-	session := sessions.Default(c)
-	id := session.Get("userId")
+	id := middleware.SessionUserId(c, h.store)
 	postId := c.Param("id")
-	post := database.ReadPost(postId)
+	post := h.store.ReadPost(postId)
 	if post == nil {
 		c.HTML(http.StatusNotFound, "errorT.html", gin.H{
 			"error":   "404 Not Found",
@@ -118,54 +95,112 @@ func GetPost(c *gin.Context) {
 		})
 		return
 	}
-	commentLimit = 10
-	comments := database.ReadComments(post.Id, 10, 0)
+	comments, next := h.store.ReadComments(post.Id, nil, pageSize)
 	for index := range comments {
-		comments[index].Username = database.ReadUserById(comments[index].UserId).Username
 		// Enable delete comment if its current user's comment
-		if id != nil && id.(string) == comments[index].UserId {
+		if id != "" && id == comments[index].UserId {
 			comments[index].Self = true
 		}
 	}
-	if id != nil {
+	if id != "" {
 		// Check if current user has voted on post
-		voted = database.Voted(id.(string), post.Id)
+		voted = h.store.Voted(id, post.Id)
 		// Enable delete post if its current user's post
-		if id.(string) == post.UserId {
+		if id == post.UserId {
 			self = true
 		}
 	}
-	fmt.Println("\n\nHere is the image data : \n\n", post.Images)
+	var revisions []models.PostRevision
+	if post.EditedAt != nil {
+		revisions, _ = h.store.ReadPostRevisions(post.Id, nil, revisionsLimit)
+	}
 
 	c.HTML(http.StatusOK, "getpostT.html", gin.H{
-		"author":   database.ReadUserById(post.UserId),
-		"post":     post,
-		"self":     self,
-		"voted":    voted,
-		"voters":   database.ReadVotes(post.Id),
-		"comments": comments,
-		"imageURL": post.Images,
+		"csrf":      middleware.CSRFToken(c),
+		"author":    h.store.ReadUserById(post.UserId),
+		"post":      post,
+		"self":      self,
+		"voted":     voted,
+		"voters":    h.store.ReadVotes(post.Id),
+		"comments":  comments,
+		"cursor":    next.Encode(),
+		"revisions": revisions,
 	})
 }
 
 // Return comments for loading through AJAX
-func LoadMoreComments(c *gin.Context) {
+func (h *Handler) LoadMoreComments(c *gin.Context) {
 	session := sessions.Default(c)
 	id := session.Get("userId")
 	postId := c.Param("id")
-	comments := database.ReadComments(postId, 10, commentLimit)
-	commentLimit += 10
+	after, ok := moreCursor(c)
+	if !ok {
+		return
+	}
+	comments, next := h.store.ReadComments(postId, after, pageSize)
 	for index := range comments {
-		comments[index].Username = database.ReadUserById(comments[index].UserId).Username
 		// Enable delete comment if its current user's comment
 		if id != nil && id.(string) == comments[index].UserId {
 			comments[index].Self = true
 		}
 	}
-	c.JSON(http.StatusOK, comments)
+	morePage(c, comments, next)
+}
+
+// Lets the author change the body of their post, keeping the old one in
+// its history
+func (h *Handler) EditPost(c *gin.Context) {
+	session := sessions.Default(c)
+	id := session.Get("userId")
+	if id == nil {
+		c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+			"error":   "401 Unauthorized",
+			"message": "User not logged in.",
+		})
+		return
+	}
+	post := h.store.ReadPost(c.Param("id"))
+	if post == nil {
+		c.HTML(http.StatusNotFound, "errorT.html", gin.H{
+			"error":   "404 Not Found",
+			"message": "Post not found or doesn't exist.",
+		})
+		return
+	}
+	if id.(string) != post.UserId {
+		c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+			"error":   "401 Unauthorized",
+			"message": "Cannot perform this task.",
+		})
+		return
+	}
+	switch c.Request.Method {
+	case "GET":
+		c.HTML(http.StatusOK, "makepostT.html", gin.H{
+			"csrf": middleware.CSRFToken(c),
+			"post": post,
+		})
+	case "POST":
+		body := strings.TrimSpace(c.PostForm("body"))
+		if body == "" || utf8.RuneCountInString(body) > maxPostLength {
+			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
+				"error":   "400 Bad Request",
+				"message": "Posts must be between 1 and 320 characters.",
+			})
+			return
+		}
+		if body != post.Body && !h.store.EditPost(post.Id, body, time.Now(), uuid.NewString()) {
+			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
+				"error":   "400 Bad Request",
+				"message": "Unable to edit post, try again later.",
+			})
+			return
+		}
+		c.Redirect(http.StatusFound, "/post/"+post.Id)
+	}
 }
 
-func DeletePost(c *gin.Context) {
+func (h *Handler) DeletePost(c *gin.Context) {
 	session := sessions.Default(c)
 	id := session.Get("userId")
 	if id == nil {
@@ -176,7 +211,14 @@ func DeletePost(c *gin.Context) {
 		return
 	}
 	postId := c.Param("id")
-	post := database.ReadPost(postId)
+	post := h.store.ReadPost(postId)
+	if post == nil {
+		c.HTML(http.StatusNotFound, "errorT.html", gin.H{
+			"error":   "404 Not Found",
+			"message": "Post not found or doesn't exist.",
+		})
+		return
+	}
 	if id.(string) != post.UserId {
 		c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
 			"error":   "401 Unauthorized",
@@ -184,7 +226,7 @@ func DeletePost(c *gin.Context) {
 		})
 		return
 	}
-	if result := database.DeletePost(post.Id); !result {
+	if result := h.store.DeletePost(post.Id); !result {
 		c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
 			"error":   "400 Bad Request",
 			"message": "Unable to delete post, try again later.",
@@ -196,7 +238,7 @@ func DeletePost(c *gin.Context) {
 	})
 }
 
-func ToggleVote(c *gin.Context) {
+func (h *Handler) ToggleVote(c *gin.Context) {
 	session := sessions.Default(c)
 	id := session.Get("userId")
 	if id == nil {
@@ -207,11 +249,11 @@ func ToggleVote(c *gin.Context) {
 		return
 	}
 	postId := c.Param("id")
-	database.ToggleVote(id.(string), postId)
+	h.store.ToggleVote(id.(string), postId)
 	c.Redirect(http.StatusFound, "/post/"+postId)
 }
 
-func Comment(c *gin.Context) {
+func (h *Handler) Comment(c *gin.Context) {
 	session := sessions.Default(c)
 	id := session.Get("userId")
 	if id == nil {
@@ -239,7 +281,7 @@ func Comment(c *gin.Context) {
 	postId := c.Param("id")
 	comment.Id = uuid.NewString()
 	comment.CreatedAt = time.Now()
-	if result := database.CreateComment(id.(string), postId, &comment); !result {
+	if result := h.store.CreateComment(id.(string), postId, &comment); !result {
 		c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
 			"error":   "400 Bad Request",
 			"message": "Unable to add comment, try again later.",
@@ -249,7 +291,7 @@ func Comment(c *gin.Context) {
 	c.Redirect(http.StatusFound, "/post/"+postId)
 }
 
-func DeleteComment(c *gin.Context) {
+func (h *Handler) DeleteComment(c *gin.Context) {
 	session := sessions.Default(c)
 	id := session.Get("userId")
 	if id == nil {
@@ -261,7 +303,7 @@ func DeleteComment(c *gin.Context) {
 	}
 	postId := c.Param("id")
 	commentId := c.Query("commentId")
-	comment := database.ReadComment(commentId)
+	comment := h.store.ReadComment(commentId)
 	if comment == nil {
 		c.HTML(http.StatusNotFound, "errorT.html", gin.H{
 			"error":   "404 Not Found",
@@ -276,7 +318,7 @@ func DeleteComment(c *gin.Context) {
 		})
 		return
 	}
-	if result := database.DeleteComment(commentId); !result {
+	if result := h.store.DeleteComment(commentId); !result {
 		c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
 			"error":   "400 Bad Request",
 			"message": "Unable to delete comment, try again later.",
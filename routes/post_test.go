@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func TestDeletePost(t *testing.T) {
+	tests := []struct {
+		name        string
+		postId      string
+		author      string
+		wantStatus  int
+		wantDeleted bool
+	}{
+		{"own post", "post", "alice-id", http.StatusOK, true},
+		{"someone else's post", "post", "bob-id", http.StatusUnauthorized, false},
+		{"missing post", "missing", "alice-id", http.StatusNotFound, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := newTestApp(t)
+			alice := app.login(t, "alice")
+			app.createUser(t, "bob")
+			app.store.CreatePost(test.author, &models.Post{Id: "post", Body: "hello", CreatedAt: time.Now()})
+
+			response := alice.do(http.MethodPost, "/post/"+test.postId+"/delete", nil)
+			if response.Code != test.wantStatus {
+				t.Fatalf("delete = %d, want %d", response.Code, test.wantStatus)
+			}
+			if deleted := app.store.ReadPost("post") == nil; deleted != test.wantDeleted {
+				t.Errorf("deleted = %v, want %v", deleted, test.wantDeleted)
+			}
+			if test.wantDeleted {
+				// Deleting again finds nothing rather than failing
+				if response := alice.do(http.MethodPost, "/post/post/delete", nil); response.Code != http.StatusNotFound {
+					t.Errorf("second delete = %d, want 404", response.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package routes
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Bhar8at/bhar8at.github.io/internal/tokens"
+	"github.com/Bhar8at/bhar8at.github.io/middleware"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	switch c.Request.Method {
+	case "GET":
+		c.HTML(http.StatusOK, "resetT.html", gin.H{
+			"csrf": middleware.CSRFToken(c),
+			"type": "forgot",
+		})
+	case "POST":
+		email := c.PostForm("email")
+		if email == "" {
+			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
+				"error":   "400 Bad Request",
+				"message": "Email is required.",
+			})
+			return
+		}
+		if err := h.resetter.Send(email); err != nil {
+			log.Println(err)
+			c.HTML(http.StatusInternalServerError, "errorT.html", gin.H{
+				"error":   "500 Internal Server Error",
+				"message": "Unable to send reset email, try again later.",
+			})
+			return
+		}
+		// Same response whether or not the account exists
+		c.HTML(http.StatusOK, "responseT.html", gin.H{
+			"message": "If an account exists for " + email + ", a password reset link has been sent to it.",
+		})
+	}
+}
+
+func (h *Handler) ResetPassword(c *gin.Context) {
+	switch c.Request.Method {
+	case "GET":
+		token := c.Query("token")
+		if token == "" {
+			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
+				"error":   "400 Bad Request",
+				"message": "Password reset link is invalid or has expired.",
+			})
+			return
+		}
+		c.HTML(http.StatusOK, "resetT.html", gin.H{
+			"csrf":  middleware.CSRFToken(c),
+			"type":  "reset",
+			"token": token,
+		})
+	case "POST":
+		password := c.PostForm("password")
+		if password == "" {
+			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
+				"error":   "400 Bad Request",
+				"message": "Password is required.",
+			})
+			return
+		}
+		if err := h.resetter.Reset(c.PostForm("token"), password); err != nil {
+			if err == tokens.ErrInvalidToken {
+				c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
+					"error":   "400 Bad Request",
+					"message": "Password reset link is invalid or has expired.",
+				})
+				return
+			}
+			log.Println(err)
+			c.HTML(http.StatusInternalServerError, "errorT.html", gin.H{
+				"error":   "500 Internal Server Error",
+				"message": "Unable to reset password, try again later.",
+			})
+			return
+		}
+		session := sessions.Default(c)
+		session.Clear()
+		session.Save()
+		c.HTML(http.StatusOK, "responseT.html", gin.H{
+			"message": "Password reset successfully, login with your new password.",
+		})
+	}
+}
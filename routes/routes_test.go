@@ -0,0 +1,138 @@
+package routes
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/internal"
+	"github.com/Bhar8at/bhar8at.github.io/internal/jobs"
+	"github.com/Bhar8at/bhar8at.github.io/internal/media"
+	"github.com/Bhar8at/bhar8at.github.io/internal/reset"
+	"github.com/Bhar8at/bhar8at.github.io/internal/throttle"
+	"github.com/Bhar8at/bhar8at.github.io/internal/timeline"
+	"github.com/Bhar8at/bhar8at.github.io/internal/tokens"
+	"github.com/Bhar8at/bhar8at.github.io/internal/verify"
+	"github.com/Bhar8at/bhar8at.github.io/middleware"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+)
+
+const testPassword = "password123"
+
+// Mailer dropping every email
+type discard struct{}
+
+func (discard) Send(to string, subject string, body string) error { return nil }
+
+// The handlers on a memory store, routed as in the app without the CSRF
+// check, which is tested with its middleware
+type testApp struct {
+	engine  *gin.Engine
+	store   *database.Memory
+	handler *Handler
+}
+
+func newTestApp(t *testing.T) *testApp {
+	gin.SetMode(gin.TestMode)
+	store := database.NewMemory()
+	queue := jobs.New(store)
+	issuer := tokens.NewIssuer(store, []byte("secret"))
+	baseURL := "http://localhost:8080"
+	handler := NewHandler(Config{
+		Store:    store,
+		Verifier: verify.New(store, issuer, discard{}, queue, baseURL),
+		Resetter: reset.New(store, issuer, discard{}, queue, baseURL),
+		Throttle: throttle.New(store, discard{}, baseURL),
+		Timeline: timeline.New(store, queue),
+		Queue:    queue,
+		Media:    &media.Local{Dir: t.TempDir(), BaseURL: baseURL + "/uploads", Secret: []byte("secret")},
+	})
+
+	engine := gin.New()
+	engine.SetFuncMap(template.FuncMap{
+		"formatAsTitle":  internal.FormatAsTitle,
+		"formatAsDate":   internal.FormatAsDate,
+		"formatAsDevice": internal.FormatAsDevice,
+		"csrfField":      internal.CSRFField,
+	})
+	engine.LoadHTMLGlob("../templates/*")
+	engine.Use(sessions.Sessions("cookie", cookie.NewStore([]byte("secret"))))
+	engine.Use(middleware.RecoveryMiddleware())
+	auth := middleware.AuthMiddleware(store)
+
+	engine.POST("/auth/login", handler.Login)
+	engine.GET("/user/:username", handler.GetUserByName)
+	engine.POST("/user/:username/toggle-follow", auth, handler.ToggleFollow)
+	engine.POST("/search/", handler.SearchUser)
+	engine.POST("/search/:username/toggle-follow", auth, handler.ToggleSearchFollow)
+	engine.GET("/post/:id", handler.GetPost)
+	engine.POST("/post/:id/delete", auth, handler.DeletePost)
+	engine.GET("/user/settings/2fa", auth, handler.TwoFactorSettings)
+	engine.POST("/user/settings/2fa/enable", auth, handler.EnableTwoFactor)
+	return &testApp{engine: engine, store: store, handler: handler}
+}
+
+// Creates a verified user with testPassword
+func (a *testApp) createUser(t *testing.T, username string) *models.User {
+	email := username + "@example.com"
+	user := models.User{
+		Id:        username + "-id",
+		Username:  username,
+		Email:     &email,
+		Password:  testPassword,
+		Verified:  true,
+		CreatedAt: time.Now(),
+	}
+	if err := user.HashPassword(); err != nil {
+		t.Fatal(err)
+	}
+	if !a.store.CreateUser(&user) {
+		t.Fatalf("unable to create %s", username)
+	}
+	return &user
+}
+
+// Browser keeping the session cookie between requests
+type client struct {
+	app     *testApp
+	cookies map[string]*http.Cookie
+}
+
+func (a *testApp) client() *client {
+	return &client{app: a, cookies: make(map[string]*http.Cookie)}
+}
+
+// Creates the user and logs in as them
+func (a *testApp) login(t *testing.T, username string) *client {
+	a.createUser(t, username)
+	c := a.client()
+	response := c.do(http.MethodPost, "/auth/login", url.Values{"username": {username}, "password": {testPassword}})
+	if response.Code != http.StatusFound {
+		t.Fatalf("login as %s = %d: %s", username, response.Code, response.Body)
+	}
+	return c
+}
+
+func (c *client) do(method string, path string, form url.Values) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+	if form != nil {
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	for _, cookie := range c.cookies {
+		request.AddCookie(cookie)
+	}
+	response := httptest.NewRecorder()
+	c.app.engine.ServeHTTP(response, request)
+	for _, cookie := range response.Result().Cookies() {
+		c.cookies[cookie.Name] = cookie
+	}
+	return response
+}
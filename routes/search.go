@@ -3,14 +3,12 @@ package routes
 import (
 	"net/http"
 
-	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/middleware"
 	"github.com/Bhar8at/bhar8at.github.io/models"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
 
-var searchLimit = 10
-
 type search struct {
 	models.User
 	Followers int
@@ -19,64 +17,65 @@ type search struct {
 	Follows   any
 }
 
-func SearchUser(c *gin.Context) {
+func (h *Handler) SearchUser(c *gin.Context) {
 	session := sessions.Default(c)
 	switch c.Request.Method {
 	case "GET":
-		searchLimit = 10
 		session.Delete("search")
 		session.Save()
-		c.HTML(http.StatusOK, "searchT.html", nil)
+		c.HTML(http.StatusOK, "searchT.html", gin.H{
+			"csrf": middleware.CSRFToken(c),
+		})
 	case "POST":
-		id := session.Get("userId")
+		id := middleware.SessionUserId(c, h.store)
 		if c.PostForm("search") != "" {
 			session.Set("search", c.PostForm("search"))
 			session.Save()
 		}
-		keyword := session.Get("search").(string)
-		searchLimit = 10
-		searchResult := database.ReadUsers(keyword, 10, 0)
-		var users []search
-		for _, result := range searchResult {
-			user := search{
-				User:      result,
-				Followers: database.ReadFollowersCount(result.Id),
-				Following: database.ReadFollowingCount(result.Id),
-				Posts:     database.ReadPostsCount(result.Id),
-			}
-			if id != nil && id.(string) != result.Id {
-				user.Follows = database.Followed(id.(string), result.Id)
-			}
-			users = append(users, user)
-		}
-		c.JSON(http.StatusOK, users)
+		keyword, _ := session.Get("search").(string)
+		searchResult, next := h.store.ReadUsers(keyword, nil, pageSize)
+		morePage(c, h.searchResults(id, searchResult), next)
 	}
 }
 
-// Return users for loading through AJAX
-func LoadMoreUsers(c *gin.Context) {
-	session := sessions.Default(c)
-	id := session.Get("userId")
-	keyword := session.Get("search").(string)
-	searchResult := database.ReadUsers(keyword, 10, searchLimit)
-	searchLimit += 10
+// Adds the counts shown for each user found
+func (h *Handler) searchResults(userId string, searchResult []models.User) []search {
+	ids := make([]string, len(searchResult))
+	for index, result := range searchResult {
+		ids[index] = result.Id
+	}
+	stats := h.store.ReadUserStats(userId, ids)
 	var users []search
 	for _, result := range searchResult {
+		stat := stats[result.Id]
 		user := search{
 			User:      result,
-			Followers: database.ReadFollowersCount(result.Id),
-			Following: database.ReadFollowingCount(result.Id),
-			Posts:     database.ReadPostsCount(result.Id),
+			Followers: stat.Followers,
+			Following: stat.Following,
+			Posts:     stat.Posts,
 		}
-		if id != nil && id.(string) != result.Id {
-			user.Follows = database.Followed(id.(string), result.Id)
+		if userId != "" && userId != result.Id {
+			user.Follows = stat.Followed
 		}
 		users = append(users, user)
 	}
-	c.JSON(http.StatusOK, users)
+	return users
+}
+
+// Return users for loading through AJAX
+func (h *Handler) LoadMoreUsers(c *gin.Context) {
+	session := sessions.Default(c)
+	id := middleware.SessionUserId(c, h.store)
+	keyword, _ := session.Get("search").(string)
+	after, ok := moreCursor(c)
+	if !ok {
+		return
+	}
+	searchResult, next := h.store.ReadUsers(keyword, after, pageSize)
+	morePage(c, h.searchResults(id, searchResult), next)
 }
 
-func ToggleSearchFollow(c *gin.Context) {
+func (h *Handler) ToggleSearchFollow(c *gin.Context) {
 	session := sessions.Default(c)
 	id := session.Get("userId")
 	if id == nil {
@@ -87,6 +86,14 @@ func ToggleSearchFollow(c *gin.Context) {
 		return
 	}
 	username := c.Param("username")
-	toFollow := database.ReadUserByName(username)
-	database.ToggleFollow(id.(string), toFollow.Id)
+	toFollow := h.store.ReadUserByName(username)
+	if toFollow == nil {
+		c.HTML(http.StatusNotFound, "errorT.html", gin.H{
+			"error":   "404 Not Found",
+			"message": "User not found",
+		})
+		return
+	}
+	h.store.ToggleFollow(id.(string), toFollow.Id)
+	h.timeline.Followed(id.(string), toFollow.Id)
 }
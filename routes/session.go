@@ -0,0 +1,91 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/Bhar8at/bhar8at.github.io/middleware"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// Lists the devices the user is logged in on
+func (h *Handler) GetSessions(c *gin.Context) {
+	session := sessions.Default(c)
+	id := session.Get("userId")
+	if id == nil {
+		c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+			"error":   "401 Unauthorized",
+			"message": "User not logged in.",
+		})
+		return
+	}
+	active := h.store.ReadSessions(id.(string))
+	for index := range active {
+		if active[index].Id == session.Get("sessionId") {
+			active[index].Current = true
+		}
+	}
+	c.HTML(http.StatusOK, "sessionsT.html", gin.H{
+		"csrf":     middleware.CSRFToken(c),
+		"sessions": active,
+	})
+}
+
+func (h *Handler) RevokeSession(c *gin.Context) {
+	session := sessions.Default(c)
+	id := session.Get("userId")
+	if id == nil {
+		c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+			"error":   "401 Unauthorized",
+			"message": "User not logged in.",
+		})
+		return
+	}
+	target := h.store.ReadSession(c.Param("id"))
+	if target == nil || target.UserId != id.(string) {
+		c.HTML(http.StatusNotFound, "errorT.html", gin.H{
+			"error":   "404 Not Found",
+			"message": "Session not found.",
+		})
+		return
+	}
+	if result := h.store.DeleteSession(target.Id); !result {
+		c.HTML(http.StatusInternalServerError, "errorT.html", gin.H{
+			"error":   "500 Internal Server Error",
+			"message": "Unable to revoke session, try again later.",
+		})
+		return
+	}
+	// Revoking the current session is the same as logging out
+	if target.Id == session.Get("sessionId") {
+		session.Clear()
+		session.Options(sessions.Options{MaxAge: -1})
+		session.Save()
+		c.HTML(http.StatusOK, "responseT.html", gin.H{
+			"message": "Logged out successfully.",
+		})
+		return
+	}
+	c.Redirect(http.StatusFound, "/user/settings/sessions")
+}
+
+func (h *Handler) RevokeOtherSessions(c *gin.Context) {
+	session := sessions.Default(c)
+	id := session.Get("userId")
+	current, _ := session.Get("sessionId").(string)
+	if id == nil || current == "" {
+		c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+			"error":   "401 Unauthorized",
+			"message": "User not logged in.",
+		})
+		return
+	}
+	if result := h.store.DeleteOtherSessions(id.(string), current); !result {
+		c.HTML(http.StatusInternalServerError, "errorT.html", gin.H{
+			"error":   "500 Internal Server Error",
+			"message": "Unable to revoke sessions, try again later.",
+		})
+		return
+	}
+	c.Redirect(http.StatusFound, "/user/settings/sessions")
+}
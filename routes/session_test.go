@@ -0,0 +1,43 @@
+package routes
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/models"
+)
+
+func TestRevokedSessionOnPublicPages(t *testing.T) {
+	app := newTestApp(t)
+	alice := app.login(t, "alice")
+	app.createUser(t, "bob")
+	app.store.CreatePost("alice-id", &models.Post{Id: "post", Body: "hello", CreatedAt: time.Now()})
+
+	if response := alice.do(http.MethodGet, "/user/bob", nil); !strings.Contains(response.Body.String(), "Follow</button>") {
+		t.Fatalf("profile while logged in has no follow button: %s", response.Body)
+	}
+	app.store.DeleteSessions("alice-id")
+
+	if response := alice.do(http.MethodGet, "/user/alice", nil); response.Code != http.StatusOK {
+		t.Errorf("own profile after revoking = %d, want 200", response.Code)
+	}
+	if response := alice.do(http.MethodGet, "/user/bob", nil); strings.Contains(response.Body.String(), "Follow</button>") {
+		t.Errorf("profile after revoking still has a follow button")
+	}
+	if response := alice.do(http.MethodGet, "/post/post", nil); strings.Contains(response.Body.String(), `action="/post/post/delete"`) {
+		t.Errorf("own post after revoking can still be deleted")
+	}
+	response := alice.do(http.MethodPost, "/search/", url.Values{"search": {"bob"}})
+	if !strings.Contains(response.Body.String(), `"Follows":null`) {
+		t.Errorf("search after revoking = %s, want no follows", response.Body)
+	}
+
+	// The revoked token was dropped from the cookie
+	response = alice.do(http.MethodGet, "/user/settings/2fa", nil)
+	if response.Code != http.StatusUnauthorized || !strings.Contains(response.Body.String(), "User not logged in.") {
+		t.Errorf("settings after revoking = %d: %s", response.Code, response.Body)
+	}
+}
@@ -0,0 +1,288 @@
+package routes
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/internal/throttle"
+	"github.com/Bhar8at/bhar8at.github.io/internal/totp"
+	"github.com/Bhar8at/bhar8at.github.io/middleware"
+	"github.com/Bhar8at/bhar8at.github.io/models"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/skip2/go-qrcode"
+)
+
+const recoveryCodesCount = 10
+
+// Generates single-use codes for logging in without the authenticator,
+// returned for showing once along with the hashes to store
+func generateRecoveryCodes() ([]string, []string, error) {
+	var codes, hashes []string
+	for len(codes) < recoveryCodesCount {
+		random := make([]byte, 10)
+		if _, err := rand.Read(random); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(random))[:10]
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// Name shown for the account in authenticator apps
+func totpIssuer() string {
+	if issuer == "" {
+		return "Connectify"
+	}
+	return issuer
+}
+
+// Second login step for users with two factor authentication
+func (h *Handler) TwoFactorLogin(c *gin.Context) {
+	switch c.Request.Method {
+	case "GET":
+		c.HTML(http.StatusOK, "twofactorT.html", gin.H{
+			"csrf": middleware.CSRFToken(c),
+			"type": "login",
+		})
+	case "POST":
+		id, err := middleware.PendingLogin(c, h.store)
+		if err != nil {
+			c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+				"error":   "401 Unauthorized",
+				"message": "Login expired, try logging in again.",
+			})
+			return
+		}
+		user := h.store.ReadUserById(id)
+		twoFactor := h.store.ReadTwoFactor(id)
+		if user == nil || twoFactor == nil || !twoFactor.Enabled {
+			c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+				"error":   "401 Unauthorized",
+				"message": "Login expired, try logging in again.",
+			})
+			return
+		}
+		// Codes are throttled along with passwords, so starting new logins
+		// doesn't give more guesses
+		if wait := h.throttle.Wait(user.Username, c.ClientIP()); wait > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			c.HTML(http.StatusTooManyRequests, "errorT.html", gin.H{
+				"error":   "429 Too Many Requests",
+				"message": fmt.Sprintf("Too many failed login attempts, try again in %s.", throttle.Describe(wait)),
+			})
+			return
+		}
+		valid := false
+		if code := c.PostForm("code"); code != "" {
+			step, ok := totp.Validate(twoFactor.Secret, code, time.Now())
+			valid = ok && h.store.UseTwoFactorStep(id, step)
+		} else if code := c.PostForm("recovery"); code != "" {
+			valid = h.store.UseRecoveryCode(id, hashRecoveryCode(code))
+		}
+		if !valid {
+			h.throttle.Failed(user.Username, c.ClientIP(), c.Request.UserAgent())
+			c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+				"error":   "401 Unauthorized",
+				"message": "Incorrect code.",
+			})
+			return
+		}
+		h.throttle.Succeeded(user.Username, c.ClientIP(), c.Request.UserAgent())
+		if err := middleware.CompleteLogin(c, h.store, id); err != nil {
+			log.Println(err)
+			c.HTML(http.StatusInternalServerError, "errorT.html", gin.H{
+				"error":   "500 Internal Server Error",
+				"message": "Unable to login, try again later.",
+			})
+			return
+		}
+		c.Redirect(http.StatusFound, "/feed")
+	}
+}
+
+// Shows the two factor status, or starts enrollment with a new secret
+// unless one is already waiting to be confirmed
+func (h *Handler) TwoFactorSettings(c *gin.Context) {
+	session := sessions.Default(c)
+	id := session.Get("userId")
+	if id == nil {
+		c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+			"error":   "401 Unauthorized",
+			"message": "User not logged in.",
+		})
+		return
+	}
+	userId := id.(string)
+	twoFactor := h.store.ReadTwoFactor(userId)
+	if twoFactor != nil && twoFactor.Enabled {
+		c.HTML(http.StatusOK, "twofactorT.html", gin.H{
+			"csrf":          middleware.CSRFToken(c),
+			"type":          "enabled",
+			"recoveryCodes": h.store.ReadRecoveryCodesCount(userId),
+			"required":      h.store.IsAdmin(userId),
+			"oauth":         h.store.IsOAuthUser(userId),
+		})
+		return
+	}
+	// The pending secret is kept, so reloading the page or opening it in
+	// another tab doesn't replace the one the user just scanned
+	if twoFactor == nil {
+		secret, err := totp.GenerateSecret()
+		if err == nil && h.store.CreateTwoFactor(&models.TwoFactor{
+			UserId:    userId,
+			Secret:    secret,
+			CreatedAt: time.Now(),
+		}) {
+			// Another request may have started enrollment first
+			twoFactor = h.store.ReadTwoFactor(userId)
+		}
+		if twoFactor == nil {
+			c.HTML(http.StatusInternalServerError, "errorT.html", gin.H{
+				"error":   "500 Internal Server Error",
+				"message": "Unable to set up two factor authentication, try again later.",
+			})
+			return
+		}
+	}
+	user := h.store.ReadUserById(userId)
+	c.HTML(http.StatusOK, "twofactorT.html", gin.H{
+		"csrf":     middleware.CSRFToken(c),
+		"type":     "enroll",
+		"secret":   twoFactor.Secret,
+		"uri":      totp.URI(totpIssuer(), user.Username, twoFactor.Secret),
+		"required": h.store.IsAdmin(userId),
+	})
+}
+
+// QR code of the otpauth URI for the secret being enrolled
+func (h *Handler) TwoFactorQR(c *gin.Context) {
+	session := sessions.Default(c)
+	id := session.Get("userId")
+	if id == nil {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+	twoFactor := h.store.ReadTwoFactor(id.(string))
+	if twoFactor == nil || twoFactor.Enabled {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	user := h.store.ReadUserById(twoFactor.UserId)
+	png, err := qrcode.Encode(totp.URI(totpIssuer(), user.Username, twoFactor.Secret), qrcode.Medium, 256)
+	if err != nil {
+		log.Println(err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", png)
+}
+
+// Confirms enrollment with a code from the authenticator app
+func (h *Handler) EnableTwoFactor(c *gin.Context) {
+	session := sessions.Default(c)
+	id := session.Get("userId")
+	if id == nil {
+		c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+			"error":   "401 Unauthorized",
+			"message": "User not logged in.",
+		})
+		return
+	}
+	twoFactor := h.store.ReadTwoFactor(id.(string))
+	if twoFactor == nil || twoFactor.Enabled {
+		c.Redirect(http.StatusFound, "/user/settings/2fa")
+		return
+	}
+	step, ok := totp.Validate(twoFactor.Secret, c.PostForm("code"), time.Now())
+	if !ok {
+		c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
+			"error":   "400 Bad Request",
+			"message": "Incorrect code, scan the QR code again and retry.",
+		})
+		return
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil || !h.store.EnableTwoFactor(twoFactor.UserId, step, hashes) {
+		c.HTML(http.StatusInternalServerError, "errorT.html", gin.H{
+			"error":   "500 Internal Server Error",
+			"message": "Unable to enable two factor authentication, try again later.",
+		})
+		return
+	}
+	c.HTML(http.StatusOK, "twofactorT.html", gin.H{
+		"csrf":  middleware.CSRFToken(c),
+		"type":  "codes",
+		"codes": codes,
+	})
+}
+
+// Turns off two factor authentication, which requires the password, or
+// a current code for OAuth users who never chose one
+func (h *Handler) DisableTwoFactor(c *gin.Context) {
+	session := sessions.Default(c)
+	id := session.Get("userId")
+	if id == nil {
+		c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+			"error":   "401 Unauthorized",
+			"message": "User not logged in.",
+		})
+		return
+	}
+	user := h.store.ReadUserById(id.(string))
+	if h.store.IsAdmin(user.Id) {
+		c.HTML(http.StatusForbidden, "errorT.html", gin.H{
+			"error":   "403 Forbidden",
+			"message": "Two factor authentication is required for admin accounts.",
+		})
+		return
+	}
+	twoFactor := h.store.ReadTwoFactor(user.Id)
+	if twoFactor == nil || !twoFactor.Enabled {
+		c.Redirect(http.StatusFound, "/user/settings/2fa")
+		return
+	}
+	if h.store.IsOAuthUser(user.Id) {
+		step, ok := totp.Validate(twoFactor.Secret, c.PostForm("code"), time.Now())
+		if !ok || !h.store.UseTwoFactorStep(user.Id, step) {
+			c.HTML(http.StatusForbidden, "errorT.html", gin.H{
+				"error":   "403 Forbidden",
+				"message": "Incorrect code.",
+			})
+			return
+		}
+	} else if !user.CheckPassword(c.PostForm("password")) {
+		c.HTML(http.StatusForbidden, "errorT.html", gin.H{
+			"error":   "403 Forbidden",
+			"message": "Incorrect password.",
+		})
+		return
+	}
+	if result := h.store.DeleteTwoFactor(user.Id); !result {
+		c.HTML(http.StatusInternalServerError, "errorT.html", gin.H{
+			"error":   "500 Internal Server Error",
+			"message": "Unable to disable two factor authentication, try again later.",
+		})
+		return
+	}
+	c.HTML(http.StatusOK, "responseT.html", gin.H{
+		"message": "Two factor authentication disabled.",
+	})
+}
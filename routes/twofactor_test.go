@@ -0,0 +1,45 @@
+package routes
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Bhar8at/bhar8at.github.io/internal/totp"
+)
+
+var secretPattern = regexp.MustCompile(`<code>([A-Z2-7]+)</code>`)
+
+func TestTwoFactorSettingsKeepsPendingSecret(t *testing.T) {
+	app := newTestApp(t)
+	alice := app.login(t, "alice")
+	// Another tab of the same session
+	other := &client{app: app, cookies: alice.cookies}
+
+	var secrets []string
+	for _, c := range []*client{alice, alice, other} {
+		response := c.do(http.MethodGet, "/user/settings/2fa", nil)
+		match := secretPattern.FindStringSubmatch(response.Body.String())
+		if response.Code != http.StatusOK || match == nil {
+			t.Fatalf("GET /user/settings/2fa = %d without a secret", response.Code)
+		}
+		secrets = append(secrets, match[1])
+	}
+	if secrets[0] != secrets[1] || secrets[0] != secrets[2] {
+		t.Fatalf("secrets %v, want the same secret on every load", secrets)
+	}
+
+	code, err := totp.Code(secrets[0], totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response := alice.do(http.MethodPost, "/user/settings/2fa/enable", url.Values{"code": {code}})
+	if response.Code != http.StatusOK {
+		t.Fatalf("enable with a code for the first secret = %d", response.Code)
+	}
+	if twoFactor := app.store.ReadTwoFactor("alice-id"); twoFactor == nil || !twoFactor.Enabled {
+		t.Error("two factor authentication not enabled")
+	}
+}
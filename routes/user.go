@@ -1,22 +1,16 @@
 package routes
 
 import (
-	"encoding/base64"
-	"encoding/json"
-	"io/ioutil"
 	"log"
 	"net/http"
-	"net/url"
-	"os"
 
-	"github.com/Bhar8at/bhar8at.github.io/database"
+	"github.com/Bhar8at/bhar8at.github.io/internal/imaging"
+	"github.com/Bhar8at/bhar8at.github.io/middleware"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
 
-var postLimit = 5
-
-func GetUser(c *gin.Context) {
+func (h *Handler) GetUser(c *gin.Context) {
 	session := sessions.Default(c)
 	id := session.Get("userId")
 	if id == nil {
@@ -27,29 +21,30 @@ func GetUser(c *gin.Context) {
 		return
 	}
 	userId := id.(string)
+	posts, _ := h.store.ReadPosts(userId, nil, 5)
 	c.HTML(http.StatusOK, "userT.html", gin.H{
+		"csrf":      middleware.CSRFToken(c),
 		"settings":  true,
-		"user":      database.ReadUserById(userId),
-		"postCount": database.ReadPostsCount(userId),
-		"followers": database.ReadFollowers(userId),
-		"following": database.ReadFollowing(userId),
-		"posts":     database.ReadPosts(userId, 5, 0),
-		"oauth":     database.IsOAuthUser(userId),
+		"user":      h.store.ReadUserById(userId),
+		"postCount": h.store.ReadPostsCount(userId),
+		"followers": h.store.ReadFollowers(userId),
+		"following": h.store.ReadFollowing(userId),
+		"posts":     posts,
+		"oauth":     h.store.IsOAuthUser(userId),
+		"admin":     h.store.IsAdmin(userId),
 	})
 }
 
-func GetUserByName(c *gin.Context) {
+func (h *Handler) GetUserByName(c *gin.Context) {
 	username := c.Param("username")
-	session := sessions.Default(c)
-	id := session.Get("userId")
-	if id != nil {
-		user := database.ReadUserById(id.(string))
-		if username == user.Username {
+	id := middleware.SessionUserId(c, h.store)
+	if id != "" {
+		if viewer := h.store.ReadUserById(id); viewer != nil && username == viewer.Username {
 			c.Redirect(http.StatusFound, "/user/")
 			return
 		}
 	}
-	user := database.ReadUserByName(username)
+	user := h.store.ReadUserByName(username)
 	if user == nil {
 		c.HTML(http.StatusNotFound, "errorT.html", gin.H{
 			"error":   "404 Not Found",
@@ -58,23 +53,25 @@ func GetUserByName(c *gin.Context) {
 		return
 	}
 	user.Email = nil
-	followers := database.ReadFollowers(user.Id)
-	following := database.ReadFollowing(user.Id)
-	postCount := database.ReadPostsCount(user.Id)
-	posts := database.ReadPosts(user.Id, 5, 0)
+	followers := h.store.ReadFollowers(user.Id)
+	following := h.store.ReadFollowing(user.Id)
+	postCount := h.store.ReadPostsCount(user.Id)
+	posts, _ := h.store.ReadPosts(user.Id, nil, 5)
 
-	if id != nil {
+	if id != "" {
 		c.HTML(http.StatusOK, "userT.html", gin.H{
+			"csrf":      middleware.CSRFToken(c),
 			"user":      user,
 			"postCount": postCount,
 			"followers": followers,
 			"following": following,
 			"posts":     posts,
-			"follows":   database.Followed(id.(string), user.Id),
+			"follows":   h.store.Followed(id, user.Id),
 		})
 		return
 	}
 	c.HTML(http.StatusOK, "userT.html", gin.H{
+		"csrf":      middleware.CSRFToken(c),
 		"user":      user,
 		"postCount": postCount,
 		"followers": followers,
@@ -83,9 +80,9 @@ func GetUserByName(c *gin.Context) {
 	})
 }
 
-func GetUserPosts(c *gin.Context) {
+func (h *Handler) GetUserPosts(c *gin.Context) {
 	username := c.Param("username")
-	user := database.ReadUserByName(username)
+	user := h.store.ReadUserByName(username)
 	if user == nil {
 		c.HTML(http.StatusNotFound, "errorT.html", gin.H{
 			"error":   "404 Not Found",
@@ -93,24 +90,31 @@ func GetUserPosts(c *gin.Context) {
 		})
 		return
 	}
-	postLimit = 10
-	posts := database.ReadPosts(user.Id, 10, 0)
+	posts, next := h.store.ReadPosts(user.Id, nil, pageSize)
 	c.HTML(http.StatusOK, "userpostsT.html", gin.H{
-		"user":  user,
-		"posts": posts,
+		"user":   user,
+		"posts":  posts,
+		"cursor": next.Encode(),
 	})
 }
 
 // Return posts for loading through AJAX
-func LoadMorePosts(c *gin.Context) {
+func (h *Handler) LoadMorePosts(c *gin.Context) {
 	username := c.Param("username")
-	user := database.ReadUserByName(username)
-	posts := database.ReadPosts(user.Id, 10, postLimit)
-	postLimit += 10
-	c.JSON(http.StatusOK, posts)
+	user := h.store.ReadUserByName(username)
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+	after, ok := moreCursor(c)
+	if !ok {
+		return
+	}
+	posts, next := h.store.ReadPosts(user.Id, after, pageSize)
+	morePage(c, posts, next)
 }
 
-func UpdateAvatar(c *gin.Context) {
+func (h *Handler) UpdateAvatar(c *gin.Context) {
 	session := sessions.Default(c)
 	id := session.Get("userId")
 	if id == nil {
@@ -123,68 +127,59 @@ func UpdateAvatar(c *gin.Context) {
 	switch c.Request.Method {
 	case "GET":
 		c.HTML(http.StatusOK, "updateT.html", gin.H{
+			"csrf": middleware.CSRFToken(c),
 			"type": "avatar",
 		})
 	case "POST":
-		// Read the image
-		file, _, err := c.Request.FormFile("avatar")
+		header, err := c.FormFile("avatar")
 		if err != nil {
 			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
 				"error":   "400 Bad Request",
-				"message": "Unable to process request, try again later.",
+				"message": "Choose an image to upload.",
 			})
 			return
 		}
-		defer file.Close()
-		fileData, err := ioutil.ReadAll(file)
+		fileData, err := readUpload(header)
 		if err != nil {
 			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
 				"error":   "400 Bad Request",
-				"message": "Unable to read image, try again later.",
+				"message": err.Error(),
 			})
 			return
 		}
-		// Post the image to Freeimage API
-		encoded := base64.StdEncoding.EncodeToString(fileData)
-		response, err := http.PostForm(
-			"https://freeimage.host/api/1/upload?key="+os.Getenv("FREEIMAGE_API_KEY")+"&format=json",
-			url.Values{"source": {encoded}},
-		)
-		if err != nil {
+		if _, _, err := imaging.Validate(fileData); err != nil {
+			h.quarantine(c.Request.Context(), id.(string), header.Filename, fileData, err)
 			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
 				"error":   "400 Bad Request",
-				"message": "Unable to read image, try again later.",
+				"message": imageError(err),
 			})
 			return
 		}
-		defer response.Body.Close()
-		var responseData map[string]interface{}
-		if err := json.NewDecoder(response.Body).Decode(&responseData); err != nil {
-			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
-				"error":   "400 Bad Request",
-				"message": "Unable to upload avatar, try again later.",
+		// Processing can be slow, so the avatar is made by a background job
+		key, err := h.stageAvatar(c.Request.Context(), fileData)
+		if err != nil {
+			log.Println(err)
+			c.HTML(http.StatusInternalServerError, "errorT.html", gin.H{
+				"error":   "500 Internal Server Error",
+				"message": "Unable to update avatar, try again later.",
 			})
 			return
 		}
-		// Update user avatar URL
-		if result := database.UpdateUser(
-			id.(string),
-			map[string]any{"avatar": responseData["image"].(map[string]interface{})["url"]},
-		); !result {
-			log.Println(responseData)
-			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
-				"error":   "400 Bad Request",
+		if !h.queue.Enqueue(kindAvatar, avatarUpload{UserId: id.(string), Key: key}) {
+			h.unstageAvatar(c.Request.Context(), key)
+			c.HTML(http.StatusInternalServerError, "errorT.html", gin.H{
+				"error":   "500 Internal Server Error",
 				"message": "Unable to update avatar, try again later.",
 			})
 			return
 		}
-		c.HTML(http.StatusOK, "response.tmpl.html", gin.H{
-			"message": "Avatar updated successfully.",
+		c.HTML(http.StatusOK, "responseT.html", gin.H{
+			"message": "Avatar uploaded, it will show on your profile shortly.",
 		})
 	}
 }
 
-func UpdateUsername(c *gin.Context) {
+func (h *Handler) UpdateUsername(c *gin.Context) {
 	session := sessions.Default(c)
 	id := session.Get("userId")
 	if id == nil {
@@ -197,11 +192,12 @@ func UpdateUsername(c *gin.Context) {
 	switch c.Request.Method {
 	case "GET":
 		c.HTML(http.StatusOK, "updateT.html", gin.H{
+			"csrf": middleware.CSRFToken(c),
 			"type": "username",
 		})
 	case "POST":
 		newUsername := c.PostForm("username")
-		user := database.ReadUserById(id.(string))
+		user := h.store.ReadUserById(id.(string))
 		if user.Username == newUsername {
 			c.HTML(http.StatusForbidden, "errorT.html", gin.H{
 				"error":   "403 Forbidden",
@@ -209,14 +205,14 @@ func UpdateUsername(c *gin.Context) {
 			})
 			return
 		}
-		if exists := database.ReadUserByName(newUsername); exists != nil {
+		if exists := h.store.ReadUserByName(newUsername); exists != nil {
 			c.HTML(http.StatusForbidden, "errorT.html", gin.H{
 				"error":   "403 Forbidden",
 				"message": "Username not available or already taken.",
 			})
 			return
 		}
-		if result := database.UpdateUser(user.Id, map[string]any{"username": newUsername}); !result {
+		if result := h.store.UpdateUser(user.Id, map[string]any{"username": newUsername}); !result {
 			c.HTML(http.StatusInternalServerError, "errorT.html", gin.H{
 				"error":   "500 Internal Server Error",
 				"message": "Unable to change username, try again later.",
@@ -229,7 +225,7 @@ func UpdateUsername(c *gin.Context) {
 	}
 }
 
-func UpdatePassword(c *gin.Context) {
+func (h *Handler) UpdatePassword(c *gin.Context) {
 	session := sessions.Default(c)
 	id := session.Get("userId")
 	if id == nil {
@@ -242,11 +238,12 @@ func UpdatePassword(c *gin.Context) {
 	switch c.Request.Method {
 	case "GET":
 		c.HTML(http.StatusOK, "updateT.html", gin.H{
+			"csrf": middleware.CSRFToken(c),
 			"type": "password",
 		})
 	case "POST":
 		newPassword := c.PostForm("password")
-		user := database.ReadUserById(id.(string))
+		user := h.store.ReadUserById(id.(string))
 		if user.CheckPassword(newPassword) {
 			c.HTML(http.StatusForbidden, "errorT.html", gin.H{
 				"error":   "403 Forbidden",
@@ -257,20 +254,29 @@ func UpdatePassword(c *gin.Context) {
 		// Create hash of new password and update it
 		user.Password = newPassword
 		user.HashPassword()
-		if result := database.UpdateUser(id.(string), map[string]any{"password": user.Password}); !result {
+		if result := h.store.UpdateUser(id.(string), map[string]any{"password": user.Password}); !result {
 			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
 				"error":   "400 Bad Request",
 				"message": "Unable to change password, try again later.",
 			})
 			return
 		}
+		// OAuth users can login with the password from now on
+		if h.store.IsOAuthUser(user.Id) {
+			h.store.DeleteOAuthUser(user.Id)
+		}
+		// Log out every other device and start a fresh session here
+		h.store.DeleteSessions(user.Id)
+		token, _ := middleware.CreateToken(c, h.store, user.Id)
+		session.Set("Authorization", token)
+		session.Save()
 		c.HTML(http.StatusOK, "responseT.html", gin.H{
 			"message": "Password updated successfully",
 		})
 	}
 }
 
-func DeleteUser(c *gin.Context) {
+func (h *Handler) DeleteUser(c *gin.Context) {
 	session := sessions.Default(c)
 	id := session.Get("userId")
 	if id == nil {
@@ -283,12 +289,13 @@ func DeleteUser(c *gin.Context) {
 	switch c.Request.Method {
 	case "GET":
 		c.HTML(http.StatusOK, "deleteT.html", gin.H{
-			"oauth": database.IsOAuthUser(id.(string)),
+			"csrf":  middleware.CSRFToken(c),
+			"oauth": h.store.IsOAuthUser(id.(string)),
 		})
 	case "POST":
-		user := database.ReadUserById(id.(string))
+		user := h.store.ReadUserById(id.(string))
 		// Password required for users who didn't sign up through OAuth
-		if !database.IsOAuthUser(user.Id) {
+		if !h.store.IsOAuthUser(user.Id) {
 			password := c.PostForm("password")
 			if !user.CheckPassword(password) {
 				c.HTML(http.StatusForbidden, "errorT.html", gin.H{
@@ -298,7 +305,8 @@ func DeleteUser(c *gin.Context) {
 				return
 			}
 		}
-		if result := database.DeleteUser(user.Id); !result {
+		h.store.DeleteSessions(user.Id)
+		if result := h.store.DeleteUser(user.Id); !result {
 			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
 				"error":   "400 Bad Request",
 				"message": "Unable to delete account, try again later.",
@@ -315,7 +323,7 @@ func DeleteUser(c *gin.Context) {
 	}
 }
 
-func ToggleFollow(c *gin.Context) {
+func (h *Handler) ToggleFollow(c *gin.Context) {
 	session := sessions.Default(c)
 	id := session.Get("userId")
 	if id == nil {
@@ -326,7 +334,15 @@ func ToggleFollow(c *gin.Context) {
 		return
 	}
 	username := c.Param("username")
-	toFollow := database.ReadUserByName(username)
-	database.ToggleFollow(id.(string), toFollow.Id)
+	toFollow := h.store.ReadUserByName(username)
+	if toFollow == nil {
+		c.HTML(http.StatusNotFound, "errorT.html", gin.H{
+			"error":   "404 Not Found",
+			"message": "User not found",
+		})
+		return
+	}
+	h.store.ToggleFollow(id.(string), toFollow.Id)
+	h.timeline.Followed(id.(string), toFollow.Id)
 	c.Redirect(http.StatusFound, "/user/"+username)
 }
@@ -0,0 +1,51 @@
+package routes
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestToggleFollow(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantFollow bool
+	}{
+		{"profile", "/user/bob/toggle-follow", http.StatusFound, true},
+		{"search", "/search/bob/toggle-follow", http.StatusOK, true},
+		{"unknown user from profile", "/user/nobody/toggle-follow", http.StatusNotFound, false},
+		{"unknown user from search", "/search/nobody/toggle-follow", http.StatusNotFound, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			app := newTestApp(t)
+			alice := app.login(t, "alice")
+			app.createUser(t, "bob")
+			response := alice.do(http.MethodPost, test.path, nil)
+			if response.Code != test.wantStatus {
+				t.Fatalf("POST %s = %d, want %d", test.path, response.Code, test.wantStatus)
+			}
+			following := app.store.ReadFollowing("alice-id")
+			if followed := len(following) == 1 && following[0] == "bob"; followed != test.wantFollow {
+				t.Errorf("following %v, want following bob %v", following, test.wantFollow)
+			}
+			if test.wantFollow {
+				// Toggling again unfollows
+				alice.do(http.MethodPost, test.path, nil)
+				if following := app.store.ReadFollowing("alice-id"); len(following) != 0 {
+					t.Errorf("following %v after toggling twice", following)
+				}
+			}
+		})
+	}
+}
+
+func TestToggleFollowLoggedOut(t *testing.T) {
+	app := newTestApp(t)
+	app.createUser(t, "bob")
+	response := app.client().do(http.MethodPost, "/user/bob/toggle-follow", nil)
+	if response.Code != http.StatusUnauthorized {
+		t.Errorf("POST while logged out = %d, want 401", response.Code)
+	}
+}
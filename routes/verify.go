@@ -0,0 +1,81 @@
+package routes
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Bhar8at/bhar8at.github.io/internal/verify"
+	"github.com/Bhar8at/bhar8at.github.io/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) Verify(c *gin.Context) {
+	// Links sent through email carry the token and work without logging in
+	if token := c.Query("token"); token != "" {
+		if _, err := h.verifier.Confirm(token); err != nil {
+			log.Println(err)
+			c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
+				"error":   "400 Bad Request",
+				"message": "Verification link is invalid or has expired.",
+			})
+			return
+		}
+		c.HTML(http.StatusOK, "responseT.html", gin.H{
+			"message": "Account verified successfully.",
+		})
+		return
+	}
+	id := middleware.SessionUserId(c, h.store)
+	user := h.store.ReadUserById(id)
+	if user == nil {
+		c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+			"error":   "401 Unauthorized",
+			"message": "User not logged in.",
+		})
+		return
+	}
+	if user.Verified {
+		c.HTML(http.StatusOK, "responseT.html", gin.H{
+			"message": "Account already verified.",
+		})
+		return
+	}
+	c.HTML(http.StatusOK, "verifyT.html", gin.H{
+		"csrf":   middleware.CSRFToken(c),
+		"signup": c.Query("signup") == "true",
+		"email":  user.Email,
+	})
+}
+
+func (h *Handler) ResendVerification(c *gin.Context) {
+	id := middleware.SessionUserId(c, h.store)
+	user := h.store.ReadUserById(id)
+	if user == nil {
+		c.HTML(http.StatusUnauthorized, "errorT.html", gin.H{
+			"error":   "401 Unauthorized",
+			"message": "User not logged in.",
+		})
+		return
+	}
+	switch err := h.verifier.Send(user); err {
+	case nil:
+		c.HTML(http.StatusOK, "responseT.html", gin.H{
+			"message": "A new verification link has been sent to " + *user.Email + ".",
+		})
+	case verify.ErrAlreadyVerified:
+		c.HTML(http.StatusOK, "responseT.html", gin.H{
+			"message": "Account already verified.",
+		})
+	case verify.ErrNoEmail:
+		c.HTML(http.StatusBadRequest, "errorT.html", gin.H{
+			"error":   "400 Bad Request",
+			"message": "No email address is linked to this account.",
+		})
+	default:
+		log.Println(err)
+		c.HTML(http.StatusInternalServerError, "errorT.html", gin.H{
+			"error":   "500 Internal Server Error",
+			"message": "Unable to send verification email, try again later.",
+		})
+	}
+}
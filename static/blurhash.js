@@ -0,0 +1,95 @@
+// Blurred placeholders for images while they load, decoded from the
+// blurhash of each image, see https://blurha.sh
+
+const base83 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~";
+
+function decode83(text) {
+    var value = 0;
+    for (var i = 0; i < text.length; i++) {
+        value = value * 83 + base83.indexOf(text[i]);
+    }
+    return value;
+}
+
+function toLinear(value) {
+    var v = value / 255;
+    return v <= 0.04045 ? v / 12.92 : Math.pow((v + 0.055) / 1.055, 2.4);
+}
+
+function toSRGB(value) {
+    var v = Math.max(0, Math.min(1, value));
+    return v <= 0.0031308 ? Math.round(v * 12.92 * 255) : Math.round((1.055 * Math.pow(v, 1 / 2.4) - 0.055) * 255);
+}
+
+function signPow(value, exponent) {
+    return Math.sign(value) * Math.pow(Math.abs(value), exponent);
+}
+
+// Data URL of the blurhash drawn at a small size, which the browser
+// scales up smoothly
+function blurhashURL(hash, width, height) {
+    var size = decode83(hash[0]);
+    var componentsX = size % 9 + 1;
+    var componentsY = Math.floor(size / 9) + 1;
+    if (hash.length != 4 + 2 * componentsX * componentsY) {
+        return null;
+    }
+    var maximum = (decode83(hash[1]) + 1) / 166;
+    var colors = [];
+    for (var i = 0; i < componentsX * componentsY; i++) {
+        if (i == 0) {
+            var dc = decode83(hash.substring(2, 6));
+            colors.push([toLinear(dc >> 16), toLinear((dc >> 8) & 255), toLinear(dc & 255)]);
+        } else {
+            var ac = decode83(hash.substring(4 + i * 2, 6 + i * 2));
+            colors.push([
+                signPow((Math.floor(ac / 361) - 9) / 9, 2) * maximum,
+                signPow((Math.floor(ac / 19) % 19 - 9) / 9, 2) * maximum,
+                signPow((ac % 19 - 9) / 9, 2) * maximum,
+            ]);
+        }
+    }
+    var canvas = document.createElement("canvas");
+    canvas.width = width;
+    canvas.height = height;
+    var context = canvas.getContext("2d");
+    var pixels = context.createImageData(width, height);
+    for (var y = 0; y < height; y++) {
+        for (var x = 0; x < width; x++) {
+            var r = 0, g = 0, b = 0;
+            for (var j = 0; j < componentsY; j++) {
+                for (var i = 0; i < componentsX; i++) {
+                    var basis = Math.cos(Math.PI * x * i / width) * Math.cos(Math.PI * y * j / height);
+                    var color = colors[i + j * componentsX];
+                    r += color[0] * basis;
+                    g += color[1] * basis;
+                    b += color[2] * basis;
+                }
+            }
+            var offset = 4 * (x + y * width);
+            pixels.data[offset] = toSRGB(r);
+            pixels.data[offset + 1] = toSRGB(g);
+            pixels.data[offset + 2] = toSRGB(b);
+            pixels.data[offset + 3] = 255;
+        }
+    }
+    context.putImageData(pixels, 0, 0);
+    return canvas.toDataURL();
+}
+
+// Shows the placeholder behind every image with a blurhash until it loads
+function showPlaceholders() {
+    document.querySelectorAll("img[data-blurhash]").forEach(function(image) {
+        var url = blurhashURL(image.dataset.blurhash, 32, 32);
+        image.removeAttribute("data-blurhash");
+        if (url == null || image.complete) {
+            return;
+        }
+        image.style.backgroundImage = `url(${url})`;
+        image.addEventListener("load", function() {
+            image.style.backgroundImage = "";
+        });
+    });
+}
+
+document.addEventListener("DOMContentLoaded", showPlaceholders);
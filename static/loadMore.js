@@ -1,13 +1,41 @@
+// Keeps the More button for the page after the cursor, or removes it
+// after the last page
+function nextPage(cursor) {
+    if (cursor) {
+        $("#more").attr("data-cursor", cursor);
+    } else {
+        $("#more").remove();
+    }
+}
+
+// Gallery of a post's images, built through jQuery so the alt text
+// written by users is escaped
+function gallery(media) {
+    if (!media || media.length == 0) {
+        return "";
+    }
+    var content = `<div class="gallery">`;
+    media.forEach(function(item) {
+        var image = $("<img />", { src: item.ThumbnailURL, alt: item.AltText, loading: "lazy" });
+        if (item.Width) {
+            image.attr({ width: item.Width, height: item.Height });
+        }
+        if (item.Blurhash) {
+            image.attr("data-blurhash", item.Blurhash);
+        }
+        content += image.prop("outerHTML");
+    });
+    return content + `</div>`;
+}
+
 // Load more feed posts
 function loadMoreFeed() {
     $.ajax({
         url: "/feed/more",
         type: "GET",
-        success: function(data) {
-            if (!data) {
-                $("#more").remove()
-                return
-            }
+        data: { cursor: $("#more").attr("data-cursor") },
+        success: function(page) {
+            var data = page.data || [];
             data.forEach(function(post) {
                 content = `<span class="avatar-small">`;
                 if (post.Avatar) {
@@ -22,13 +50,13 @@ function loadMoreFeed() {
                 </h3>
                 <a href="/post/${post.Id}">
                     <p>${post.Body}</p>
+                    ${gallery(post.Media)}
                     <p class="separator">${post.CreatedAt}</p>
                 </a>`;
                 $("#posts").append(content);
             });
-            if (data.length < 10) {
-                $("#more").remove()
-            }
+            showPlaceholders();
+            nextPage(page.nextCursor);
         },
     });
 }
@@ -38,11 +66,9 @@ function loadMoreComments(postId) {
     $.ajax({
         url: `/post/${postId}/comments`,
         type: "GET",
-        success: function(data) {
-            if (!data) {
-                $("#more").remove()
-                return
-            }
+        data: { cursor: $("#more").attr("data-cursor") },
+        success: function(page) {
+            var data = page.data || [];
             data.forEach(function(comment) {
                 content = `
                 <p>${comment.Body}</p>
@@ -50,16 +76,21 @@ function loadMoreComments(postId) {
                 <a href="/user/${comment.Username}">@${comment.Username}</a> &nbsp;`;
                 if (comment.Self) {
                     content += `
-                    <a href="/post/${postId}/comment/delete?commentId=${comment.Id}">
-                        <i class="fa-regular fa-trash-can"></i> Delete
-                    </a>`;
+                    <form
+                        action="/post/${postId}/comment/delete?commentId=${comment.Id}"
+                        method="POST"
+                        style="display: inline"
+                    >
+                        <input type="hidden" name="csrf_token" value="${csrfToken()}" />
+                        <button type="submit">
+                            <i class="fa-regular fa-trash-can"></i> Delete
+                        </button>
+                    </form>`;
                 }
                 content += `</p>`;
                 $("#comments").append(content);
             });
-            if (data.length < 10) {
-                $("#more").remove()
-            }
+            nextPage(page.nextCursor);
         },
     });
 }
@@ -69,11 +100,9 @@ function loadMoreUsers() {
     $.ajax({
         url: "/search/more",
         type: "GET",
-        success: function(data) {
-            if (!data) {
-                return
-            }
-            $("#more").remove()
+        data: { cursor: $("#more").attr("data-cursor") },
+        success: function(page) {
+            var data = page.data || [];
             data.forEach(function(user) {
                 content = `
                 <span class="avatar-small">`;
@@ -106,16 +135,9 @@ function loadMoreUsers() {
                 </p>`;
                 $("#users").append(content);
             });
-            if (data.length == 10) {
-                content = `
-                <div id="more">
-                <h3 style="padding-top: 10px">
-                    <a onclick="loadMoreUsers()">
-                    <i class="fa-solid fa-circle-chevron-down"></i> More
-                    </a>
-                </h3>
-                </div>`;
-                $("#users").append(content)
+            $("#more").remove()
+            if (page.nextCursor) {
+                $("#users").append(moreUsersButton(page.nextCursor))
             }
         },
     });
@@ -126,22 +148,20 @@ function loadMorePosts(username) {
     $.ajax({
         url: `/user/${username}/posts/more`,
         type: "GET",
-        success: function(data) {
-            if (!data) {
-                $("#more").remove()
-                return
-            }
+        data: { cursor: $("#more").attr("data-cursor") },
+        success: function(page) {
+            var data = page.data || [];
             data.forEach(function(post) {
                 content = `
                 <a href="/post/${post.Id}">
+                    ${gallery(post.Media)}
                     <p class="content">${post.Body}</p>
                     <p class="separator">${post.CreatedAt}</p>
                 </a>`
                 $("#posts").append(content);
             });
-            if (data.length < 10) {
-                $("#more").remove()
-            }
+            showPlaceholders();
+            nextPage(page.nextCursor);
         },
     });
 }
\ No newline at end of file
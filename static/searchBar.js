@@ -7,8 +7,10 @@ function loadUsers(str) {
     $.ajax({
         url: "/search",
         type: "POST",
+        headers: { "X-CSRF-Token": csrfToken() },
         data: { search: str },
-        success: function(data) {
+        success: function(page) {
+            var data = page.data;
             if (!data) {
                 div.innerHTML = `
                 <p style="color: rgb(130, 130, 130)">No users found.</p>`;
@@ -46,26 +48,32 @@ function loadUsers(str) {
                         following
                     </p>`;
             });
-            if (data.length == 10) {
-                content += `
-                <div id="more">
-                <h3 style="padding-top: 10px">
-                    <a onclick="loadMoreUsers()">
-                    <i class="fa-solid fa-circle-chevron-down"></i> More
-                    </a>
-                </h3>
-                </div>`;
+            if (page.nextCursor) {
+                content += moreUsersButton(page.nextCursor);
             }
             div.innerHTML = content;
         },
     });
 }
 
+// More button of the search results, loading the page after the cursor
+function moreUsersButton(cursor) {
+    return `
+    <div id="more" data-cursor="${cursor}">
+    <h3 style="padding-top: 10px">
+        <a onclick="loadMoreUsers()">
+        <i class="fa-solid fa-circle-chevron-down"></i> More
+        </a>
+    </h3>
+    </div>`;
+}
+
 function toggleFollow(username) {
     var follows = document.getElementById(`follows-${username}`);
     $.ajax({
         url: `/search/${username}/toggle-follow`,
         type: "POST",
+        headers: { "X-CSRF-Token": csrfToken() },
         success: function() {
             follows.innerText = follows.innerText == "Unfollow" ? "Follow" : "Unfollow";
         }
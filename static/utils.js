@@ -43,4 +43,10 @@ if (togglePassword != null && password != null) {
         password.setAttribute("type", type);
         this.classList.toggle("fa-eye-slash");
     });
-}
\ No newline at end of file
+}
+
+// CSRF token of the page, sent along with requests that change anything
+function csrfToken() {
+    var field = document.querySelector('input[name="csrf_token"]');
+    return field != null ? field.value : "";
+}